@@ -0,0 +1,343 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/babaYaga451/social/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var errSelfAction = errors.New("admins cannot perform this action on their own account")
+
+type ModerateUserPayload struct {
+	Reason string `json:"reason" validate:"required,max=1000"`
+}
+
+// ListUsers godoc
+//
+//	@Summary		Lists users
+//	@Description	Lists and searches users, including inactive and banned accounts
+//	@Tags			admin
+//	@Produce		json
+//	@Param			limit			query		int		false	"Limit"
+//	@Param			offset			query		int		false	"Offset"
+//	@Param			sort			query		string	false	"Sort by creation date (asc, desc)"
+//	@Param			search			query		string	false	"Username or email contains"
+//	@Param			active			query		bool	false	"Active"
+//	@Param			role			query		string	false	"Role name"
+//	@Param			created_after	query		string	false	"Created at or after (RFC3339)"
+//	@Param			created_before	query		string	false	"Created before (RFC3339)"
+//	@Success		200				{object}	[]store.User
+//	@Failure		400				{object}	error
+//	@Failure		403				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users [get]
+func (app *application) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	uq := store.PaginatedUserQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "desc",
+	}
+
+	uq, err := uq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(uq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	users, err := app.store.Users.List(r.Context(), uq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, users); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DeactivateUser godoc
+//
+//	@Summary		Deactivates a user
+//	@Description	Deactivates the account of a user below the admin's level and revokes its sessions
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			userId	path		int					true	"User ID"
+//	@Param			payload	body		ModerateUserPayload	true	"Reason"
+//	@Success		204		{string}	string				"User deactivated"
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userId}/deactivate [post]
+func (app *application) deactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, payload, ok := app.readModerationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	if err := app.store.Users.Deactivate(ctx, userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.invalidateUserCache(ctx, userID)
+	app.recordAudit(r, store.AuditActionUserDeactivate, store.AuditTargetUser, userID, map[string]any{
+		"reason": payload.Reason,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BanUser godoc
+//
+//	@Summary		Bans a user
+//	@Description	Bans a user below the admin's level with a reason and revokes its sessions
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			userId	path		int					true	"User ID"
+//	@Param			payload	body		ModerateUserPayload	true	"Reason"
+//	@Success		204		{string}	string				"User banned"
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userId}/ban [post]
+func (app *application) banUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, payload, ok := app.readModerationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	if err := app.store.Users.Ban(ctx, userID, payload.Reason); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.invalidateUserCache(ctx, userID)
+	app.recordAudit(r, store.AuditActionUserBan, store.AuditTargetUser, userID, map[string]any{
+		"reason": payload.Reason,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnbanUser godoc
+//
+//	@Summary		Lifts a ban
+//	@Description	Lifts the ban on a user
+//	@Tags			admin
+//	@Produce		json
+//	@Param			userId	path		int		true	"User ID"
+//	@Success		204		{string}	string	"User unbanned"
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userId}/ban [delete]
+func (app *application) unbanUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseUserIDParam(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if _, ok := app.manageableUser(w, r, userID); !ok {
+		return
+	}
+
+	ctx := r.Context()
+	if err := app.store.Users.Unban(ctx, userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.invalidateUserCache(ctx, userID)
+	app.recordAudit(r, store.AuditActionUserUnban, store.AuditTargetUser, userID, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutUser godoc
+//
+//	@Summary		Forces a user to log out
+//	@Description	Revokes every token issued to the user so far
+//	@Tags			admin
+//	@Produce		json
+//	@Param			userId	path		int		true	"User ID"
+//	@Success		204		{string}	string	"Sessions revoked"
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userId}/logout [post]
+func (app *application) logoutUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseUserIDParam(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if _, ok := app.manageableUser(w, r, userID); !ok {
+		return
+	}
+
+	ctx := r.Context()
+	if err := app.store.Users.RevokeTokens(ctx, userID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.invalidateUserCache(ctx, userID)
+	app.recordAudit(r, store.AuditActionUserLogout, store.AuditTargetUser, userID, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ImpersonateUser godoc
+//
+//	@Summary		Impersonates a user
+//	@Description	Issues a short-lived token that acts as the user, for support purposes
+//	@Tags			admin
+//	@Produce		json
+//	@Param			userId	path		int		true	"User ID"
+//	@Success		201		{string}	string	"Token"
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userId}/impersonate [post]
+func (app *application) impersonateUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseUserIDParam(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	admin := getUserFromContext(r)
+	if admin.ID == userID || getImpersonatorFromContext(r) != 0 {
+		app.badRequestError(w, r, errSelfAction)
+		return
+	}
+
+	ctx := r.Context()
+	target, err := app.store.Users.GetById(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// never hand out a token that could be used to impersonate someone else
+	privileged, err := app.hasPermission(ctx, target, store.PermissionUserImpersonate)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if privileged {
+		app.forbiddenErrorResponse(w, r)
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"sub": target.ID,
+		"act": map[string]any{"sub": admin.ID},
+		"exp": time.Now().Add(app.conf.Auth.Token.ImpersonationExp).Unix(),
+		"iat": time.Now().Unix(),
+		"ngf": time.Now().Unix(),
+		"iss": app.conf.Auth.Token.Iss,
+		"aud": app.conf.Auth.Token.Iss,
+		"ver": target.TokenVersion,
+	}
+
+	token, err := app.authenticatort.GenerateToken(claims)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.recordAudit(r, store.AuditActionUserImpersonate, store.AuditTargetUser, target.ID, map[string]any{
+		"expires_in": app.conf.Auth.Token.ImpersonationExp.String(),
+	})
+
+	if err := app.jsonResponse(w, http.StatusCreated, token); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// readModerationRequest parses the target user and the reason payload shared
+// by the deactivate and ban endpoints and checks the admin outranks the
+// target, writing the error response itself.
+func (app *application) readModerationRequest(w http.ResponseWriter, r *http.Request) (int64, ModerateUserPayload, bool) {
+	var payload ModerateUserPayload
+
+	userID, err := parseUserIDParam(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return 0, payload, false
+	}
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return 0, payload, false
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return 0, payload, false
+	}
+
+	if _, ok := app.manageableUser(w, r, userID); !ok {
+		return 0, payload, false
+	}
+
+	return userID, payload, true
+}
+
+func parseUserIDParam(r *http.Request) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+}
@@ -1,15 +1,28 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/babaYaga451/social/docs"
 	"github.com/babaYaga451/social/internal/auth"
+	"github.com/babaYaga451/social/internal/blob"
+	"github.com/babaYaga451/social/internal/config"
+	"github.com/babaYaga451/social/internal/filter"
+	"github.com/babaYaga451/social/internal/health"
+	"github.com/babaYaga451/social/internal/lifecycle"
 	"github.com/babaYaga451/social/internal/mailer"
+	"github.com/babaYaga451/social/internal/metrics"
+	"github.com/babaYaga451/social/internal/realtime"
 	"github.com/babaYaga451/social/internal/store"
 	"github.com/babaYaga451/social/internal/store/cache"
+	"github.com/babaYaga451/social/internal/unfurl"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"go.uber.org/zap"
@@ -18,67 +31,22 @@ import (
 )
 
 type application struct {
-	conf           config
+	conf           config.Config
 	store          store.Storage
 	cacheStorage   cache.Storage
 	logger         *zap.SugaredLogger
 	mailer         mailer.Client
 	authenticatort auth.Authenticator
-}
-
-type authConfig struct {
-	basic basicConfig
-	token tokenConfig
-}
-
-type tokenConfig struct {
-	secret string
-	exp    time.Duration
-	iss    string
-}
-
-type basicConfig struct {
-	user string
-	pass string
-}
-
-type config struct {
-	addr        string
-	db          dbConfig
-	env         string
-	frontendURL string
-	apiURL      string
-	mail        mailConfig
-	auth        authConfig
-	redis       redisConfig
-}
-
-type redisConfig struct {
-	addr    string
-	pw      string
-	db      int
-	enabled bool
-}
-
-type mailConfig struct {
-	exp       time.Duration
-	fromEmail string
-	sendGrid  sendGridConfig
-	mailTrap  mailTrapConfig
-}
-
-type sendGridConfig struct {
-	apiKey string
-}
-
-type mailTrapConfig struct {
-	apiKey string
-}
-type dbConfig struct {
-	addr         string
-	maxOpenConns int
-	maxIdleConns int
-	maxIdleTime  string
+	contentFilter  *filter.Chain
+	health         *health.Checker
+	lifecycle      *lifecycle.Manager
+	blobs          blob.Store
+	unfurler       *unfurl.Fetcher
+	unfurlQueue    chan unfurlJob
+	realtime       *realtime.Hub
+	// shuttingDown is closed once the server starts shutting down, to end
+	// the long-lived streams Shutdown would otherwise wait for
+	shuttingDown chan struct{}
 }
 
 func (app *application) mount() http.Handler {
@@ -86,6 +54,8 @@ func (app *application) mount() http.Handler {
 
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(app.tracingMiddleware)
+	r.Use(app.metricsMiddleware)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
@@ -94,10 +64,14 @@ func (app *application) mount() http.Handler {
 	// processing should be stopped.
 	r.Use(middleware.Timeout(60 * time.Second))
 
+	r.Get("/metrics", metrics.Handler().ServeHTTP)
+	r.Get("/livez", app.livenessHandler)
+	r.Get("/readyz", app.readinessHandler)
+
 	r.Route("/v1", func(r chi.Router) {
 		r.With(app.BasicAuthMiddleware()).Get("/health", app.healthCheckHandler)
 
-		docsUrl := fmt.Sprintf("%s/swagger/doc.json", app.conf.addr)
+		docsUrl := fmt.Sprintf("%s/swagger/doc.json", app.conf.Addr)
 		r.Get("/swagger/*", httpSwagger.Handler(
 			httpSwagger.URL(docsUrl), //The url pointing to API definition
 		))
@@ -110,9 +84,18 @@ func (app *application) mount() http.Handler {
 				r.Use(app.postsContextMiddleWare)
 
 				r.Get("/", app.getPostHandler)
-				r.Delete("/", app.checkPostOwnership("admin", app.deletePostHandler))
-				r.Patch("/", app.checkPostOwnership("moderator", app.updatePostHandler))
+				r.Delete("/", app.checkPostOwnership(store.PermissionPostDeleteAny, app.deletePostHandler))
+				r.Patch("/", app.checkPostOwnership(store.PermissionPostUpdateAny, app.updatePostHandler))
+				r.Put("/bookmark", app.bookmarkPostHandler)
+				r.Delete("/bookmark", app.unbookmarkPostHandler)
+				r.Post("/poll/votes", app.votePollHandler)
+				r.Put("/repost", app.repostHandler)
+				r.Delete("/repost", app.undoRepostHandler)
+				r.Get("/revisions", app.checkPostOwnership(store.PermissionReportManage, app.getPostRevisionsHandler))
+				r.Get("/revisions/diff", app.checkPostOwnership(store.PermissionReportManage, app.getPostRevisionDiffHandler))
 				r.Post("/comments", app.createCommentHandler)
+				r.Post("/report", app.reportPostHandler)
+				r.Post("/comments/{commentId}/report", app.reportCommentHandler)
 			})
 		})
 
@@ -125,11 +108,145 @@ func (app *application) mount() http.Handler {
 				r.Get("/", app.getUserHandler)
 				r.Put("/follow", app.followUserHandler)
 				r.Put("/unfollow", app.unfollowUserHandler)
+				r.Put("/block", app.blockUserHandler)
+				r.Delete("/block", app.unblockUserHandler)
 			})
 
 			r.Group(func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
 				r.Get("/feed", app.getUserFeedHandler)
+				r.Get("/autocomplete", app.autocompleteUsersHandler)
+				r.Get("/me/drafts", app.getDraftsHandler)
+				r.Get("/me/warnings", app.getWarningsHandler)
+				r.Put("/privacy", app.setPrivacyHandler)
+			})
+		})
+
+		r.Route("/media", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Post("/", app.uploadMediaHandler)
+
+			r.Route("/uploads", func(r chi.Router) {
+				r.Post("/", app.createUploadHandler)
+
+				r.Route("/{mediaId}", func(r chi.Router) {
+					r.Use(app.mediaContextMiddleware)
+
+					r.Head("/", app.getUploadOffsetHandler)
+					r.Patch("/", app.uploadChunkHandler)
+				})
+			})
+
+			r.Route("/{mediaId}", func(r chi.Router) {
+				r.Use(app.mediaContextMiddleware)
+
+				r.Get("/", app.getMediaHandler)
+				r.Get("/content", app.getMediaContentHandler)
+				r.Get("/thumbnail", app.getMediaThumbnailHandler)
+			})
+		})
+
+		r.With(app.AuthTokenMiddleware).Get("/search", app.searchHandler)
+
+		r.Route("/bookmarks", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getBookmarksHandler)
+
+			r.Route("/collections", func(r chi.Router) {
+				r.Get("/", app.getCollectionsHandler)
+				r.Post("/", app.createCollectionHandler)
+
+				r.Route("/{collectionId}", func(r chi.Router) {
+					r.Use(app.collectionContextMiddleware)
+
+					r.Get("/", app.getCollectionHandler)
+					r.Patch("/", app.renameCollectionHandler)
+					r.Delete("/", app.deleteCollectionHandler)
+					r.Get("/posts", app.getCollectionPostsHandler)
+				})
+			})
+		})
+
+		r.Route("/conversations", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getConversationsHandler)
+			r.Post("/", app.createConversationHandler)
+			r.Get("/unread", app.getUnreadCountHandler)
+			r.Get("/stream", app.streamMessagesHandler)
+
+			r.Route("/{conversationId}", func(r chi.Router) {
+				r.Use(app.conversationContextMiddleware)
+
+				r.Get("/", app.getConversationHandler)
+				r.Get("/messages", app.getMessagesHandler)
+				r.Post("/messages", app.sendMessageHandler)
+				r.Put("/read", app.markReadHandler)
+			})
+		})
+
+		r.Route("/tags", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+
+			r.Get("/trending", app.getTrendingTagsHandler)
+			r.Get("/followed", app.getFollowedTagsHandler)
+
+			r.Route("/{tag}", func(r chi.Router) {
+				r.Get("/posts", app.getTagPostsHandler)
+				r.Put("/follow", app.followTagHandler)
+				r.Put("/unfollow", app.unfollowTagHandler)
+			})
+		})
+
+		r.Route("/moderation", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requirePermission(store.PermissionReportManage))
+
+			r.Get("/reports", app.listReportsHandler)
+			r.Post("/reports/{reportId}/resolve", app.resolveReportHandler)
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.requirePermission(store.PermissionRoleManage))
+
+				r.Get("/permissions", app.listPermissionsHandler)
+
+				r.Route("/roles", func(r chi.Router) {
+					r.Get("/", app.listRolesHandler)
+					r.Post("/", app.createRoleHandler)
+					r.Put("/{roleName}/permissions", app.setRolePermissionsHandler)
+				})
+			})
+
+			r.With(app.requirePermission(store.PermissionAuditRead)).Get("/audit", app.listAuditEventsHandler)
+
+			r.Route("/users", func(r chi.Router) {
+				r.With(app.requirePermission(store.PermissionUserManage)).Get("/", app.listUsersHandler)
+
+				r.Route("/{userId}", func(r chi.Router) {
+					r.Group(func(r chi.Router) {
+						r.Use(app.requirePermission(store.PermissionRoleManage))
+						r.Put("/role", app.assignUserRoleHandler)
+						r.Put("/permissions/{permission}", app.grantUserPermissionHandler)
+						r.Delete("/permissions/{permission}", app.revokeUserPermissionHandler)
+					})
+
+					r.Group(func(r chi.Router) {
+						r.Use(app.requirePermission(store.PermissionUserManage))
+						r.Post("/deactivate", app.deactivateUserHandler)
+						r.Post("/logout", app.logoutUserHandler)
+					})
+
+					r.Group(func(r chi.Router) {
+						r.Use(app.requirePermission(store.PermissionUserBan))
+						r.Post("/ban", app.banUserHandler)
+						r.Delete("/ban", app.unbanUserHandler)
+					})
+
+					r.With(app.requirePermission(store.PermissionUserImpersonate)).Post("/impersonate", app.impersonateUserHandler)
+				})
 			})
 		})
 
@@ -145,17 +262,42 @@ func (app *application) mount() http.Handler {
 func (app *application) run(mux http.Handler) error {
 	// Docs
 	docs.SwaggerInfo.Version = "0.0.1"
-	docs.SwaggerInfo.Host = app.conf.apiURL
+	docs.SwaggerInfo.Host = app.conf.APIURL
 
 	srv := &http.Server{
-		Addr:         app.conf.addr,
+		Addr:         app.conf.Addr,
 		Handler:      mux,
 		ReadTimeout:  time.Second * 10,
 		WriteTimeout: time.Second * 30,
 		IdleTimeout:  time.Minute,
 	}
+	srv.RegisterOnShutdown(func() { close(app.shuttingDown) })
+
+	shutdown := make(chan error, 1)
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		s := <-quit
+
+		app.logger.Infow("Shutting down server", "signal", s.String(), "timeout", app.conf.ShutdownTimeout)
+
+		// stop accepting connections and wait for in-flight requests
+		ctx, cancel := context.WithTimeout(context.Background(), app.conf.ShutdownTimeout)
+		defer cancel()
+
+		shutdown <- srv.Shutdown(ctx)
+	}()
 
-	app.logger.Infow("Server started", "addr", app.conf.addr)
+	app.logger.Infow("Server started", "addr", app.conf.Addr)
+
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	if err := <-shutdown; err != nil {
+		return err
+	}
 
-	return srv.ListenAndServe()
+	app.logger.Infow("Server stopped", "addr", app.conf.Addr)
+	return nil
 }
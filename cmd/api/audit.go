@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/babaYaga451/social/internal/store"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// recordAudit appends an audit event for the authenticated user. Failures are
+// logged rather than surfaced because the audited action has already happened.
+func (app *application) recordAudit(r *http.Request, action, targetType string, targetID int64, metadata map[string]any) {
+	app.writeAudit(r, &store.AuditEvent{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Metadata:   metadata,
+	})
+}
+
+// recordAuditChange is recordAudit for updates, storing the fields that
+// differ between before and after.
+func (app *application) recordAuditChange(r *http.Request, action, targetType string, targetID int64, before, after any) {
+	diff, err := auditDiff(before, after)
+	if err != nil {
+		app.logger.Errorw("error computing audit diff", "action", action, "target_id", targetID, "error", err)
+	}
+
+	app.writeAudit(r, &store.AuditEvent{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Diff:       diff,
+	})
+}
+
+// writeAudit fills in the request details and stores the event. The actor
+// defaults to the authenticated user when the event does not name one.
+func (app *application) writeAudit(r *http.Request, event *store.AuditEvent) {
+	if event.Metadata == nil {
+		event.Metadata = map[string]any{}
+	}
+
+	if event.ActorID == 0 {
+		if user := getUserFromContext(r); user != nil {
+			event.ActorID = user.ID
+		}
+	}
+
+	if impersonatorID := getImpersonatorFromContext(r); impersonatorID != 0 {
+		event.Metadata["impersonated_by"] = impersonatorID
+	}
+
+	event.IP = clientIP(r)
+	event.RequestID = middleware.GetReqID(r.Context())
+
+	if err := app.store.Audit.Create(r.Context(), event); err != nil {
+		app.logger.Errorw("error recording audit event", "action", event.Action, "target_id", event.TargetID, "error", err)
+	}
+}
+
+// ListAuditEvents godoc
+//
+//	@Summary		Lists audit events
+//	@Description	Queries the audit log, newest first. Use format=csv or format=ndjson to export.
+//	@Tags			admin
+//	@Produce		json
+//	@Produce		text/csv
+//	@Produce		application/x-ndjson
+//	@Param			limit		query		int		false	"Limit"
+//	@Param			offset		query		int		false	"Offset"
+//	@Param			sort		query		string	false	"Sort by creation date (asc, desc)"
+//	@Param			actor_id	query		int		false	"Actor ID"
+//	@Param			action		query		string	false	"Action"
+//	@Param			target_type	query		string	false	"Target type"
+//	@Param			target_id	query		int		false	"Target ID"
+//	@Param			from		query		string	false	"Created at or after (RFC3339)"
+//	@Param			to			query		string	false	"Created before (RFC3339)"
+//	@Param			format		query		string	false	"Response format (json, csv, ndjson)"
+//	@Success		200			{object}	[]store.AuditEvent
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/audit [get]
+func (app *application) listAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	aq := store.PaginatedAuditQuery{
+		Limit:  50,
+		Offset: 0,
+		Sort:   "desc",
+	}
+
+	aq, err := aq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(aq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" && format != "ndjson" {
+		app.badRequestError(w, r, errors.New("format must be one of json, csv, ndjson"))
+		return
+	}
+
+	events, err := app.store.Audit.List(r.Context(), aq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	switch format {
+	case "csv":
+		err = writeAuditCSV(w, events)
+	case "ndjson":
+		err = writeAuditNDJSON(w, events)
+	default:
+		err = app.jsonResponse(w, http.StatusOK, events)
+	}
+
+	if err != nil {
+		app.logger.Errorw("error writing audit events", "format", format, "error", err)
+	}
+}
+
+func writeAuditCSV(w http.ResponseWriter, events []store.AuditEvent) error {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	header := []string{"id", "created_at", "actor_id", "action", "target_type", "target_id", "ip", "request_id", "metadata", "diff"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		metadata, err := json.Marshal(e.Metadata)
+		if err != nil {
+			return err
+		}
+
+		diff, err := json.Marshal(e.Diff)
+		if err != nil {
+			return err
+		}
+
+		record := []string{
+			strconv.FormatInt(e.ID, 10),
+			e.CreatedAt,
+			strconv.FormatInt(e.ActorID, 10),
+			e.Action,
+			e.TargetType,
+			strconv.FormatInt(e.TargetID, 10),
+			e.IP,
+			e.RequestID,
+			string(metadata),
+			string(diff),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeAuditNDJSON(w http.ResponseWriter, events []store.AuditEvent) error {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.ndjson"`)
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// auditDiff compares the JSON representations of before and after and returns
+// {"field": {"from": ..., "to": ...}} for every top-level field that changed.
+func auditDiff(before, after any) (map[string]any, error) {
+	from, err := toJSONObject(before)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := toJSONObject(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := map[string]any{}
+	for key, value := range to {
+		if old, ok := from[key]; !ok || !reflect.DeepEqual(old, value) {
+			diff[key] = map[string]any{"from": from[key], "to": value}
+		}
+	}
+
+	for key, old := range from {
+		if _, ok := to[key]; !ok {
+			diff[key] = map[string]any{"from": old, "to": nil}
+		}
+	}
+
+	return diff, nil
+}
+
+func toJSONObject(v any) (map[string]any, error) {
+	obj := map[string]any{}
+	if v == nil {
+		return obj, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// clientIP is the address set by middleware.RealIP, without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -14,7 +14,7 @@ import (
 )
 
 type RegisterUserPayload struct {
-	UserName string `json:"username" validate:"required,max=100"`
+	UserName string `json:"username" validate:"required,max=100,username"`
 	Email    string `json:"email" validate:"required,email,max=255"`
 	Password string `json:"password" validate:"required,min=3,max=72"`
 }
@@ -27,7 +27,7 @@ type UserWithToken struct {
 // registerUserHandler godoc
 //
 //	@Summary		Registers a user
-//	@Description	Registers a user
+//	@Description	Registers a user. Usernames may only use ASCII letters, digits and underscores, so that they can be mentioned
 //	@Tags			authentication
 //	@Accept			json
 //	@Produce		json
@@ -70,7 +70,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	hash := sha256.Sum256([]byte(plainToken))
 	hashToken := hex.EncodeToString(hash[:])
 
-	err := app.store.Users.CreateAndInvite(ctx, user, hashToken, app.conf.mail.exp)
+	err := app.store.Users.CreateAndInvite(ctx, user, hashToken, app.conf.Mail.Exp)
 	if err != nil {
 		switch err {
 		case store.ErrDuplicateEmail:
@@ -88,8 +88,8 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		Token: plainToken,
 	}
 
-	activationURL := fmt.Sprintf("http://localhost%s/v1/users/activate/%s", app.conf.addr, plainToken)
-	isProdEnv := app.conf.env == "production"
+	activationURL := fmt.Sprintf("http://localhost%s/v1/users/activate/%s", app.conf.Addr, plainToken)
+	isProdEnv := app.conf.IsProduction()
 	vars := struct {
 		Username      string
 		ActivationURL string
@@ -99,7 +99,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	//TODO: Implement notification service to send mail
-	status, err := app.mailer.Send(mailer.UserWelcomeTemplate, user.UserName, user.Email, vars, !isProdEnv)
+	status, err := app.mailer.Send(ctx, mailer.UserWelcomeTemplate, user.UserName, user.Email, vars, !isProdEnv)
 	if err != nil {
 		app.logger.Errorf("error sending welcome email", "error", err)
 
@@ -112,6 +112,13 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 
 	app.logger.Infow("Email sent with status code%v", status)
 
+	app.writeAudit(r, &store.AuditEvent{
+		ActorID:    user.ID,
+		Action:     store.AuditActionUserRegister,
+		TargetType: store.AuditTargetUser,
+		TargetID:   user.ID,
+	})
+
 	if err := app.jsonResponse(w, status, userWithToken); err != nil {
 		app.internalServerError(w, r, err)
 	}
@@ -151,6 +158,11 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 	if err != nil {
 		switch err {
 		case store.ErrorNotFound:
+			app.writeAudit(r, &store.AuditEvent{
+				Action:     store.AuditActionLoginFailure,
+				TargetType: store.AuditTargetUser,
+				Metadata:   map[string]any{"email": payload.Email, "reason": "unknown email"},
+			})
 			app.unauthorizedErrorResponse(w, r, err)
 		default:
 			app.internalServerError(w, r, err)
@@ -159,17 +171,24 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	if err := user.Password.Compare(payload.Password); err != nil {
+		app.writeAudit(r, &store.AuditEvent{
+			Action:     store.AuditActionLoginFailure,
+			TargetType: store.AuditTargetUser,
+			TargetID:   user.ID,
+			Metadata:   map[string]any{"email": payload.Email, "reason": "invalid password"},
+		})
 		app.unauthorizedErrorResponse(w, r, err)
 		return
 	}
 
 	claims := jwt.MapClaims{
 		"sub": user.ID,
-		"exp": time.Now().Add(app.conf.auth.token.exp).Unix(),
+		"exp": time.Now().Add(app.conf.Auth.Token.Exp).Unix(),
 		"iat": time.Now().Unix(),
 		"ngf": time.Now().Unix(),
-		"iss": app.conf.auth.token.iss,
-		"aud": app.conf.auth.token.iss,
+		"iss": app.conf.Auth.Token.Iss,
+		"aud": app.conf.Auth.Token.Iss,
+		"ver": user.TokenVersion,
 	}
 
 	token, err := app.authenticatort.GenerateToken(claims)
@@ -178,6 +197,13 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	app.writeAudit(r, &store.AuditEvent{
+		ActorID:    user.ID,
+		Action:     store.AuditActionLoginSuccess,
+		TargetType: store.AuditTargetUser,
+		TargetID:   user.ID,
+	})
+
 	if err := app.jsonResponse(w, http.StatusCreated, token); err != nil {
 		app.internalServerError(w, r, err)
 	}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/babaYaga451/social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type CollectionKey string
+
+const CollectionCtx CollectionKey = "collection"
+
+var errCollectionNotFound = errors.New("collection not found")
+
+type BookmarkPayload struct {
+	CollectionID *int64 `json:"collection_id" validate:"omitempty,gt=0"`
+}
+
+type CollectionPayload struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// BookmarkPost godoc
+//
+//	@Summary		Bookmarks a post
+//	@Description	Saves a post to the current user's private bookmarks, optionally filed in one of their collections. Bookmarking it again moves it to the given collection
+//	@Tags			bookmarks
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Post ID"
+//	@Param			payload	body		BookmarkPayload	false	"Collection to file the bookmark in"
+//	@Success		204		{string}	string			"Post bookmarked"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/bookmark [put]
+func (app *application) bookmarkPostHandler(w http.ResponseWriter, r *http.Request) {
+	var payload BookmarkPayload
+	if err := readJSON(w, r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		app.badRequestError(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+
+	if err := app.store.Bookmarks.Save(r.Context(), user.ID, post.ID, payload.CollectionID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.badRequestError(w, r, errCollectionNotFound)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnbookmarkPost godoc
+//
+//	@Summary		Removes a bookmark
+//	@Description	Removes a post from the current user's bookmarks
+//	@Tags			bookmarks
+//	@Produce		json
+//	@Param			id	path		int		true	"Post ID"
+//	@Success		204	{string}	string	"Bookmark removed"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/bookmark [delete]
+func (app *application) unbookmarkPostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+
+	if err := app.store.Bookmarks.Delete(r.Context(), user.ID, post.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetBookmarks godoc
+//
+//	@Summary		Lists bookmarked posts
+//	@Description	Lists the posts the current user bookmarked, in any collection, most recently bookmarked first by default
+//	@Tags			bookmarks
+//	@Produce		json
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Param			sort	query		string	false	"Sort (asc, desc)"
+//	@Success		200		{object}	[]store.PostWithMetaData
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/bookmarks [get]
+func (app *application) getBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	app.listBookmarks(w, r, nil)
+}
+
+// GetCollectionPosts godoc
+//
+//	@Summary		Lists the posts of a collection
+//	@Description	Lists the bookmarked posts filed in a collection, most recently bookmarked first by default
+//	@Tags			bookmarks
+//	@Produce		json
+//	@Param			collectionId	path		int		true	"Collection ID"
+//	@Param			limit			query		int		false	"Limit"
+//	@Param			offset			query		int		false	"Offset"
+//	@Param			sort			query		string	false	"Sort (asc, desc)"
+//	@Success		200				{object}	[]store.PostWithMetaData
+//	@Failure		400				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/bookmarks/collections/{collectionId}/posts [get]
+func (app *application) getCollectionPostsHandler(w http.ResponseWriter, r *http.Request) {
+	app.listBookmarks(w, r, &getCollectionFromCtx(r).ID)
+}
+
+// listBookmarks writes a page of the current user's bookmarks in
+// collectionID, or in any collection if it is nil.
+func (app *application) listBookmarks(w http.ResponseWriter, r *http.Request, collectionID *int64) {
+	fq := store.PaginatedFeedQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "desc",
+		Mode:   store.FeedModeChronological,
+	}
+
+	fq, err := fq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(fq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	user := getUserFromContext(r)
+
+	fq.IncludeHidden, err = app.hasPermission(ctx, user, store.PermissionReportManage)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	posts, err := app.store.Bookmarks.GetPosts(ctx, user.ID, collectionID, fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.enrichPosts(ctx, user.ID, posts); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, posts); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetCollections godoc
+//
+//	@Summary		Lists bookmark collections
+//	@Description	Lists the bookmark collections of the current user by name
+//	@Tags			bookmarks
+//	@Produce		json
+//	@Success		200	{object}	[]store.BookmarkCollection
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/bookmarks/collections [get]
+func (app *application) getCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	collections, err := app.store.Bookmarks.Collections(r.Context(), getUserFromContext(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, collections); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// CreateCollection godoc
+//
+//	@Summary		Creates a bookmark collection
+//	@Description	Creates a named collection to file bookmarks in
+//	@Tags			bookmarks
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CollectionPayload	true	"Collection payload"
+//	@Success		201		{object}	store.BookmarkCollection
+//	@Failure		400		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/bookmarks/collections [post]
+func (app *application) createCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CollectionPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	collection := &store.BookmarkCollection{
+		UserID: getUserFromContext(r).ID,
+		Name:   payload.Name,
+	}
+
+	if err := app.store.Bookmarks.CreateCollection(r.Context(), collection); err != nil {
+		switch err {
+		case store.ErrDuplicateCollection:
+			app.conflictError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, collection); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetCollection godoc
+//
+//	@Summary		Fetches a bookmark collection
+//	@Description	Fetches one of the current user's bookmark collections
+//	@Tags			bookmarks
+//	@Produce		json
+//	@Param			collectionId	path		int	true	"Collection ID"
+//	@Success		200				{object}	store.BookmarkCollection
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/bookmarks/collections/{collectionId} [get]
+func (app *application) getCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, getCollectionFromCtx(r)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// RenameCollection godoc
+//
+//	@Summary		Renames a bookmark collection
+//	@Description	Renames one of the current user's bookmark collections
+//	@Tags			bookmarks
+//	@Accept			json
+//	@Produce		json
+//	@Param			collectionId	path		int					true	"Collection ID"
+//	@Param			payload			body		CollectionPayload	true	"Collection payload"
+//	@Success		200				{object}	store.BookmarkCollection
+//	@Failure		400				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		409				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/bookmarks/collections/{collectionId} [patch]
+func (app *application) renameCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CollectionPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	collection := getCollectionFromCtx(r)
+	collection.Name = payload.Name
+
+	if err := app.store.Bookmarks.RenameCollection(r.Context(), collection); err != nil {
+		switch err {
+		case store.ErrDuplicateCollection:
+			app.conflictError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, collection); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DeleteCollection godoc
+//
+//	@Summary		Deletes a bookmark collection
+//	@Description	Deletes one of the current user's bookmark collections. Its bookmarks are kept, outside of any collection
+//	@Tags			bookmarks
+//	@Produce		json
+//	@Param			collectionId	path		int		true	"Collection ID"
+//	@Success		204				{string}	string	"Collection deleted"
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/bookmarks/collections/{collectionId} [delete]
+func (app *application) deleteCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.store.Bookmarks.DeleteCollection(r.Context(), getCollectionFromCtx(r).ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// collectionContextMiddleware loads the collection of the URL. Collections
+// are private: anyone but their owner gets a not found.
+func (app *application) collectionContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "collectionId"), 10, 64)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+		collection, err := app.store.Bookmarks.GetCollection(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrorNotFound):
+				app.notFoundError(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		if collection.UserID != getUserFromContext(r).ID {
+			app.notFoundError(w, r, store.ErrorNotFound)
+			return
+		}
+
+		ctx = context.WithValue(ctx, CollectionCtx, collection)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getCollectionFromCtx(r *http.Request) *store.BookmarkCollection {
+	collection, _ := r.Context().Value(CollectionCtx).(*store.BookmarkCollection)
+	return collection
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+
+	"github.com/babaYaga451/social/internal/store"
+)
+
+func (app *application) getPost(ctx context.Context, postID int64) (*store.Post, error) {
+	return app.cacheStorage.Posts.Get(ctx, postID, func(ctx context.Context) (*store.Post, error) {
+		return app.store.Posts.GetById(ctx, postID)
+	})
+}
+
+func (app *application) getComments(ctx context.Context, postID int64) ([]store.Comment, error) {
+	return app.cacheStorage.Comments.GetByPostID(ctx, postID, func(ctx context.Context) ([]store.Comment, error) {
+		return app.store.Comment.GetByPostID(ctx, postID)
+	})
+}
+
+func (app *application) getFeed(ctx context.Context, userID int64, fq store.PaginatedFeedQuery) ([]store.PostWithMetaData, error) {
+	load := func(ctx context.Context) ([]store.PostWithMetaData, error) {
+		if fq.Mode != store.FeedModeRanked {
+			feed, err := app.store.Timeline.Get(ctx, userID, fq, app.conf.Timeline.CelebrityThreshold)
+			if err != nil {
+				return nil, err
+			}
+			return feed, app.enrichPosts(ctx, userID, feed)
+		}
+
+		ranked, err := app.rankFeed(ctx, userID, fq)
+		if err != nil {
+			return nil, err
+		}
+
+		feed := make([]store.PostWithMetaData, len(ranked))
+		for i, r := range ranked {
+			feed[i] = r.Post
+		}
+		return feed, nil
+	}
+
+	if !app.cacheStorage.Feeds.Cacheable(fq) {
+		return load(ctx)
+	}
+
+	return app.cacheStorage.Feeds.Get(ctx, userID, fq, load)
+}
+
+// cachePost stores a post that was just updated, so readers get the new
+// version without going to the database.
+func (app *application) cachePost(ctx context.Context, post *store.Post) {
+	if err := app.cacheStorage.Posts.Set(ctx, post); err != nil {
+		app.logger.Warnw("error caching post", "post_id", post.ID, "error", err)
+		app.cacheStorage.Posts.Delete(ctx, post.ID)
+	}
+}
+
+// invalidatePost drops a post that changed without a version bump (hidden,
+// shown) or was deleted, together with its comments and the author's feed.
+func (app *application) invalidatePost(ctx context.Context, postID, authorID int64) {
+	app.cacheStorage.Posts.Delete(ctx, postID)
+	app.cacheStorage.Comments.DeleteByPostID(ctx, postID)
+	app.cacheStorage.Feeds.Delete(ctx, authorID)
+}
+
+func (app *application) invalidateComments(ctx context.Context, postID int64) {
+	app.cacheStorage.Comments.DeleteByPostID(ctx, postID)
+}
+
+func (app *application) invalidateFeed(ctx context.Context, userID int64) {
+	app.cacheStorage.Feeds.Delete(ctx, userID)
+}
+
+// invalidatePublished drops the feeds a newly published post of authorID was
+// fanned out to: the author's own and those of their followers.
+func (app *application) invalidatePublished(ctx context.Context, authorID int64) {
+	app.invalidateFeed(ctx, authorID)
+
+	followerIDs, err := app.store.Follower.FanOutFollowerIDs(ctx, authorID, app.conf.Timeline.CelebrityThreshold)
+	if err != nil {
+		app.logger.Errorw("error listing followers to invalidate", "user_id", authorID, "error", err)
+		return
+	}
+	app.cacheStorage.Feeds.Delete(ctx, followerIDs...)
+}
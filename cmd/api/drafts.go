@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/babaYaga451/social/internal/store"
+)
+
+var (
+	errPublishAtRequired   = errors.New("scheduled posts need a publish_at in the future")
+	errPublishAtNotAllowed = errors.New("publish_at is only allowed for scheduled posts")
+	errAlreadyPublished    = errors.New("post is already published")
+)
+
+// checkSchedule validates the publication settings of an unpublished post.
+func checkSchedule(status string, publishAt *time.Time) error {
+	if status != store.PostStatusScheduled {
+		if publishAt != nil {
+			return errPublishAtNotAllowed
+		}
+		return nil
+	}
+
+	if publishAt == nil || !publishAt.After(time.Now()) {
+		return errPublishAtRequired
+	}
+	return nil
+}
+
+// GetDrafts godoc
+//
+//	@Summary		Lists unpublished posts
+//	@Description	Lists the drafts and scheduled posts of the current user, newest first by default
+//	@Tags			posts
+//	@Produce		json
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Param			sort	query		string	false	"Sort (asc, desc)"
+//	@Success		200		{object}	[]store.Post
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/drafts [get]
+func (app *application) getDraftsHandler(w http.ResponseWriter, r *http.Request) {
+	lq := store.PaginatedListQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "desc",
+	}
+
+	lq, err := lq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(lq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	posts, err := app.store.Posts.GetUnpublished(r.Context(), getUserFromContext(r).ID, lq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, posts); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// publishScheduledPosts publishes due scheduled posts every configured
+// interval until ctx is cancelled. Every API instance runs it; the store
+// makes sure each post is published once.
+func (app *application) publishScheduledPosts(ctx context.Context) error {
+	conf := app.conf.Scheduler
+
+	ticker := time.NewTicker(conf.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := app.publishDuePosts(ctx); err != nil && ctx.Err() == nil {
+			app.logger.Errorw("publishing scheduled posts", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishDuePosts publishes due posts batch by batch until none is left.
+func (app *application) publishDuePosts(ctx context.Context) error {
+	conf := app.conf.Scheduler
+
+	for {
+		published, err := app.store.Posts.PublishDue(ctx, conf.BatchSize, app.conf.Timeline.CelebrityThreshold)
+		if err != nil {
+			return err
+		}
+
+		for _, post := range published {
+			app.invalidatePost(ctx, post.ID, post.UserID)
+			app.invalidatePublished(ctx, post.UserID)
+			app.logger.Infow("published scheduled post", "post_id", post.ID, "user_id", post.UserID)
+		}
+
+		if len(published) < conf.BatchSize {
+			return nil
+		}
+	}
+}
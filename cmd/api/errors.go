@@ -44,3 +44,21 @@ func (app *application) forbiddenErrorResponse(w http.ResponseWriter, r *http.Re
 	writeJSONError(w, http.StatusForbidden, "forbidden")
 
 }
+
+func (app *application) conflictError(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnw("conflict", "method", r.Method, "path", r.URL.Path, "error", err)
+
+	writeJSONError(w, http.StatusConflict, err.Error())
+}
+
+func (app *application) payloadTooLargeError(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnw("payload too large", "method", r.Method, "path", r.URL.Path, "error", err)
+
+	writeJSONError(w, http.StatusRequestEntityTooLarge, err.Error())
+}
+
+func (app *application) unsupportedMediaTypeError(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnw("unsupported media type", "method", r.Method, "path", r.URL.Path, "error", err)
+
+	writeJSONError(w, http.StatusUnsupportedMediaType, err.Error())
+}
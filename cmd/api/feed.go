@@ -1,23 +1,37 @@
 package main
 
 import (
+	"context"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/babaYaga451/social/internal/ranking"
 	"github.com/babaYaga451/social/internal/store"
 )
 
+// rankedPost is a feed post with, on request, the breakdown of its score.
+type rankedPost struct {
+	store.PostWithMetaData
+	Ranking *ranking.Explanation `json:"ranking,omitempty"`
+}
+
 // getUserFeedHandler godoc
 //
 //	@Summary		Fetches the user feed
-//	@Description	Fetches the user feed
+//	@Description	Fetches the home timeline: the user's own posts and those of the users they follow. The ranked feed only covers the newest posts, so it ends sooner than the chronological one; the Next-Offset header is left out on the last page
 //	@Tags			feed
 //	@Accept			json
 //	@Produce		json
 //	@Param			limit	query		int		false	"Limit"
 //	@Param			offset	query		int		false	"Offset"
-//	@Param			sort	query		string	false	"Sort"
-//	@Success		200		{object}	[]store.PostWithMetaData
+//	@Param			sort	query		string	false	"Sort, chronological mode only (asc, desc)"
+//	@Param			mode	query		string	false	"Mode (chronological, ranked)"
+//	@Param			debug	query		bool	false	"Include the ranking explanation, ranked mode only"
+//	@Success		200		{object}	[]rankedPost
+//	@Header			200		{int}		Next-Offset	"Offset of the next page"
 //	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
 //	@Failure		500		{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/users/feed [get]
@@ -28,6 +42,7 @@ func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Reques
 		Limit:  20,
 		Offset: 0,
 		Sort:   "desc",
+		Mode:   store.FeedModeChronological,
 	}
 
 	fq, err := fq.Parse(r)
@@ -41,14 +56,140 @@ func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	feed, err := app.store.Posts.GetUserFeed(ctx, int64(42), fq)
+	user := getUserFromContext(r)
+
+	fq.IncludeHidden, err = app.hasPermission(ctx, user, store.PermissionReportManage)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if fq.Debug {
+		allowed, err := app.hasPermission(ctx, user, store.PermissionFeedDebug)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !allowed {
+			app.forbiddenErrorResponse(w, r)
+			return
+		}
+	}
+
+	if fq.Mode == store.FeedModeRanked && fq.Debug {
+		ranked, err := app.rankFeed(ctx, user.ID, fq)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		posts := make([]rankedPost, len(ranked))
+		for i := range ranked {
+			posts[i] = rankedPost{PostWithMetaData: ranked[i].Post, Ranking: &ranked[i].Explanation}
+		}
+		app.setNextFeedOffset(w, fq, len(ranked))
+
+		if err := app.jsonResponse(w, http.StatusOK, posts); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	feed, err := app.getFeed(ctx, user.ID, fq)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
+	app.setNextFeedOffset(w, fq, len(feed))
+
 	if err := app.jsonResponse(w, http.StatusOK, feed); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
+
+// setNextFeedOffset sets the Next-Offset header to the offset of the page
+// after fq, which returned n posts, unless that was the last one. The ranked
+// feed ends with its candidates.
+func (app *application) setNextFeedOffset(w http.ResponseWriter, fq store.PaginatedFeedQuery, n int) {
+	next := fq.Offset + n
+	if n < fq.Limit {
+		return
+	}
+	if fq.Mode == store.FeedModeRanked && next >= app.conf.Ranking.Candidates {
+		return
+	}
+
+	w.Header().Set("Next-Offset", strconv.Itoa(next))
+}
+
+// rankFeed scores the newest timeline posts and returns the requested page
+// of the result.
+func (app *application) rankFeed(ctx context.Context, userID int64, fq store.PaginatedFeedQuery) ([]ranking.Ranked, error) {
+	conf := app.conf.Ranking
+
+	candidates, err := app.store.Timeline.Get(ctx, userID, store.PaginatedFeedQuery{
+		Limit:         conf.Candidates,
+		Sort:          "desc",
+		IncludeHidden: fq.IncludeHidden,
+	}, app.conf.Timeline.CelebrityThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	signals, err := app.store.Timeline.Signals(ctx, userID, conf.SignalWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := ranking.Rank(time.Now(), candidates, *signals, ranking.Weights{
+		Recency:          conf.RecencyWeight,
+		Affinity:         conf.AffinityWeight,
+		Engagement:       conf.EngagementWeight,
+		Tags:             conf.TagWeight,
+		RecencyHalfLife:  conf.RecencyHalfLife,
+		DiversityPenalty: conf.DiversityPenalty,
+	})
+
+	if fq.Offset >= len(ranked) {
+		return []ranking.Ranked{}, nil
+	}
+
+	page := ranked[fq.Offset:min(fq.Offset+fq.Limit, len(ranked))]
+
+	posts := make([]store.PostWithMetaData, len(page))
+	for i, r := range page {
+		posts[i] = r.Post
+	}
+	if err := app.enrichPosts(ctx, userID, posts); err != nil {
+		return nil, err
+	}
+	for i := range page {
+		page[i].Post = posts[i]
+	}
+
+	return page, nil
+}
+
+// capTimelines periodically cuts the fanned-out home timelines back to the
+// configured number of entries so they do not grow without bound.
+func (app *application) capTimelines(ctx context.Context) error {
+	conf := app.conf.Timeline
+
+	ticker := time.NewTicker(conf.TrimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if err := app.store.Timeline.Cap(ctx, conf.MaxEntries); err != nil {
+			app.logger.Errorw("capping timelines", "error", err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/babaYaga451/social/internal/config"
+	"github.com/babaYaga451/social/internal/filter"
+	"github.com/babaYaga451/social/internal/store"
+)
+
+// newContentFilter builds the content filter chain with the action
+// configured for each filter.
+func newContentFilter(cfg config.FilterConfig) (*filter.Chain, error) {
+	var errs []error
+	action := func(name string) filter.Action {
+		a, err := filter.ParseAction(name)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return a
+	}
+
+	chain := filter.NewChain(
+		filter.NewBannedWords(cfg.BannedWords, action(cfg.BannedWordsAction)),
+		filter.NewDeniedDomains(cfg.DeniedDomains, action(cfg.DeniedDomainsAction)),
+		filter.NewMaxMentions(cfg.MaxMentions, action(cfg.MaxMentionsAction)),
+		filter.NewMaxHashtags(cfg.MaxHashtags, action(cfg.MaxHashtagsAction)),
+		filter.NewDuplicateSpam(cfg.DuplicateWindow, cfg.DuplicateMax, action(cfg.DuplicateSpamAction)),
+	)
+
+	return chain, errors.Join(errs...)
+}
+
+// checkContent runs the content filter chain. When the content is rejected it
+// writes the error response itself and returns false.
+func (app *application) checkContent(w http.ResponseWriter, r *http.Request, content filter.Content) (filter.Verdict, bool) {
+	verdict, err := app.contentFilter.Run(r.Context(), content)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return verdict, false
+	}
+
+	if verdict.Action == filter.Reject {
+		app.badRequestError(w, r, fmt.Errorf("content rejected: %s", verdict.Reason()))
+		return verdict, false
+	}
+
+	return verdict, true
+}
+
+// heldAt returns the hidden_at timestamp for content the filter held back,
+// or nil when it can be published straight away.
+func heldAt(verdict filter.Verdict) *string {
+	if verdict.Action != filter.Hold {
+		return nil
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	return &now
+}
+
+// holdForModeration files a report without a reporter so held content shows
+// up in the moderation queue. Content held again while its report is still
+// open does not file another one.
+func (app *application) holdForModeration(ctx context.Context, targetType string, targetID, authorID int64, verdict filter.Verdict) error {
+	if verdict.Action != filter.Hold {
+		return nil
+	}
+
+	reasons := make([]string, 0, len(verdict.Matches))
+	for _, m := range verdict.Matches {
+		reasons = append(reasons, m.Filter+": "+m.Reason)
+	}
+
+	report := &store.Report{
+		TargetType:   targetType,
+		TargetID:     targetID,
+		TargetUserID: authorID,
+		Reason:       store.ReportReasonFilter,
+		Details:      strings.Join(reasons, "; "),
+	}
+
+	err := app.store.Reports.Create(ctx, report)
+	if errors.Is(err, store.ErrDuplicateReport) {
+		return nil
+	}
+
+	return err
+}
@@ -2,22 +2,69 @@ package main
 
 import (
 	"net/http"
+
+	"github.com/babaYaga451/social/internal/health"
 )
 
 // healthcheckHandler godoc
 //
 //	@Summary		Healthcheck
-//	@Description	Healthcheck endpoint
+//	@Description	Detailed report of every dependency check, including errors and timings
 //	@Tags			ops
 //	@Produce		json
-//	@Success		200	{object}	string	"ok"
+//	@Success		200	{object}	health.Report
+//	@Failure		503	{object}	health.Report
+//	@Security		BasicAuth
 //	@Router			/health [get]
 func (app *application) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	data := map[string]string{
-		"env":    app.conf.env,
-		"status": "ok",
+	report := app.health.Run(r.Context())
+
+	data := struct {
+		Env string `json:"env"`
+		health.Report
+	}{
+		Env:    app.conf.Env,
+		Report: report,
 	}
-	if err := app.jsonResponse(w, http.StatusOK, data); err != nil {
+	if err := app.jsonResponse(w, healthStatus(report), data); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
+
+// livenessHandler reports that the process is serving requests. It does not
+// look at dependencies, so an outage does not get the pod restarted.
+func (app *application) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	if err := writeJSON(w, http.StatusOK, map[string]string{"status": health.StatusUp}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// readinessHandler runs the dependency checks and answers 503 if any fails.
+// Errors are only logged; the detailed report is on the authenticated
+// health route.
+func (app *application) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	report := app.health.Run(r.Context())
+
+	checks := make(map[string]string, len(report.Checks))
+	for name, result := range report.Checks {
+		checks[name] = result.Status
+		if result.Error != "" {
+			app.logger.Warnw("readiness check failed", "check", name, "error", result.Error)
+		}
+	}
+
+	data := map[string]any{
+		"status": report.Status,
+		"checks": checks,
+	}
+	if err := writeJSON(w, healthStatus(report), data); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func healthStatus(report health.Report) int {
+	if report.Healthy() {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/babaYaga451/social/internal/mentions"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -11,6 +12,11 @@ var Validate *validator.Validate
 
 func init() {
 	Validate = validator.New(validator.WithRequiredStructEnabled())
+
+	// usernames must be mentionable
+	Validate.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+		return mentions.ValidUserName(fl.Field().String())
+	})
 }
 
 func writeJSON(w http.ResponseWriter, status int, data any) error {
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/babaYaga451/social/internal/store"
+	"github.com/babaYaga451/social/internal/unfurl"
+)
+
+type unfurlJob struct {
+	postID   int64
+	authorID int64
+	content  string
+}
+
+// enqueueUnfurl schedules previews for the links in post. The queue is best
+// effort: when it is full the post simply goes without previews.
+func (app *application) enqueueUnfurl(post *store.Post) {
+	select {
+	case app.unfurlQueue <- unfurlJob{postID: post.ID, authorID: post.UserID, content: post.Content}:
+	default:
+		app.logger.Warnw("link preview queue is full", "post_id", post.ID)
+	}
+}
+
+// unfurlLinks processes queued posts until ctx is cancelled.
+func (app *application) unfurlLinks(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case job := <-app.unfurlQueue:
+			if err := app.unfurlPost(ctx, job); err != nil && ctx.Err() == nil {
+				app.logger.Errorw("unfurling post links", "post_id", job.postID, "error", err)
+			}
+		}
+	}
+}
+
+// unfurlPost records the links of a post, makes sure each has a fresh
+// preview and drops the cached post so readers see them.
+func (app *application) unfurlPost(ctx context.Context, job unfurlJob) error {
+	urls := unfurl.ExtractURLs(job.content, app.conf.Links.MaxPerPost)
+
+	if err := app.store.Links.SetPostLinks(ctx, job.postID, urls); err != nil {
+		return err
+	}
+
+	for _, url := range urls {
+		if err := app.refreshPreview(ctx, url); err != nil {
+			return err
+		}
+	}
+
+	app.invalidatePost(ctx, job.postID, job.authorID)
+	return nil
+}
+
+// refreshPreview fetches the preview of url unless a recent one is cached.
+// Failed fetches are cached too, for a shorter time.
+func (app *application) refreshPreview(ctx context.Context, url string) error {
+	conf := app.conf.Links
+
+	cached, err := app.store.Links.GetPreview(ctx, url)
+	switch {
+	case err == nil:
+		ttl := conf.PreviewTTL
+		if cached.Failed {
+			ttl = conf.FailureTTL
+		}
+		if time.Since(cached.FetchedAt) < ttl {
+			return nil
+		}
+	case !errors.Is(err, store.ErrorNotFound):
+		return err
+	}
+
+	preview := &store.LinkPreview{URL: url}
+
+	fetched, err := app.unfurler.Fetch(ctx, url)
+	if err != nil {
+		app.logger.Infow("fetching link preview", "url", url, "error", err)
+		preview.Failed = true
+	} else {
+		preview.Title = fetched.Title
+		preview.Description = fetched.Description
+		preview.ImageURL = fetched.ImageURL
+		preview.SiteName = fetched.SiteName
+		preview.Failed = fetched.Title == "" && fetched.Description == ""
+	}
+
+	return app.store.Links.SavePreview(ctx, preview)
+}
+
+// attachLinks adds the link previews to a page of posts.
+func (app *application) attachLinks(ctx context.Context, posts []store.PostWithMetaData) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+
+	links, err := app.store.Links.GetByPostIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		posts[i].Links = links[posts[i].ID]
+	}
+	return nil
+}
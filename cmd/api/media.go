@@ -0,0 +1,537 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/babaYaga451/social/internal/blob"
+	"github.com/babaYaga451/social/internal/media"
+	"github.com/babaYaga451/social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type MediaKey string
+
+const MediaCtx MediaKey = "media"
+
+// multipartOverhead is the room left for multipart boundaries and headers
+// on top of the file itself.
+const multipartOverhead = 1 << 20
+
+var errMediaTooLarge = errors.New("media is larger than allowed")
+
+// UploadMedia godoc
+//
+//	@Summary		Uploads media
+//	@Description	Uploads an image (JPEG, PNG, GIF or WebP) in a single multipart request. Its metadata is stripped and a thumbnail is generated; attach it to a post through media_ids.
+//	@Tags			media
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			file	formData	file	true	"Image"
+//	@Success		201		{object}	store.Media
+//	@Failure		400		{object}	error
+//	@Failure		413		{object}	error
+//	@Failure		415		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/media [post]
+func (app *application) uploadMediaHandler(w http.ResponseWriter, r *http.Request) {
+	maxBytes := int64(app.conf.Media.MaxBytes)
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+multipartOverhead)
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			app.payloadTooLargeError(w, r, errMediaTooLarge)
+		default:
+			app.badRequestError(w, r, err)
+		}
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	if int64(len(data)) > maxBytes {
+		app.payloadTooLargeError(w, r, errMediaTooLarge)
+		return
+	}
+
+	img, err := media.Process(data, app.conf.Media.ThumbnailSize)
+	if err != nil {
+		app.mediaError(w, r, err)
+		return
+	}
+
+	m := &store.Media{
+		UserID: getUserFromContext(r).ID,
+		Status: store.MediaStatusProcessing,
+		Size:   int64(len(img.Data)),
+	}
+
+	ctx := r.Context()
+	if err := app.store.Media.Create(ctx, m); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.storeMedia(ctx, m, img); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, m); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type CreateUploadPayload struct {
+	Size int64 `json:"size" validate:"gt=0"`
+}
+
+// CreateUpload godoc
+//
+//	@Summary		Starts a resumable upload
+//	@Description	Reserves media of the given size. Send the bytes in order with PATCH /media/uploads/{id}; after an interruption HEAD tells where to resume.
+//	@Tags			media
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateUploadPayload	true	"Upload payload"
+//	@Success		201		{object}	store.Media
+//	@Failure		400		{object}	error
+//	@Failure		413		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/media/uploads [post]
+func (app *application) createUploadHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateUploadPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if payload.Size > int64(app.conf.Media.MaxBytes) {
+		app.payloadTooLargeError(w, r, errMediaTooLarge)
+		return
+	}
+
+	m := &store.Media{
+		UserID: getUserFromContext(r).ID,
+		Status: store.MediaStatusUploading,
+		Size:   payload.Size,
+	}
+
+	if err := app.store.Media.Create(r.Context(), m); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/v1/media/uploads/%d", m.ID))
+	if err := app.jsonResponse(w, http.StatusCreated, m); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetUploadOffset godoc
+//
+//	@Summary		Fetches the progress of a resumable upload
+//	@Description	Returns the number of bytes received so far in the Upload-Offset header and the total in Upload-Length
+//	@Tags			media
+//	@Param			id	path	int	true	"Media ID"
+//	@Success		200
+//	@Failure		404	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/media/uploads/{id} [head]
+func (app *application) getUploadOffsetHandler(w http.ResponseWriter, r *http.Request) {
+	m := getMediaFromCtx(r)
+	if m.UserID != getUserFromContext(r).ID {
+		app.notFoundError(w, r, store.ErrorNotFound)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(m.UploadOffset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(m.Size, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+// UploadChunk godoc
+//
+//	@Summary		Uploads a chunk of a resumable upload
+//	@Description	Appends the request body at the offset given in the Upload-Offset header, which must equal the bytes received so far. The chunk completing the upload returns the processed media.
+//	@Tags			media
+//	@Accept			application/octet-stream
+//	@Produce		json
+//	@Param			id				path		int		true	"Media ID"
+//	@Param			Upload-Offset	header		int		true	"Offset of the chunk"
+//	@Success		200				{object}	store.Media
+//	@Failure		400				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		409				{object}	error
+//	@Failure		413				{object}	error
+//	@Failure		415				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/media/uploads/{id} [patch]
+func (app *application) uploadChunkHandler(w http.ResponseWriter, r *http.Request) {
+	m := getMediaFromCtx(r)
+	if m.UserID != getUserFromContext(r).ID {
+		app.notFoundError(w, r, store.ErrorNotFound)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(m.UploadOffset, 10))
+
+	if m.Status != store.MediaStatusUploading {
+		app.conflictError(w, r, errors.New("upload is already complete"))
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, errors.New("Upload-Offset header is missing or malformed"))
+		return
+	}
+	if offset != m.UploadOffset {
+		app.conflictError(w, r, store.ErrUploadOffsetMismatch)
+		return
+	}
+
+	limit := min(int64(app.conf.Media.ChunkMaxBytes), m.Size-offset)
+	chunk, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			app.payloadTooLargeError(w, r, fmt.Errorf("chunk may be at most %d bytes", limit))
+		default:
+			app.badRequestError(w, r, err)
+		}
+		return
+	}
+	if len(chunk) == 0 {
+		app.badRequestError(w, r, errors.New("chunk is empty"))
+		return
+	}
+
+	ctx := r.Context()
+	if err := app.blobs.Put(ctx, store.ChunkKey(m.ID, offset), bytes.NewReader(chunk), int64(len(chunk)), "application/octet-stream"); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Media.AppendChunk(ctx, m.ID, offset, int64(len(chunk))); err != nil {
+		switch err {
+		case store.ErrUploadOffsetMismatch:
+			app.conflictError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	m.UploadOffset += int64(len(chunk))
+	w.Header().Set("Upload-Offset", strconv.FormatInt(m.UploadOffset, 10))
+
+	if m.UploadOffset == m.Size {
+		if err := app.finishUpload(ctx, m); err != nil {
+			app.mediaError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, m); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetMedia godoc
+//
+//	@Summary		Fetches media details
+//	@Description	Fetches the details of media by ID
+//	@Tags			media
+//	@Produce		json
+//	@Param			id	path		int	true	"Media ID"
+//	@Success		200	{object}	store.Media
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/media/{id} [get]
+func (app *application) getMediaHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, getMediaFromCtx(r)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetMediaContent godoc
+//
+//	@Summary		Fetches the media file
+//	@Description	Streams the stored image, without its original metadata
+//	@Tags			media
+//	@Produce		image/jpeg,image/png,image/gif,image/webp
+//	@Param			id	path		int	true	"Media ID"
+//	@Success		200	{file}		file
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/media/{id}/content [get]
+func (app *application) getMediaContentHandler(w http.ResponseWriter, r *http.Request) {
+	m := getMediaFromCtx(r)
+	app.serveBlob(w, r, store.MediaKey(m.ID), m.ContentType)
+}
+
+// GetMediaThumbnail godoc
+//
+//	@Summary		Fetches the media thumbnail
+//	@Description	Streams the JPEG thumbnail of the media
+//	@Tags			media
+//	@Produce		image/jpeg
+//	@Param			id	path		int	true	"Media ID"
+//	@Success		200	{file}		file
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/media/{id}/thumbnail [get]
+func (app *application) getMediaThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	app.serveBlob(w, r, store.ThumbnailKey(getMediaFromCtx(r).ID), media.TypeJPEG)
+}
+
+func (app *application) serveBlob(w http.ResponseWriter, r *http.Request, key, contentType string) {
+	if getMediaFromCtx(r).Status != store.MediaStatusReady {
+		app.notFoundError(w, r, store.ErrorNotFound)
+		return
+	}
+
+	rc, err := app.blobs.Get(r.Context(), key)
+	if err != nil {
+		switch {
+		case errors.Is(err, blob.ErrNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if _, err := io.Copy(w, rc); err != nil {
+		app.logger.Warnw("streaming media", "key", key, "error", err)
+	}
+}
+
+// mediaContextMiddleware loads the media of the URL. Media that is not
+// attached yet is only visible to its uploader; attached media is visible
+// to whoever can see its post.
+func (app *application) mediaContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "mediaId"), 10, 64)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+		m, err := app.store.Media.GetById(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrorNotFound):
+				app.notFoundError(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		user := getUserFromContext(r)
+		if m.UserID != user.ID {
+			allowed := false
+			if m.PostID != nil {
+				post, err := app.getPost(ctx, *m.PostID)
+				if err != nil && !errors.Is(err, store.ErrorNotFound) {
+					app.internalServerError(w, r, err)
+					return
+				}
+
+				if post != nil {
+					allowed, err = app.canViewPost(ctx, user, post)
+					if err != nil {
+						app.internalServerError(w, r, err)
+						return
+					}
+				}
+			}
+
+			if !allowed {
+				app.notFoundError(w, r, store.ErrorNotFound)
+				return
+			}
+		}
+
+		ctx = context.WithValue(ctx, MediaCtx, m)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getMediaFromCtx(r *http.Request) *store.Media {
+	m, _ := r.Context().Value(MediaCtx).(*store.Media)
+	return m
+}
+
+// storeMedia writes a processed image and its thumbnail to the blob store
+// and marks m ready.
+func (app *application) storeMedia(ctx context.Context, m *store.Media, img *media.Image) error {
+	if err := app.blobs.Put(ctx, store.MediaKey(m.ID), bytes.NewReader(img.Data), int64(len(img.Data)), img.ContentType); err != nil {
+		return err
+	}
+
+	if err := app.blobs.Put(ctx, store.ThumbnailKey(m.ID), bytes.NewReader(img.Thumbnail), int64(len(img.Thumbnail)), media.TypeJPEG); err != nil {
+		return err
+	}
+
+	m.ContentType = img.ContentType
+	m.Size = int64(len(img.Data))
+	m.Width = img.Width
+	m.Height = img.Height
+
+	return app.store.Media.Complete(ctx, m)
+}
+
+// finishUpload assembles the chunks of a completed resumable upload and
+// processes it like a single upload. Uploads that turn out not to be valid
+// images are deleted.
+func (app *application) finishUpload(ctx context.Context, m *store.Media) error {
+	if err := app.store.Media.SetStatus(ctx, m.ID, store.MediaStatusProcessing); err != nil {
+		return err
+	}
+	m.Status = store.MediaStatusProcessing
+
+	chunks, err := app.store.Media.Chunks(ctx, m.ID)
+	if err != nil {
+		return err
+	}
+
+	var data bytes.Buffer
+	data.Grow(int(m.Size))
+	for _, c := range chunks {
+		rc, err := app.blobs.Get(ctx, store.ChunkKey(m.ID, c.Offset))
+		if err != nil {
+			return err
+		}
+
+		_, err = data.ReadFrom(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	img, err := media.Process(data.Bytes(), app.conf.Media.ThumbnailSize)
+	if err != nil {
+		if deleteErr := app.deleteMedia(ctx, m, chunks); deleteErr != nil {
+			app.logger.Warnw("deleting rejected upload", "media_id", m.ID, "error", deleteErr)
+		}
+		return err
+	}
+
+	if err := app.storeMedia(ctx, m, img); err != nil {
+		return err
+	}
+
+	for _, c := range chunks {
+		if err := app.blobs.Delete(ctx, store.ChunkKey(m.ID, c.Offset)); err != nil {
+			app.logger.Warnw("deleting upload chunk", "media_id", m.ID, "offset", c.Offset, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// deleteMedia removes the record of m and then its blobs, including
+// leftover upload chunks. Media attached to a post in the meantime is kept,
+// and ErrorNotFound returned.
+func (app *application) deleteMedia(ctx context.Context, m *store.Media, chunks []store.MediaChunk) error {
+	if err := app.store.Media.Delete(ctx, m.ID); err != nil {
+		return err
+	}
+
+	keys := []string{store.MediaKey(m.ID), store.ThumbnailKey(m.ID)}
+	for _, c := range chunks {
+		keys = append(keys, store.ChunkKey(m.ID, c.Offset))
+	}
+
+	for _, key := range keys {
+		if err := app.blobs.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sweepMedia periodically deletes media that has not been attached to a
+// post for the configured grace period: abandoned uploads, media never used
+// and media whose post was deleted or edited.
+func (app *application) sweepMedia(ctx context.Context) error {
+	conf := app.conf.Media
+
+	ticker := time.NewTicker(conf.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		orphaned, err := app.store.Media.ListOrphaned(ctx, time.Now().Add(-conf.OrphanTTL), 100)
+		if err != nil {
+			app.logger.Errorw("listing orphaned media", "error", err)
+			continue
+		}
+
+		for i := range orphaned {
+			m := &orphaned[i]
+
+			chunks, err := app.store.Media.Chunks(ctx, m.ID)
+			if err == nil {
+				err = app.deleteMedia(ctx, m, chunks)
+			}
+			if err != nil && !errors.Is(err, store.ErrorNotFound) {
+				app.logger.Errorw("deleting orphaned media", "media_id", m.ID, "error", err)
+			}
+		}
+	}
+}
+
+// mediaError answers with the status matching a media.Process error.
+func (app *application) mediaError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case media.ErrUnsupportedType:
+		app.unsupportedMediaTypeError(w, r, err)
+	case media.ErrMalformed, media.ErrTooManyPixels:
+		app.badRequestError(w, r, err)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,514 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/babaYaga451/social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type ConversationKey string
+
+const ConversationCtx ConversationKey = "conversation"
+
+// events written to the message streams of users
+const (
+	eventMessage = "message"
+	eventRead    = "read"
+	eventUnread  = "unread"
+)
+
+var (
+	errConversationSelf  = errors.New("conversations need at least one other member")
+	errConversationSize  = errors.New("too many members for a group conversation")
+	errDirectTitle       = errors.New("only group conversations have a title")
+	errStreamUnsupported = errors.New("streaming is not supported")
+)
+
+type CreateConversationPayload struct {
+	MemberIDs []int64 `json:"member_ids" validate:"required,min=1,dive,gt=0"`
+	Title     string  `json:"title" validate:"max=100"`
+}
+
+type SendMessagePayload struct {
+	Content string `json:"content" validate:"required,max=2000"`
+}
+
+type MarkReadPayload struct {
+	MessageID int64 `json:"message_id" validate:"required,gt=0"`
+}
+
+// MessagePage is a page of messages and the cursor to the next one, unset
+// on the last page.
+type MessagePage struct {
+	Messages   []store.Message `json:"messages"`
+	NextCursor *int64          `json:"next_cursor,omitempty"`
+}
+
+// readReceipt tells the members of a conversation how far one of them read.
+type readReceipt struct {
+	ConversationID    int64 `json:"conversation_id"`
+	UserID            int64 `json:"user_id"`
+	LastReadMessageID int64 `json:"last_read_message_id"`
+}
+
+// CreateConversation godoc
+//
+//	@Summary		Starts a conversation
+//	@Description	Starts a 1:1 conversation with one other user, or a group conversation with several. A pair of users shares one 1:1 conversation, which is returned if it exists. A conversation cannot include two users who blocked each other
+//	@Tags			messages
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateConversationPayload	true	"Conversation payload"
+//	@Success		201		{object}	store.Conversation
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/conversations [post]
+func (app *application) createConversationHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateConversationPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	others := slices.DeleteFunc(slices.Compact(slices.Sorted(slices.Values(payload.MemberIDs))), func(id int64) bool {
+		return id == user.ID
+	})
+	switch {
+	case len(others) == 0:
+		app.badRequestError(w, r, errConversationSelf)
+		return
+	case len(others)+1 > app.conf.Messages.MaxGroupSize:
+		app.badRequestError(w, r, errConversationSize)
+		return
+	case len(others) == 1 && payload.Title != "":
+		app.badRequestError(w, r, errDirectTitle)
+		return
+	}
+
+	// no two members of a conversation may have blocked each other
+	blocked, err := app.store.Blocks.AnyBlocked(ctx, append([]int64{user.ID}, others...))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if blocked {
+		app.forbiddenErrorResponse(w, r)
+		return
+	}
+
+	conversation := &store.Conversation{
+		Title:     payload.Title,
+		IsGroup:   len(others) > 1,
+		CreatedBy: user.ID,
+	}
+	if err := app.store.Messages.CreateConversation(ctx, conversation, others); err != nil {
+		switch err {
+		case store.ErrorNotFound:
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	conversation, err = app.store.Messages.GetConversation(ctx, conversation.ID, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, conversation); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetConversations godoc
+//
+//	@Summary		Lists conversations
+//	@Description	Lists the conversations of the current user with their last message and unread count, the most recently active first by default
+//	@Tags			messages
+//	@Produce		json
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Param			sort	query		string	false	"Sort (asc, desc)"
+//	@Success		200		{object}	[]store.Conversation
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/conversations [get]
+func (app *application) getConversationsHandler(w http.ResponseWriter, r *http.Request) {
+	lq := store.PaginatedListQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "desc",
+	}
+
+	lq, err := lq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(lq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	conversations, err := app.store.Messages.Conversations(r.Context(), getUserFromContext(r).ID, lq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, conversations); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetUnreadCount godoc
+//
+//	@Summary		Counts unread messages
+//	@Description	Counts the messages the current user has not read yet and the conversations they are in
+//	@Tags			messages
+//	@Produce		json
+//	@Success		200	{object}	store.UnreadCount
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/conversations/unread [get]
+func (app *application) getUnreadCountHandler(w http.ResponseWriter, r *http.Request) {
+	unread, err := app.store.Messages.Unread(r.Context(), getUserFromContext(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, unread); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetConversation godoc
+//
+//	@Summary		Fetches a conversation
+//	@Description	Fetches a conversation of the current user with its members and their read receipts
+//	@Tags			messages
+//	@Produce		json
+//	@Param			conversationId	path		int	true	"Conversation ID"
+//	@Success		200				{object}	store.Conversation
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/conversations/{conversationId} [get]
+func (app *application) getConversationHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, getConversationFromCtx(r)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetMessages godoc
+//
+//	@Summary		Lists the messages of a conversation
+//	@Description	Pages back through a conversation newest first, starting before the message ID in before (the next_cursor of the previous page). With after instead, returns the messages newer than that ID oldest first, to catch up
+//	@Tags			messages
+//	@Produce		json
+//	@Param			conversationId	path		int	true	"Conversation ID"
+//	@Param			limit			query		int	false	"Limit"
+//	@Param			before			query		int	false	"Return messages older than this ID"
+//	@Param			after			query		int	false	"Return messages newer than this ID"
+//	@Success		200				{object}	MessagePage
+//	@Failure		400				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/conversations/{conversationId}/messages [get]
+func (app *application) getMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	mq := store.PaginatedMessageQuery{
+		Limit: 50,
+	}
+
+	mq, err := mq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(mq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	messages, err := app.store.Messages.GetMessages(r.Context(), getConversationFromCtx(r).ID, mq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	page := MessagePage{Messages: messages}
+	if len(messages) == mq.Limit {
+		next := messages[len(messages)-1].ID
+		page.NextCursor = &next
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, page); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// SendMessage godoc
+//
+//	@Summary		Sends a message
+//	@Description	Sends a message to a conversation. Members with an open stream get it right away; the others find it in their unread counts. Members who blocked another member, or were blocked by one, cannot message in it
+//	@Tags			messages
+//	@Accept			json
+//	@Produce		json
+//	@Param			conversationId	path		int					true	"Conversation ID"
+//	@Param			payload			body		SendMessagePayload	true	"Message payload"
+//	@Success		201				{object}	store.Message
+//	@Failure		400				{object}	error
+//	@Failure		403				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/conversations/{conversationId}/messages [post]
+func (app *application) sendMessageHandler(w http.ResponseWriter, r *http.Request) {
+	var payload SendMessagePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	conversation := getConversationFromCtx(r)
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	// members who blocked each other after the conversation started can no
+	// longer write to it, in groups as in 1:1 conversations
+	blocked, err := app.store.Blocks.Blocked(ctx, user.ID, memberIDs(conversation))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if blocked {
+		app.forbiddenErrorResponse(w, r)
+		return
+	}
+
+	message := &store.Message{
+		ConversationID: conversation.ID,
+		SenderID:       user.ID,
+		Content:        payload.Content,
+	}
+	if err := app.store.Messages.CreateMessage(ctx, message); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// the sender gets it too, for their other devices
+	app.publish(ctx, memberIDs(conversation), eventMessage, message)
+
+	if err := app.jsonResponse(w, http.StatusCreated, message); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// MarkRead godoc
+//
+//	@Summary		Marks a conversation read
+//	@Description	Moves the read receipt of the current user up to the given message. Receipts never move back, and the members with an open stream are told when one moves
+//	@Tags			messages
+//	@Accept			json
+//	@Produce		json
+//	@Param			conversationId	path		int				true	"Conversation ID"
+//	@Param			payload			body		MarkReadPayload	true	"Newest message read"
+//	@Success		204				{string}	string			"Conversation marked read"
+//	@Failure		400				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/conversations/{conversationId}/read [put]
+func (app *application) markReadHandler(w http.ResponseWriter, r *http.Request) {
+	var payload MarkReadPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	conversation := getConversationFromCtx(r)
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	lastRead, err := app.store.Messages.MarkRead(ctx, conversation.ID, user.ID, payload.MessageID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if lastRead > 0 {
+		app.publish(ctx, memberIDs(conversation), eventRead, readReceipt{
+			ConversationID:    conversation.ID,
+			UserID:            user.ID,
+			LastReadMessageID: lastRead,
+		})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// StreamMessages godoc
+//
+//	@Summary		Streams messaging events
+//	@Description	Opens a server-sent events stream of the current user's messaging events: unread (the counts, sent first), message (a new message in one of their conversations) and read (a read receipt of another member). The server ends streams after a while; clients reconnect and catch up with the after cursor of the messages
+//	@Tags			messages
+//	@Produce		text/event-stream
+//	@Success		200	{string}	string	"Event stream"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/conversations/stream [get]
+func (app *application) streamMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	// subscribe first so nothing is missed between the counts and the stream
+	events, unsubscribe := app.realtime.Subscribe(user.ID)
+	defer unsubscribe()
+
+	unread, err := app.store.Messages.Unread(ctx, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// the stream outlives the server write timeout; the request timeout
+	// and server shutdown still end it
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		app.internalServerError(w, r, errStreamUnsupported)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	data, err := json.Marshal(unread)
+	if err != nil {
+		return
+	}
+	if _, err := fmt.Fprint(w, "retry: 2000\n\n"); err != nil {
+		return
+	}
+	if err := writeEvent(w, eventUnread, data); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(app.conf.Messages.StreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-app.shuttingDown:
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event := <-events:
+			if err := writeEvent(w, event.Type, event.Data); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes a server-sent event of the given type carrying data,
+// which must be single-line JSON.
+func writeEvent(w http.ResponseWriter, eventType string, data []byte) error {
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, data)
+	return err
+}
+
+// publish streams an event to userIDs. Delivery is best effort: missed
+// events are still reflected by the stored messages and unread counts.
+func (app *application) publish(ctx context.Context, userIDs []int64, eventType string, data any) {
+	if err := app.realtime.Publish(ctx, userIDs, eventType, data); err != nil {
+		app.logger.Warnw("publishing event", "type", eventType, "error", err)
+	}
+}
+
+// memberIDs returns the user IDs of the members of a conversation.
+func memberIDs(c *store.Conversation) []int64 {
+	ids := make([]int64, len(c.Members))
+	for i, m := range c.Members {
+		ids[i] = m.UserID
+	}
+	return ids
+}
+
+// conversationContextMiddleware loads the conversation of the URL as the
+// current user sees it. Conversations are private: anyone but their
+// members gets a not found.
+func (app *application) conversationContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "conversationId"), 10, 64)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+		conversation, err := app.store.Messages.GetConversation(ctx, id, getUserFromContext(r).ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrorNotFound):
+				app.notFoundError(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, ConversationCtx, conversation)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getConversationFromCtx(r *http.Request) *store.Conversation {
+	conversation, _ := r.Context().Value(ConversationCtx).(*store.Conversation)
+	return conversation
+}
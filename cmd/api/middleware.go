@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -34,8 +35,8 @@ func (app *application) BasicAuthMiddleware() func(http.Handler) http.Handler {
 				return
 			}
 
-			username := app.conf.auth.basic.user
-			pass := app.conf.auth.basic.pass
+			username := app.conf.Auth.Basic.User
+			pass := app.conf.Auth.Basic.Pass
 
 			creds := strings.SplitN(string(decoded), ":", 2)
 			if len(creds) != 2 || creds[0] != username || creds[1] != pass {
@@ -85,12 +86,51 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// tokens issued before the user's sessions were revoked carry an
+		// older version and are rejected
+		version, _ := claims["ver"].(float64)
+		if int(version) != user.TokenVersion {
+			app.unauthorizedErrorResponse(w, r, fmt.Errorf("token has been revoked"))
+			return
+		}
+
 		ctx = context.WithValue(ctx, userCtx, user)
+
+		if act, ok := claims["act"].(map[string]any); ok {
+			impersonatorID, err := strconv.ParseInt(fmt.Sprintf("%.f", act["sub"]), 10, 64)
+			if err != nil {
+				app.unauthorizedErrorResponse(w, r, err)
+				return
+			}
+			ctx = context.WithValue(ctx, impersonatorCtx, impersonatorID)
+		}
+
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-func (app *application) checkPostOwnership(requiredRole string, next http.HandlerFunc) http.HandlerFunc {
+func (app *application) requirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := getUserFromContext(r)
+
+			allowed, err := app.hasPermission(r.Context(), user, permission)
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+
+			if !allowed {
+				app.forbiddenErrorResponse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (app *application) checkPostOwnership(permission string, next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		user := getUserFromContext(r)
@@ -101,35 +141,69 @@ func (app *application) checkPostOwnership(requiredRole string, next http.Handle
 			return
 		}
 
-		allowed, err := app.checkRolePrecedence(r.Context(), user, requiredRole)
+		app.requirePermission(permission)(next).ServeHTTP(w, r)
+	})
+}
+
+func (app *application) hasPermission(ctx context.Context, user *store.User, permission string) (bool, error) {
+	rolePermissions, err := app.getRolePermissions(ctx, user.Role.ID)
+	if err != nil {
+		return false, err
+	}
+
+	if slices.Contains(rolePermissions, permission) {
+		return true, nil
+	}
+
+	userPermissions, err := app.getUserPermissions(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.Contains(userPermissions, permission), nil
+}
+
+func (app *application) getRolePermissions(ctx context.Context, roleID int64) ([]string, error) {
+	permissions, err := app.cacheStorage.Permissions.GetByRole(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if permissions == nil {
+		permissions, err = app.store.Permissions.GetByRoleID(ctx, roleID)
 		if err != nil {
-			app.internalServerError(w, r, err)
-			return
+			return nil, err
 		}
 
-		if !allowed {
-			app.forbiddenErrorResponse(w, r)
-			return
+		if err := app.cacheStorage.Permissions.SetByRole(ctx, roleID, permissions); err != nil {
+			return nil, err
 		}
+	}
 
-		next.ServeHTTP(w, r)
-	})
+	return permissions, nil
 }
 
-func (app *application) checkRolePrecedence(ctx context.Context, user *store.User, roleName string) (bool, error) {
-	role, err := app.store.Roles.GetByName(ctx, roleName)
+func (app *application) getUserPermissions(ctx context.Context, userID int64) ([]string, error) {
+	permissions, err := app.cacheStorage.Permissions.GetByUser(ctx, userID)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	return user.Role.Level >= role.Level, nil
-}
+	if permissions == nil {
+		permissions, err = app.store.Permissions.GetByUserID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
 
-func (app *application) getUser(ctx context.Context, userID int64) (*store.User, error) {
-	if !app.conf.redis.enabled {
-		return app.store.Users.GetById(ctx, userID)
+		if err := app.cacheStorage.Permissions.SetByUser(ctx, userID, permissions); err != nil {
+			return nil, err
+		}
 	}
 
+	return permissions, nil
+}
+
+func (app *application) getUser(ctx context.Context, userID int64) (*store.User, error) {
 	user, err := app.cacheStorage.User.Get(ctx, userID)
 	if err != nil {
 		return nil, err
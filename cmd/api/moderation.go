@@ -0,0 +1,409 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/babaYaga451/social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type CreateReportPayload struct {
+	Reason  string `json:"reason" validate:"required,oneof=spam harassment hate violence nudity misinformation other"`
+	Details string `json:"details" validate:"max=1000"`
+}
+
+type ResolveReportPayload struct {
+	Action      string `json:"action" validate:"required,oneof=dismiss hide delete warn suspend"`
+	Note        string `json:"note" validate:"max=1000"`
+	SuspendDays int    `json:"suspend_days" validate:"required_if=Action suspend,gte=0,lte=365"`
+}
+
+// ReportPost godoc
+//
+//	@Summary		Reports a post
+//	@Description	Flags a post for review by moderators
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Post ID"
+//	@Param			payload	body		CreateReportPayload	true	"Report payload"
+//	@Success		201		{object}	store.Report
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/report [post]
+func (app *application) reportPostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+
+	app.createReport(w, r, store.ReportTargetPost, post.ID, post.UserID)
+}
+
+// ReportComment godoc
+//
+//	@Summary		Reports a comment
+//	@Description	Flags a comment for review by moderators
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int					true	"Post ID"
+//	@Param			commentId	path		int					true	"Comment ID"
+//	@Param			payload		body		CreateReportPayload	true	"Report payload"
+//	@Success		201			{object}	store.Report
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments/{commentId}/report [post]
+func (app *application) reportCommentHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentId"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	comment, err := app.store.Comment.GetById(r.Context(), commentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if comment.PostID != post.ID || comment.HiddenAt != nil {
+		app.notFoundError(w, r, store.ErrorNotFound)
+		return
+	}
+
+	app.createReport(w, r, store.ReportTargetComment, comment.ID, comment.UserID)
+}
+
+func (app *application) createReport(w http.ResponseWriter, r *http.Request, targetType string, targetID, targetUserID int64) {
+	var payload CreateReportPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	report := &store.Report{
+		ReporterID:   getUserFromContext(r).ID,
+		TargetType:   targetType,
+		TargetID:     targetID,
+		TargetUserID: targetUserID,
+		Reason:       payload.Reason,
+		Details:      payload.Details,
+	}
+
+	if err := app.store.Reports.Create(r.Context(), report); err != nil {
+		switch err {
+		case store.ErrDuplicateReport:
+			app.badRequestError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, report); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ListReports godoc
+//
+//	@Summary		Lists reports
+//	@Description	Paginated moderation queue, oldest open reports first by default
+//	@Tags			moderation
+//	@Produce		json
+//	@Param			limit		query		int		false	"Limit"
+//	@Param			offset		query		int		false	"Offset"
+//	@Param			sort		query		string	false	"Sort by creation date (asc, desc)"
+//	@Param			status		query		string	false	"Status (open, resolved)"
+//	@Param			target_type	query		string	false	"Target type (post, comment)"
+//	@Success		200			{object}	[]store.Report
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/reports [get]
+func (app *application) listReportsHandler(w http.ResponseWriter, r *http.Request) {
+	rq := store.PaginatedReportQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "asc",
+		Status: store.ReportStatusOpen,
+	}
+
+	rq, err := rq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(rq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	reports, err := app.store.Reports.List(r.Context(), rq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, reports); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ResolveReport godoc
+//
+//	@Summary		Resolves a report
+//	@Description	Applies a moderator action and closes every open report on the same content. Hiding or deleting a comment also requires comment.moderate
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			reportId	path		int						true	"Report ID"
+//	@Param			payload		body		ResolveReportPayload	true	"Resolution payload"
+//	@Success		200			{object}	store.Report
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/reports/{reportId}/resolve [post]
+func (app *application) resolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	reportID, err := strconv.ParseInt(chi.URLParam(r, "reportId"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	var payload ResolveReportPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	report, err := app.store.Reports.GetById(ctx, reportID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if report.Status != store.ReportStatusOpen {
+		app.badRequestError(w, r, errors.New("report is already resolved"))
+		return
+	}
+
+	// hiding or removing someone else's comment takes comment.moderate on
+	// top of report.manage
+	if report.TargetType == store.ReportTargetComment &&
+		(payload.Action == store.ReportActionHide || payload.Action == store.ReportActionDelete) {
+		allowed, err := app.hasPermission(ctx, getUserFromContext(r), store.PermissionCommentModerate)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		if !allowed {
+			app.forbiddenErrorResponse(w, r)
+			return
+		}
+	}
+
+	moderator := getUserFromContext(r)
+
+	// moderators cannot suspend anyone at or above their own level
+	if payload.Action == store.ReportActionSuspend {
+		target, err := app.store.Users.GetById(ctx, report.TargetUserID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrorNotFound):
+				app.notFoundError(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		if !outranks(moderator, target) {
+			app.forbiddenErrorResponse(w, r)
+			return
+		}
+	}
+
+	if err := app.applyReportAction(r, report, payload); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	report.Resolution = &payload.Action
+	report.ResolutionNote = payload.Note
+	report.ResolvedBy = &moderator.ID
+
+	// a suspension is written together with the resolution
+	if payload.Action == store.ReportActionSuspend {
+		until := time.Now().Add(time.Hour * 24 * time.Duration(payload.SuspendDays))
+		err = app.store.Reports.ResolveWithSuspension(ctx, report, until)
+		app.invalidateUserCache(ctx, report.TargetUserID)
+	} else {
+		err = app.store.Reports.Resolve(ctx, report)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	metadata := map[string]any{
+		"action":      payload.Action,
+		"note":        payload.Note,
+		"target_type": report.TargetType,
+		"target_id":   report.TargetID,
+	}
+	if payload.Action == store.ReportActionSuspend {
+		metadata["suspend_days"] = payload.SuspendDays
+	}
+	app.recordAudit(r, store.AuditActionReportResolve, store.AuditTargetReport, report.ID, metadata)
+
+	if err := app.jsonResponse(w, http.StatusOK, report); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetWarnings godoc
+//
+//	@Summary		Lists warnings
+//	@Description	Lists the warnings moderators gave the current user, newest first by default
+//	@Tags			moderation
+//	@Produce		json
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Param			sort	query		string	false	"Sort (asc, desc)"
+//	@Success		200		{object}	[]store.Warning
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/warnings [get]
+func (app *application) getWarningsHandler(w http.ResponseWriter, r *http.Request) {
+	lq := store.PaginatedListQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "desc",
+	}
+
+	lq, err := lq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(lq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	warnings, err := app.store.Reports.Warnings(r.Context(), getUserFromContext(r).ID, lq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, warnings); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// applyReportAction carries out the moderator decision on the reported
+// content or its author. Content that is already gone is not an error.
+// Suspensions are left to ResolveWithSuspension.
+func (app *application) applyReportAction(r *http.Request, report *store.Report, payload ResolveReportPayload) error {
+	ctx := r.Context()
+
+	// the post of a comment has to be known before the comment is deleted
+	// to drop the cached comment list afterwards
+	var commentPostID int64
+	if report.TargetType == store.ReportTargetComment {
+		comment, err := app.store.Comment.GetById(ctx, report.TargetID)
+		switch {
+		case err == nil:
+			commentPostID = comment.PostID
+		case !errors.Is(err, store.ErrorNotFound):
+			return err
+		}
+	}
+
+	var err error
+	switch payload.Action {
+	case store.ReportActionDismiss:
+		// content held back by the filter is released when its report is dismissed
+		if report.Reason != store.ReportReasonFilter {
+			break
+		}
+		if report.TargetType == store.ReportTargetPost {
+			err = app.store.Posts.SetHidden(ctx, report.TargetID, false)
+		} else {
+			err = app.store.Comment.SetHidden(ctx, report.TargetID, false)
+		}
+	case store.ReportActionHide:
+		if report.TargetType == store.ReportTargetPost {
+			err = app.store.Posts.SetHidden(ctx, report.TargetID, true)
+		} else {
+			err = app.store.Comment.SetHidden(ctx, report.TargetID, true)
+		}
+	case store.ReportActionDelete:
+		if report.TargetType == store.ReportTargetPost {
+			err = app.store.Posts.Delete(ctx, report.TargetID)
+		} else {
+			err = app.store.Comment.Delete(ctx, report.TargetID)
+		}
+	case store.ReportActionWarn:
+		err = app.store.Reports.Warn(ctx, &store.Warning{
+			UserID:   report.TargetUserID,
+			ReportID: report.ID,
+			Reason:   report.Reason,
+			Note:     payload.Note,
+		})
+	}
+
+	if err != nil && !errors.Is(err, store.ErrorNotFound) {
+		return err
+	}
+
+	if report.TargetType == store.ReportTargetPost {
+		app.invalidatePost(ctx, report.TargetID, report.TargetUserID)
+	} else if commentPostID != 0 {
+		app.invalidateComments(ctx, commentPostID)
+	}
+
+	return nil
+}
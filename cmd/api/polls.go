@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/babaYaga451/social/internal/store"
+)
+
+var (
+	errPollEnd          = errors.New("polls must end in the future and within the allowed duration")
+	errPollOptionsEqual = errors.New("poll options must be different")
+	errSingleChoice     = errors.New("this poll allows a single choice")
+	errPollNotOpen      = errors.New("only polls of published posts can be voted on")
+)
+
+type PollPayload struct {
+	Options        []string  `json:"options" validate:"min=2,max=6,dive,required,max=100"`
+	EndsAt         time.Time `json:"ends_at" validate:"required"`
+	MultipleChoice bool      `json:"multiple_choice"`
+}
+
+type VotePayload struct {
+	OptionIDs []int64 `json:"option_ids" validate:"required,min=1,max=6,dive,gt=0"`
+}
+
+// newPoll checks payload and turns it into the poll of a post going public
+// at publishAt, or right away if it is nil.
+func (app *application) newPoll(payload *PollPayload, publishAt *time.Time) (*store.Poll, error) {
+	start := time.Now()
+	if publishAt != nil {
+		start = *publishAt
+	}
+
+	if !payload.EndsAt.After(start) || payload.EndsAt.Sub(start) > app.conf.Polls.MaxDuration {
+		return nil, errPollEnd
+	}
+
+	poll := &store.Poll{
+		MultipleChoice: payload.MultipleChoice,
+		EndsAt:         payload.EndsAt,
+	}
+
+	seen := map[string]bool{}
+	for _, text := range payload.Options {
+		text = strings.TrimSpace(text)
+		key := strings.ToLower(text)
+		if text == "" || seen[key] {
+			return nil, errPollOptionsEqual
+		}
+		seen[key] = true
+
+		poll.Options = append(poll.Options, store.PollOption{Text: text})
+	}
+
+	return poll, nil
+}
+
+// VotePoll godoc
+//
+//	@Summary		Votes in the poll of a post
+//	@Description	Casts the current user's ballot: one option, or several for multiple choice polls. Each user votes once; results show once voted or after the poll ended
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int			true	"Post ID"
+//	@Param			payload	body		VotePayload	true	"Vote payload"
+//	@Success		201		{object}	store.Poll
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/poll/votes [post]
+func (app *application) votePollHandler(w http.ResponseWriter, r *http.Request) {
+	var payload VotePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	post := getPostFromCtx(r)
+	if !post.Published() {
+		app.badRequestError(w, r, errPollNotOpen)
+		return
+	}
+
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	poll, err := app.getPoll(ctx, user.ID, post.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if poll == nil {
+		app.notFoundError(w, r, store.ErrorNotFound)
+		return
+	}
+
+	optionIDs := slices.Compact(slices.Sorted(slices.Values(payload.OptionIDs)))
+	if !poll.MultipleChoice && len(optionIDs) > 1 {
+		app.badRequestError(w, r, errSingleChoice)
+		return
+	}
+
+	if err := app.store.Polls.Vote(ctx, poll.ID, user.ID, optionIDs); err != nil {
+		switch err {
+		case store.ErrAlreadyVoted:
+			app.conflictError(w, r, err)
+		case store.ErrPollEnded, store.ErrInvalidPollOption:
+			app.badRequestError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	app.invalidateFeed(ctx, user.ID)
+
+	poll, err = app.getPoll(ctx, user.ID, post.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, poll); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getPoll returns the poll of a post as viewerID sees it, or nil if the
+// post has none.
+func (app *application) getPoll(ctx context.Context, viewerID, postID int64) (*store.Poll, error) {
+	polls, err := app.store.Polls.GetByPostIDs(ctx, viewerID, []int64{postID})
+	if err != nil {
+		return nil, err
+	}
+	return polls[postID], nil
+}
+
+// attachPolls adds the polls of a page of posts as viewerID sees them.
+func (app *application) attachPolls(ctx context.Context, viewerID int64, posts []store.PostWithMetaData) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+
+	polls, err := app.store.Polls.GetByPostIDs(ctx, viewerID, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		posts[i].Poll = polls[posts[i].ID]
+	}
+	return nil
+}
+
+// closePolls stores the final tallies of ended polls every configured
+// interval until ctx is cancelled.
+func (app *application) closePolls(ctx context.Context) error {
+	conf := app.conf.Polls
+
+	ticker := time.NewTicker(conf.CloseInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := app.closeEndedPolls(ctx); err != nil && ctx.Err() == nil {
+			app.logger.Errorw("closing polls", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// closeEndedPolls closes ended polls batch by batch until none is left.
+func (app *application) closeEndedPolls(ctx context.Context) error {
+	conf := app.conf.Polls
+
+	for {
+		closed, err := app.store.Polls.CloseEnded(ctx, conf.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, poll := range closed {
+			app.invalidatePost(ctx, poll.PostID, poll.AuthorID)
+		}
+
+		if len(closed) < conf.BatchSize {
+			return nil
+		}
+	}
+}
@@ -5,8 +5,13 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/babaYaga451/social/internal/filter"
+	"github.com/babaYaga451/social/internal/mentions"
 	"github.com/babaYaga451/social/internal/store"
+	"github.com/babaYaga451/social/internal/tags"
+	"github.com/babaYaga451/social/internal/unfurl"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -15,15 +20,20 @@ type PostKey string
 const PostCtx PostKey = "post"
 
 type CreatePostPayload struct {
-	Title   string   `json:"title" validate:"required,max=100"`
-	Content string   `json:"content" validate:"required,max=1000"`
-	Tags    []string `json:"tags"`
+	Title        string       `json:"title" validate:"required,max=100"`
+	Content      string       `json:"content" validate:"required,max=1000"`
+	Tags         []string     `json:"tags"`
+	MediaIDs     []int64      `json:"media_ids" validate:"max=4,dive,gt=0"`
+	Status       string       `json:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt    *time.Time   `json:"publish_at"`
+	QuotedPostID *int64       `json:"quoted_post_id" validate:"omitempty,gt=0"`
+	Poll         *PollPayload `json:"poll"`
 }
 
 // CreatePost godoc
 //
 //	@Summary		Creates a post
-//	@Description	Creates a post
+//	@Description	Creates a post, published right away unless it is a draft or scheduled for later. Setting quoted_post_id makes it a quote post embedding that post
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
@@ -45,19 +55,95 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if payload.Status == "" {
+		payload.Status = store.PostStatusPublished
+	}
+	if err := checkSchedule(payload.Status, payload.PublishAt); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	postTags, err := tags.NormalizeAll(payload.Tags)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
 	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	var poll *store.Poll
+	if payload.Poll != nil {
+		poll, err = app.newPoll(payload.Poll, payload.PublishAt)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+	}
+
+	if payload.QuotedPostID != nil {
+		if err := app.checkQuotable(ctx, user, *payload.QuotedPostID); err != nil {
+			switch err {
+			case errNotShareable:
+				app.badRequestError(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+	}
+
+	verdict, ok := app.checkContent(w, r, filter.Content{
+		Kind:     filter.KindPost,
+		AuthorID: user.ID,
+		Title:    payload.Title,
+		Body:     payload.Content,
+		Tags:     postTags,
+	})
+	if !ok {
+		return
+	}
 
 	post := &store.Post{
-		Title:   payload.Title,
-		Content: payload.Content,
-		UserID:  user.ID,
-		Tags:    payload.Tags,
+		Title:        payload.Title,
+		Content:      payload.Content,
+		UserID:       user.ID,
+		Tags:         postTags,
+		Status:       payload.Status,
+		PublishAt:    payload.PublishAt,
+		HiddenAt:     heldAt(verdict),
+		Labels:       verdict.Labels(),
+		Media:        mediaRefs(payload.MediaIDs),
+		QuotedPostID: payload.QuotedPostID,
+		Poll:         poll,
+		Entities:     app.mentionRefs(payload.Content),
 	}
-	ctx := r.Context()
-	if err := app.store.Posts.Create(ctx, post); err != nil {
+	if err := app.store.Posts.Create(ctx, post, app.conf.Timeline.CelebrityThreshold); err != nil {
+		switch err {
+		case store.ErrMediaUnavailable:
+			app.badRequestError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	if err := app.holdForModeration(ctx, store.ReportTargetPost, post.ID, post.UserID, verdict); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	if post.Published() {
+		app.invalidatePublished(ctx, post.UserID)
+	}
+	if len(unfurl.ExtractURLs(post.Content, 1)) > 0 {
+		app.enqueueUnfurl(post)
+	}
+
+	app.recordAudit(r, store.AuditActionPostCreate, store.AuditTargetPost, post.ID, map[string]any{
+		"title":  post.Title,
+		"held":   post.HiddenAt != nil,
+		"labels": post.Labels,
+	})
+
 	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -78,13 +164,34 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{id} [get]
 func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
-	post := getPostFromCtx(r)
-	comments, err := app.store.Comment.GetByPostID(r.Context(), post.ID)
+	// the post may be shared through the cache; the quote and the poll
+	// depend on who is looking, so they go on a copy
+	post := *getPostFromCtx(r)
+	ctx := r.Context()
+
+	comments, err := app.getComments(ctx, post.ID)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 	post.Comments = comments
+
+	user := getUserFromContext(r)
+
+	if post.QuotedPostID != nil {
+		post.Quote, err = app.quotedPost(ctx, user, *post.QuotedPostID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	post.Poll, err = app.getPoll(ctx, user.ID, post.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -121,18 +228,32 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 		}
 		return
 	}
+
+	post := getPostFromCtx(r)
+	app.invalidatePost(ctx, id, post.UserID)
+
+	app.recordAudit(r, store.AuditActionPostDelete, store.AuditTargetPost, id, map[string]any{
+		"owner_id": post.UserID,
+		"title":    post.Title,
+		"override": post.UserID != getUserFromContext(r).ID,
+	})
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
 type UpdatePostPayload struct {
-	Title   *string `json:"title" validate:"omitempty,max=100"`
-	Content *string `json:"content" validate:"omitempty,max=1000"`
+	Title     *string    `json:"title" validate:"omitempty,max=100"`
+	Content   *string    `json:"content" validate:"omitempty,max=1000"`
+	Tags      *[]string  `json:"tags"`
+	MediaIDs  *[]int64   `json:"media_ids" validate:"omitempty,max=4,dive,gt=0"`
+	Status    *string    `json:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt *time.Time `json:"publish_at"`
 }
 
 // UpdatePost godoc
 //
 //	@Summary		Updates a post
-//	@Description	Updates a post by ID
+//	@Description	Updates a post by ID. Drafts and scheduled posts can be rescheduled or published; published posts stay published
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
@@ -147,6 +268,7 @@ type UpdatePostPayload struct {
 //	@Router			/posts/{id} [patch]
 func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromCtx(r)
+	before := *post
 	var payload UpdatePostPayload
 	if err := readJSON(w, r, &payload); err != nil {
 		app.badRequestError(w, r, err)
@@ -162,10 +284,81 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 	if payload.Title != nil {
 		post.Title = *payload.Title
 	}
-	if err := app.updatePost(r.Context(), post); err != nil {
+	if payload.Tags != nil {
+		postTags, err := tags.NormalizeAll(*payload.Tags)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+		post.Tags = postTags
+	}
+	if payload.Status != nil || payload.PublishAt != nil {
+		if post.Published() {
+			app.badRequestError(w, r, errAlreadyPublished)
+			return
+		}
+
+		status := post.Status
+		if payload.Status != nil {
+			status = *payload.Status
+		}
+		// rescheduling keeps the stored time unless a new one is given
+		publishAt := payload.PublishAt
+		if publishAt == nil && status == store.PostStatusScheduled {
+			publishAt = post.PublishAt
+		}
+		if err := checkSchedule(status, publishAt); err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+		post.Status, post.PublishAt = status, publishAt
+	}
+
+	verdict, ok := app.checkContent(w, r, filter.Content{
+		Kind:     filter.KindPost,
+		AuthorID: post.UserID,
+		Title:    post.Title,
+		Body:     post.Content,
+		Tags:     post.Tags,
+	})
+	if !ok {
+		return
+	}
+	post.Labels = verdict.Labels()
+
+	if payload.MediaIDs != nil {
+		post.Media = mediaRefs(*payload.MediaIDs)
+	}
+
+	ctx := r.Context()
+	if err := app.updatePost(ctx, post, getUserFromContext(r).ID); err != nil {
+		switch err {
+		case store.ErrMediaUnavailable:
+			app.badRequestError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	if post.Published() && !before.Published() {
+		app.invalidatePublished(ctx, post.UserID)
+	}
+	if verdict.Action == filter.Hold && post.HiddenAt == nil {
+		if err := app.store.Posts.SetHidden(ctx, post.ID, true); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		post.HiddenAt = heldAt(verdict)
+		// updatePost cached the edit before it was held
+		app.invalidatePost(ctx, post.ID, post.UserID)
+	}
+	if err := app.holdForModeration(ctx, store.ReportTargetPost, post.ID, post.UserID, verdict); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+
+	app.recordAuditChange(r, store.AuditActionPostUpdate, store.AuditTargetPost, post.ID, before, post)
+
 	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -181,7 +374,7 @@ func (app *application) postsContextMiddleWare(next http.Handler) http.Handler {
 			return
 		}
 		ctx := r.Context()
-		post, err := app.store.Posts.GetById(ctx, id)
+		post, err := app.getPost(ctx, id)
 		if err != nil {
 			switch {
 			case errors.Is(err, store.ErrorNotFound):
@@ -191,18 +384,60 @@ func (app *application) postsContextMiddleWare(next http.Handler) http.Handler {
 			}
 			return
 		}
+
+		allowed, err := app.canViewPost(ctx, getUserFromContext(r), post)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !allowed {
+			app.notFoundError(w, r, store.ErrorNotFound)
+			return
+		}
+
 		ctx = context.WithValue(ctx, PostCtx, post)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// canViewPost reports whether user may see post: drafts and scheduled posts
+// are only visible to their author, hidden posts to their author and
+// moderators, and the posts of private users to them and their followers.
+func (app *application) canViewPost(ctx context.Context, user *store.User, post *store.Post) (bool, error) {
+	switch {
+	case post.UserID == user.ID:
+		return true, nil
+	case !post.Published():
+		return false, nil
+	}
+
+	if post.HiddenAt != nil {
+		allowed, err := app.hasPermission(ctx, user, store.PermissionReportManage)
+		if err != nil || !allowed {
+			return false, err
+		}
+	}
+
+	author, err := app.getUser(ctx, post.UserID)
+	switch {
+	case errors.Is(err, store.ErrorNotFound):
+		return false, nil
+	case err != nil:
+		return false, err
+	case !author.IsPrivate:
+		return true, nil
+	}
+
+	return app.store.Follower.IsFollowing(ctx, user.ID, author.ID)
+}
+
 func getPostFromCtx(r *http.Request) *store.Post {
 	post, _ := r.Context().Value(PostCtx).(*store.Post)
 	return post
 }
 
 type CreateCommentPayload struct {
-	UserID  int64  `json:"userId" validate:"required"`
 	Content string `json:"content" validate:"required"`
 }
 
@@ -231,27 +466,110 @@ func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Requ
 		app.badRequestError(w, r, err)
 		return
 	}
+	user := getUserFromContext(r)
+	verdict, ok := app.checkContent(w, r, filter.Content{
+		Kind:     filter.KindComment,
+		AuthorID: user.ID,
+		Body:     payload.Content,
+	})
+	if !ok {
+		return
+	}
+
 	comment := &store.Comment{
-		PostID:  post.ID,
-		UserID:  payload.UserID,
-		Content: payload.Content,
+		PostID:   post.ID,
+		UserID:   user.ID,
+		Content:  payload.Content,
+		HiddenAt: heldAt(verdict),
+		Labels:   verdict.Labels(),
+		Entities: app.mentionRefs(payload.Content),
 	}
 	ctx := r.Context()
 	if err := app.store.Comment.Create(ctx, comment); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	if err := app.holdForModeration(ctx, store.ReportTargetComment, comment.ID, comment.UserID, verdict); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.invalidateComments(ctx, post.ID)
 	if err := app.jsonResponse(w, http.StatusCreated, comment); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
 
-func (app *application) updatePost(ctx context.Context, post *store.Post) error {
-	if err := app.store.Posts.Update(ctx, post); err != nil {
+func (app *application) updatePost(ctx context.Context, post *store.Post, editorID int64) error {
+	post.Entities = app.mentionRefs(post.Content)
+	if err := app.store.Posts.Update(ctx, post, editorID, app.conf.Timeline.CelebrityThreshold); err != nil {
+		return err
+	}
+
+	app.cachePost(ctx, post)
+	app.invalidateFeed(ctx, post.UserID)
+	app.enqueueUnfurl(post)
+	return nil
+}
+
+// enrichPosts adds what listings show beyond the post rows: mentions, link
+// previews, and polls as viewerID sees them.
+func (app *application) enrichPosts(ctx context.Context, viewerID int64, posts []store.PostWithMetaData) error {
+	if err := app.attachEntities(ctx, posts); err != nil {
+		return err
+	}
+	if err := app.attachLinks(ctx, posts); err != nil {
+		return err
+	}
+	return app.attachPolls(ctx, viewerID, posts)
+}
+
+func (app *application) attachEntities(ctx context.Context, posts []store.PostWithMetaData) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+
+	entities, err := app.store.Posts.GetEntities(ctx, ids)
+	if err != nil {
 		return err
 	}
 
-	app.cacheStorage.User.Delete(ctx, post.UserID)
+	for i := range posts {
+		posts[i].Entities = entities[posts[i].ID]
+		if posts[i].Entities == nil {
+			posts[i].Entities = []store.Entity{}
+		}
+	}
 	return nil
 }
+
+// mentionRefs finds the @mentions in content, up to the number the content
+// filter lets through, as the stubs the post and comment stores resolve.
+func (app *application) mentionRefs(content string) []store.Entity {
+	found := mentions.Extract(content, app.conf.Filter.MaxMentions)
+
+	refs := make([]store.Entity, len(found))
+	for i, m := range found {
+		refs[i] = store.Entity{
+			Type:     store.EntityMention,
+			Start:    m.Start,
+			End:      m.End,
+			UserName: m.UserName,
+		}
+	}
+	return refs
+}
+
+// mediaRefs turns media IDs into the stubs store.PostStore.Create attaches.
+func mediaRefs(ids []int64) []store.Media {
+	refs := make([]store.Media, len(ids))
+	for i, id := range ids {
+		refs[i] = store.Media{ID: id}
+	}
+	return refs
+}
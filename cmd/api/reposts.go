@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/babaYaga451/social/internal/store"
+)
+
+var errNotShareable = errors.New("only published, visible posts of public accounts can be reposted or quoted")
+
+// RepostPost godoc
+//
+//	@Summary		Reposts a post
+//	@Description	Shares a post with the current user's followers. Published, visible posts of public accounts can be reposted; reposts go away with the original
+//	@Tags			posts
+//	@Produce		json
+//	@Param			id	path		int		true	"Post ID"
+//	@Success		204	{string}	string	"Post reposted"
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/repost [put]
+func (app *application) repostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	allowed, err := app.canSharePost(ctx, user, post)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if !allowed {
+		app.badRequestError(w, r, errNotShareable)
+		return
+	}
+
+	if err := app.store.Reposts.Create(ctx, user.ID, post.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.invalidatePost(ctx, post.ID, post.UserID)
+	app.invalidateFeed(ctx, user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UndoRepost godoc
+//
+//	@Summary		Undoes a repost
+//	@Description	Removes the current user's repost of a post
+//	@Tags			posts
+//	@Produce		json
+//	@Param			id	path		int		true	"Post ID"
+//	@Success		204	{string}	string	"Repost removed"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/repost [delete]
+func (app *application) undoRepostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	if err := app.store.Reposts.Delete(ctx, user.ID, post.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.invalidatePost(ctx, post.ID, post.UserID)
+	app.invalidateFeed(ctx, user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// canSharePost reports whether user may repost or quote post: it must be
+// published and not hidden, and posts of private accounts can only be
+// shared by their author.
+func (app *application) canSharePost(ctx context.Context, user *store.User, post *store.Post) (bool, error) {
+	if !post.Published() || post.HiddenAt != nil {
+		return false, nil
+	}
+
+	if post.UserID == user.ID {
+		return true, nil
+	}
+
+	author, err := app.getUser(ctx, post.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	return !author.IsPrivate, nil
+}
+
+// checkQuotable makes sure user may quote the post with the given ID.
+func (app *application) checkQuotable(ctx context.Context, user *store.User, id int64) error {
+	original, err := app.getPost(ctx, id)
+	switch {
+	case errors.Is(err, store.ErrorNotFound):
+		return errNotShareable
+	case err != nil:
+		return err
+	}
+
+	allowed, err := app.canViewPost(ctx, user, original)
+	if err != nil {
+		return err
+	}
+	if allowed {
+		allowed, err = app.canSharePost(ctx, user, original)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !allowed {
+		return errNotShareable
+	}
+	return nil
+}
+
+// quotedPost loads the original of a quote post as user may see it. Deleted
+// originals and those user may not view are marked unavailable.
+func (app *application) quotedPost(ctx context.Context, user *store.User, id int64) (*store.QuotedPost, error) {
+	quote := &store.QuotedPost{ID: id}
+
+	original, err := app.getPost(ctx, id)
+	switch {
+	case errors.Is(err, store.ErrorNotFound):
+		return quote, nil
+	case err != nil:
+		return nil, err
+	}
+
+	allowed, err := app.canViewPost(ctx, user, original)
+	if err != nil || !allowed {
+		return quote, err
+	}
+
+	quote.Available = true
+	quote.UserID = original.UserID
+	quote.Title = original.Title
+	quote.Content = original.Content
+	quote.CreatedAt = original.CreatedAt
+	return quote, nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/babaYaga451/social/internal/diff"
+	"github.com/babaYaga451/social/internal/store"
+)
+
+var errNoEarlierRevision = errors.New("there is no earlier revision to compare with")
+
+// revisionDiff shows what changed in a post between two versions.
+type revisionDiff struct {
+	From        int         `json:"from"`
+	To          int         `json:"to"`
+	Title       []diff.Edit `json:"title"`
+	Content     []diff.Edit `json:"content"`
+	AddedTags   []string    `json:"added_tags"`
+	RemovedTags []string    `json:"removed_tags"`
+}
+
+// GetPostRevisions godoc
+//
+//	@Summary		Lists the revisions of a post
+//	@Description	Lists every version of a post with who wrote it and when, newest first. Only the author and moderators can see them
+//	@Tags			posts
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		200	{object}	[]store.PostRevision
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/revisions [get]
+func (app *application) getPostRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+
+	revisions, err := app.store.Revisions.GetByPostID(r.Context(), post.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, revisions); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetPostRevisionDiff godoc
+//
+//	@Summary		Compares two revisions of a post
+//	@Description	Shows the word-level changes of title and content and the tag changes between two versions of a post. By default the latest version is compared with the one before it. Only the author and moderators can see them
+//	@Tags			posts
+//	@Produce		json
+//	@Param			id		path		int	true	"Post ID"
+//	@Param			from	query		int	false	"Older version, defaults to the one before to"
+//	@Param			to		query		int	false	"Newer version, defaults to the latest"
+//	@Success		200		{object}	revisionDiff
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/revisions/diff [get]
+func (app *application) getPostRevisionDiffHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+
+	revisions, err := app.store.Revisions.GetByPostID(r.Context(), post.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if len(revisions) == 0 {
+		app.notFoundError(w, r, store.ErrorNotFound)
+		return
+	}
+
+	// revisions are newest first
+	toIdx := 0
+	if v := r.URL.Query().Get("to"); v != "" {
+		toIdx, err = revisionIndex(revisions, v)
+		if err != nil {
+			app.revisionError(w, r, err)
+			return
+		}
+	}
+
+	fromIdx := toIdx + 1
+	if v := r.URL.Query().Get("from"); v != "" {
+		fromIdx, err = revisionIndex(revisions, v)
+		if err != nil {
+			app.revisionError(w, r, err)
+			return
+		}
+	}
+
+	if fromIdx >= len(revisions) {
+		app.badRequestError(w, r, errNoEarlierRevision)
+		return
+	}
+
+	from, to := revisions[fromIdx], revisions[toIdx]
+
+	result := revisionDiff{
+		From:        from.Version,
+		To:          to.Version,
+		Title:       diff.Words(from.Title, to.Title),
+		Content:     diff.Words(from.Content, to.Content),
+		AddedTags:   missingFrom(from.Tags, to.Tags),
+		RemovedTags: missingFrom(to.Tags, from.Tags),
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, result); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// revisionIndex finds the revision with the version given in v.
+func revisionIndex(revisions []store.PostRevision, v string) (int, error) {
+	version, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, err
+	}
+
+	i := slices.IndexFunc(revisions, func(r store.PostRevision) bool {
+		return r.Version == version
+	})
+	if i < 0 {
+		return 0, store.ErrorNotFound
+	}
+	return i, nil
+}
+
+func (app *application) revisionError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrorNotFound):
+		app.notFoundError(w, r, err)
+	default:
+		app.badRequestError(w, r, err)
+	}
+}
+
+// missingFrom returns the values of b that are not in a.
+func missingFrom(a, b []string) []string {
+	missing := []string{}
+	for _, v := range b {
+		if !slices.Contains(a, v) {
+			missing = append(missing, v)
+		}
+	}
+	return missing
+}
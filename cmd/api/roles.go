@@ -0,0 +1,458 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/babaYaga451/social/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// ListPermissions godoc
+//
+//	@Summary		Lists permissions
+//	@Description	Lists every permission that can be assigned to roles or users
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	[]store.Permission
+//	@Failure		403	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/permissions [get]
+func (app *application) listPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	permissions, err := app.store.Permissions.List(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, permissions); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ListRoles godoc
+//
+//	@Summary		Lists roles
+//	@Description	Lists roles together with their permissions
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	[]store.Role
+//	@Failure		403	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/roles [get]
+func (app *application) listRolesHandler(w http.ResponseWriter, r *http.Request) {
+	roles, err := app.store.Roles.List(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, roles); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type CreateRolePayload struct {
+	Name        string   `json:"name" validate:"required,max=255"`
+	Description string   `json:"description" validate:"max=1000"`
+	Level       int      `json:"level" validate:"gte=0"`
+	Permissions []string `json:"permissions"`
+}
+
+// CreateRole godoc
+//
+//	@Summary		Creates a role
+//	@Description	Creates a role with an optional set of permissions. Admins can only create roles up to their own level with permissions they hold
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateRolePayload	true	"Role payload"
+//	@Success		201		{object}	store.Role
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/roles [post]
+func (app *application) createRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateRolePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	admin := getUserFromContext(r)
+
+	// role.manage does not let anyone create a role above their own level
+	// or hand out permissions they do not have themselves
+	if payload.Level > admin.Role.Level {
+		app.forbiddenErrorResponse(w, r)
+		return
+	}
+
+	held, err := app.holdsPermissions(ctx, admin, payload.Permissions)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if !held {
+		app.forbiddenErrorResponse(w, r)
+		return
+	}
+
+	role := &store.Role{
+		Name:        payload.Name,
+		Description: payload.Description,
+		Level:       payload.Level,
+		Permissions: payload.Permissions,
+	}
+
+	if err := app.store.Roles.Create(ctx, role); err != nil {
+		switch err {
+		case store.ErrDuplicateRole, store.ErrUnknownPermission:
+			app.badRequestError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.recordAudit(r, store.AuditActionRoleCreate, store.AuditTargetRole, role.ID, map[string]any{
+		"name":        role.Name,
+		"permissions": role.Permissions,
+	})
+
+	if err := app.jsonResponse(w, http.StatusCreated, role); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type SetRolePermissionsPayload struct {
+	Permissions []string `json:"permissions" validate:"required"`
+}
+
+// SetRolePermissions godoc
+//
+//	@Summary		Replaces the permissions of a role
+//	@Description	Replaces the full set of permissions assigned to a role up to the admin's own level. Admins can only assign permissions they hold
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			roleName	path		string						true	"Role name"
+//	@Param			payload		body		SetRolePermissionsPayload	true	"Permissions payload"
+//	@Success		200			{object}	store.Role
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/roles/{roleName}/permissions [put]
+func (app *application) setRolePermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	var payload SetRolePermissionsPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	role, err := app.store.Roles.GetByName(ctx, chi.URLParam(r, "roleName"))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	admin := getUserFromContext(r)
+	if role.Level > admin.Role.Level {
+		app.forbiddenErrorResponse(w, r)
+		return
+	}
+
+	held, err := app.holdsPermissions(ctx, admin, payload.Permissions)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if !held {
+		app.forbiddenErrorResponse(w, r)
+		return
+	}
+
+	if err := app.store.Permissions.SetRolePermissions(ctx, role.ID, payload.Permissions); err != nil {
+		switch err {
+		case store.ErrUnknownPermission:
+			app.badRequestError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.cacheStorage.Permissions.DeleteByRole(ctx, role.ID)
+
+	app.recordAudit(r, store.AuditActionRolePermissionsSet, store.AuditTargetRole, role.ID, map[string]any{
+		"permissions": payload.Permissions,
+	})
+
+	role.Permissions = payload.Permissions
+	if err := app.jsonResponse(w, http.StatusOK, role); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type AssignRolePayload struct {
+	Role string `json:"role" validate:"required,max=255"`
+}
+
+// AssignUserRole godoc
+//
+//	@Summary		Assigns a role to a user
+//	@Description	Assigns a role to a user by ID. Admins can neither assign a role with a higher level than their own nor change the role of a user at or above their own level
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			userId	path		int					true	"User ID"
+//	@Param			payload	body		AssignRolePayload	true	"Role payload"
+//	@Success		204		{string}	string				"Role assigned"
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userId}/role [put]
+func (app *application) assignUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseUserIDParam(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	var payload AssignRolePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	admin := getUserFromContext(r)
+
+	role, err := app.store.Roles.GetByName(ctx, payload.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if _, ok := app.manageableUser(w, r, userID); !ok {
+		return
+	}
+
+	// role.manage does not let anyone raise others above their own level
+	if role.Level > admin.Role.Level {
+		app.forbiddenErrorResponse(w, r)
+		return
+	}
+
+	if err := app.store.Users.SetRole(ctx, userID, payload.Role); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.invalidateUserCache(ctx, userID)
+	app.recordAudit(r, store.AuditActionUserRoleAssign, store.AuditTargetUser, userID, map[string]any{
+		"role": payload.Role,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GrantUserPermission godoc
+//
+//	@Summary		Grants a permission to a user
+//	@Description	Grants a single permission to a user on top of their role. Admins can only grant permissions they hold to users below their own level
+//	@Tags			admin
+//	@Produce		json
+//	@Param			userId		path		int		true	"User ID"
+//	@Param			permission	path		string	true	"Permission name"
+//	@Success		204			{string}	string	"Permission granted"
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userId}/permissions/{permission} [put]
+func (app *application) grantUserPermissionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseUserIDParam(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	admin := getUserFromContext(r)
+	permission := chi.URLParam(r, "permission")
+	ctx := r.Context()
+
+	if _, ok := app.manageableUser(w, r, userID); !ok {
+		return
+	}
+
+	held, err := app.hasPermission(ctx, admin, permission)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if !held {
+		app.forbiddenErrorResponse(w, r)
+		return
+	}
+
+	if err := app.store.Permissions.Grant(ctx, userID, permission, admin.ID); err != nil {
+		switch err {
+		case store.ErrUnknownPermission:
+			app.badRequestError(w, r, err)
+		case store.ErrorNotFound:
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.invalidateUserCache(ctx, userID)
+	app.recordAudit(r, store.AuditActionUserPermissionGrant, store.AuditTargetUser, userID, map[string]any{
+		"permission": permission,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeUserPermission godoc
+//
+//	@Summary		Revokes a permission from a user
+//	@Description	Revokes a permission that was granted directly to a user below the admin's own level
+//	@Tags			admin
+//	@Produce		json
+//	@Param			userId		path		int		true	"User ID"
+//	@Param			permission	path		string	true	"Permission name"
+//	@Success		204			{string}	string	"Permission revoked"
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userId}/permissions/{permission} [delete]
+func (app *application) revokeUserPermissionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseUserIDParam(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if _, ok := app.manageableUser(w, r, userID); !ok {
+		return
+	}
+
+	permission := chi.URLParam(r, "permission")
+	ctx := r.Context()
+
+	if err := app.store.Permissions.Revoke(ctx, userID, permission); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.invalidateUserCache(ctx, userID)
+	app.recordAudit(r, store.AuditActionUserPermissionRevoke, store.AuditTargetUser, userID, map[string]any{
+		"permission": permission,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// invalidateUserCache drops the cached user (which embeds the role) and
+// the cached direct grants so the next request resolves them again.
+func (app *application) invalidateUserCache(ctx context.Context, userID int64) {
+	app.cacheStorage.User.Delete(ctx, userID)
+	app.cacheStorage.Permissions.DeleteByUser(ctx, userID)
+}
+
+// manageableUser loads the target of an admin action on userID. Admins can
+// neither act on themselves nor on users at or above their own level;
+// otherwise the error response is written and ok is false.
+func (app *application) manageableUser(w http.ResponseWriter, r *http.Request, userID int64) (*store.User, bool) {
+	if userID == getUserFromContext(r).ID {
+		app.badRequestError(w, r, errSelfAction)
+		return nil, false
+	}
+
+	target, err := app.store.Users.GetById(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return nil, false
+	}
+
+	if !outranks(getUserFromContext(r), target) {
+		app.forbiddenErrorResponse(w, r)
+		return nil, false
+	}
+
+	return target, true
+}
+
+// outranks reports whether actor's role is strictly above target's.
+func outranks(actor, target *store.User) bool {
+	return actor.Role.Level > target.Role.Level
+}
+
+// holdsPermissions reports whether user has every one of names, through
+// their role or granted directly.
+func (app *application) holdsPermissions(ctx context.Context, user *store.User, names []string) (bool, error) {
+	for _, name := range names {
+		ok, err := app.hasPermission(ctx, user, name)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
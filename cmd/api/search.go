@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/babaYaga451/social/internal/store"
+	"github.com/babaYaga451/social/internal/tags"
+)
+
+// Search godoc
+//
+//	@Summary		Searches users, posts or tags
+//	@Description	Searches usernames by prefix and similarity, posts by full text (quotes, "or" and "-" are supported) or tags by prefix. Results are ranked; inactive, banned and private accounts are excluded, and so are hidden posts.
+//	@Tags			search
+//	@Produce		json
+//	@Param			q		query		string	true	"Search terms"
+//	@Param			type	query		string	false	"What to search (users, posts, tags)"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Success		200		{object}	[]store.PostSearchResult	"[]store.UserSearchResult or []store.TagSearchResult for the other types"
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/search [get]
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	sq := store.PaginatedSearchQuery{
+		Type:   store.SearchTypePosts,
+		Limit:  20,
+		Offset: 0,
+	}
+
+	sq, err := sq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(sq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	var results any
+	switch sq.Type {
+	case store.SearchTypeUsers:
+		results, err = app.store.Search.Users(ctx, sq)
+	case store.SearchTypeTags:
+		sq.Query, err = tags.Normalize(sq.Query)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+		results, err = app.store.Search.Tags(ctx, sq)
+	default:
+		sq.IncludeHidden, err = app.hasPermission(ctx, user, store.PermissionReportManage)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		results, err = app.searchPosts(ctx, user.ID, sq)
+	}
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, results); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// searchPosts runs a post search and enriches the results like any other
+// listing.
+func (app *application) searchPosts(ctx context.Context, viewerID int64, sq store.PaginatedSearchQuery) ([]store.PostSearchResult, error) {
+	results, err := app.store.Search.Posts(ctx, viewerID, sq)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make([]store.PostWithMetaData, len(results))
+	for i := range results {
+		posts[i] = results[i].PostWithMetaData
+	}
+	if err := app.enrichPosts(ctx, viewerID, posts); err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].PostWithMetaData = posts[i]
+	}
+
+	return results, nil
+}
+
+// AutocompleteUsers godoc
+//
+//	@Summary		Suggests users to mention
+//	@Description	Suggests users whose username starts with the prefix, for completing a mention. Accounts the current user follows come first, then the most followed ones
+//	@Tags			search
+//	@Produce		json
+//	@Param			prefix	query		string	true	"Start of the username, with or without @"
+//	@Param			limit	query		int		false	"Limit"
+//	@Success		200		{object}	[]store.UserSuggestion
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/autocomplete [get]
+func (app *application) autocompleteUsersHandler(w http.ResponseWriter, r *http.Request) {
+	aq := store.AutocompleteQuery{
+		Limit: 10,
+	}
+
+	aq, err := aq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(aq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	users, err := app.store.Search.Autocomplete(r.Context(), getUserFromContext(r).ID, aq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, users); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/babaYaga451/social/internal/store"
+	"github.com/babaYaga451/social/internal/tags"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetTagPosts godoc
+//
+//	@Summary		Fetches the posts of a tag
+//	@Description	Fetches the posts carrying a tag, newest first by default
+//	@Tags			tags
+//	@Produce		json
+//	@Param			tag		path		string	true	"Tag, with or without a leading #"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Param			sort	query		string	false	"Sort (asc, desc)"
+//	@Success		200		{object}	[]store.PostWithMetaData
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag}/posts [get]
+func (app *application) getTagPostsHandler(w http.ResponseWriter, r *http.Request) {
+	tag, err := tags.Normalize(chi.URLParam(r, "tag"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	fq := store.PaginatedFeedQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "desc",
+		Mode:   store.FeedModeChronological,
+	}
+
+	fq, err = fq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(fq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	fq.IncludeHidden, err = app.hasPermission(ctx, getUserFromContext(r), store.PermissionReportManage)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	posts, err := app.store.Tags.GetPosts(ctx, getUserFromContext(r).ID, tag, fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.enrichPosts(ctx, getUserFromContext(r).ID, posts); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, posts); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetTrendingTags godoc
+//
+//	@Summary		Fetches the trending tags
+//	@Description	Fetches the tags whose usage grew the most in the last window compared to their baseline
+//	@Tags			tags
+//	@Produce		json
+//	@Success		200	{object}	[]store.TrendingTag
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/trending [get]
+func (app *application) getTrendingTagsHandler(w http.ResponseWriter, r *http.Request) {
+	trending, err := app.store.Tags.Trending(r.Context(), app.conf.Trending.Limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, trending); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetFollowedTags godoc
+//
+//	@Summary		Lists followed tags
+//	@Description	Lists the tags the current user follows
+//	@Tags			tags
+//	@Produce		json
+//	@Success		200	{object}	[]string
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/followed [get]
+func (app *application) getFollowedTagsHandler(w http.ResponseWriter, r *http.Request) {
+	followed, err := app.store.Tags.Followed(r.Context(), getUserFromContext(r).ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, followed); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// FollowTag godoc
+//
+//	@Summary		Follows a tag
+//	@Description	Follows a tag so that its posts show up in the feed
+//	@Tags			tags
+//	@Produce		json
+//	@Param			tag	path		string	true	"Tag, with or without a leading #"
+//	@Success		204	{string}	string	"Tag followed"
+//	@Failure		400	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag}/follow [put]
+func (app *application) followTagHandler(w http.ResponseWriter, r *http.Request) {
+	tag, err := tags.Normalize(chi.URLParam(r, "tag"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	if err := app.store.Tags.Follow(ctx, user.ID, tag); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.invalidateFeed(ctx, user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnfollowTag godoc
+//
+//	@Summary		Unfollows a tag
+//	@Description	Unfollows a tag
+//	@Tags			tags
+//	@Produce		json
+//	@Param			tag	path		string	true	"Tag, with or without a leading #"
+//	@Success		204	{string}	string	"Tag unfollowed"
+//	@Failure		400	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag}/unfollow [put]
+func (app *application) unfollowTagHandler(w http.ResponseWriter, r *http.Request) {
+	tag, err := tags.Normalize(chi.URLParam(r, "tag"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	if err := app.store.Tags.Unfollow(ctx, user.ID, tag); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.invalidateFeed(ctx, user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// refreshTrendingTags recomputes the trending tags right away and then every
+// configured interval until ctx is cancelled. Failed runs are logged and the
+// previous results stay in place.
+func (app *application) refreshTrendingTags(ctx context.Context) error {
+	conf := app.conf.Trending
+
+	ticker := time.NewTicker(conf.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := app.store.Tags.RefreshTrending(ctx, conf.Window, conf.Baseline, conf.Limit); err != nil && ctx.Err() == nil {
+			app.logger.Errorw("refreshing trending tags", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
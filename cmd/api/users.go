@@ -12,7 +12,10 @@ import (
 
 type UserKey string
 
-const userCtx UserKey = "users"
+const (
+	userCtx         UserKey = "users"
+	impersonatorCtx UserKey = "impersonator"
+)
 
 // GetUser godoc
 //
@@ -82,6 +85,13 @@ func getUserFromContext(r *http.Request) *store.User {
 	return user
 }
 
+// getImpersonatorFromContext returns the ID of the admin acting on behalf of
+// the authenticated user, or 0 when the request is not impersonated.
+func getImpersonatorFromContext(r *http.Request) int64 {
+	id, _ := r.Context().Value(impersonatorCtx).(int64)
+	return id
+}
+
 // FollowUser godoc
 //
 //	@Summary		Follows a user
@@ -109,6 +119,14 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 		app.internalServerError(w, r, err)
 		return
 	}
+
+	err = app.store.Timeline.Backfill(ctx, followUser.ID, followedID, app.conf.Timeline.BackfillLimit, app.conf.Timeline.CelebrityThreshold)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.invalidateFeed(ctx, followUser.ID)
+
 	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -144,6 +162,12 @@ func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if err := app.store.Timeline.Trim(ctx, followerUser.ID, unfollowID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.invalidateFeed(ctx, followerUser.ID)
+
 	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -151,6 +175,47 @@ func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Reque
 
 }
 
+type SetPrivacyPayload struct {
+	Private *bool `json:"private" validate:"required"`
+}
+
+// SetPrivacy godoc
+//
+//	@Summary		Makes the current user private or public
+//	@Description	Private users are left out of user search, and their posts are only found by their followers
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		SetPrivacyPayload	true	"Privacy payload"
+//	@Success		204		{string}	string				"Privacy updated"
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/privacy [put]
+func (app *application) setPrivacyHandler(w http.ResponseWriter, r *http.Request) {
+	var payload SetPrivacyPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	if err := app.store.Users.SetPrivate(ctx, user.ID, *payload.Private); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.cacheStorage.User.Delete(ctx, user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // ActivateUser godoc
 //
 //	@Summary		Activates/Registers a user
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS permissions (
+  id bigserial PRIMARY KEY,
+  name varchar(255) NOT NULL UNIQUE,
+  description text
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+  role_id bigint NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+  permission_id bigint NOT NULL REFERENCES permissions (id) ON DELETE CASCADE,
+  PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_permissions (
+  user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  permission_id bigint NOT NULL REFERENCES permissions (id) ON DELETE CASCADE,
+  granted_by bigint REFERENCES users (id) ON DELETE SET NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, permission_id)
+);
+
+INSERT INTO permissions (name, description)
+VALUES
+  ('post.update.any', 'Edit posts created by other users'),
+  ('post.delete.any', 'Delete posts created by other users'),
+  ('comment.moderate', 'Edit or remove comments created by other users'),
+  ('user.ban', 'Deactivate or ban user accounts'),
+  ('role.manage', 'Create roles, change their permissions and assign them to users')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON (
+  (r.name = 'moderator' AND p.name IN ('post.update.any', 'comment.moderate'))
+  OR (r.name = 'admin')
+)
+ON CONFLICT DO NOTHING;
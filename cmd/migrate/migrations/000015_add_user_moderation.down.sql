@@ -0,0 +1,10 @@
+DELETE FROM permissions WHERE name IN ('user.manage', 'user.impersonate');
+
+DROP TABLE IF EXISTS audit_events;
+
+DROP INDEX IF EXISTS idx_users_created_at;
+
+ALTER TABLE users
+  DROP COLUMN IF EXISTS banned_at,
+  DROP COLUMN IF EXISTS ban_reason,
+  DROP COLUMN IF EXISTS token_version;
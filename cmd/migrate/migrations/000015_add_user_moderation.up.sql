@@ -0,0 +1,32 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS banned_at timestamp(0) with time zone,
+  ADD COLUMN IF NOT EXISTS ban_reason text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS token_version int NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at);
+
+CREATE TABLE IF NOT EXISTS audit_events (
+  id bigserial PRIMARY KEY,
+  actor_id bigint REFERENCES users (id) ON DELETE SET NULL,
+  action varchar(255) NOT NULL,
+  target_type varchar(255) NOT NULL,
+  target_id bigint NOT NULL,
+  metadata jsonb NOT NULL DEFAULT '{}',
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events (target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events (created_at);
+
+INSERT INTO permissions (name, description)
+VALUES
+  ('user.manage', 'List users, deactivate accounts and revoke their sessions'),
+  ('user.impersonate', 'Act as another user for support purposes')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name IN ('user.manage', 'user.impersonate')
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
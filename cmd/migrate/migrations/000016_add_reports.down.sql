@@ -0,0 +1,9 @@
+DELETE FROM permissions WHERE name = 'report.manage';
+
+DROP TABLE IF EXISTS reports;
+
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_until;
+
+ALTER TABLE comments DROP COLUMN IF EXISTS hidden_at;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS hidden_at;
@@ -0,0 +1,40 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS hidden_at timestamp(0) with time zone;
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS hidden_at timestamp(0) with time zone;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_until timestamp(0) with time zone;
+
+CREATE TABLE IF NOT EXISTS reports (
+  id bigserial PRIMARY KEY,
+  reporter_id bigint REFERENCES users (id) ON DELETE SET NULL,
+  target_type varchar(20) NOT NULL CHECK (target_type IN ('post', 'comment')),
+  target_id bigint NOT NULL,
+  target_user_id bigint REFERENCES users (id) ON DELETE CASCADE,
+  reason varchar(50) NOT NULL,
+  details text NOT NULL DEFAULT '',
+  status varchar(20) NOT NULL DEFAULT 'open',
+  resolution varchar(20),
+  resolution_note text NOT NULL DEFAULT '',
+  resolved_by bigint REFERENCES users (id) ON DELETE SET NULL,
+  resolved_at timestamp(0) with time zone,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_unique
+  ON reports (reporter_id, target_type, target_id)
+  WHERE status = 'open';
+
+CREATE INDEX IF NOT EXISTS idx_reports_status_created_at ON reports (status, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_reports_target ON reports (target_type, target_id);
+
+INSERT INTO permissions (name, description)
+VALUES ('report.manage', 'Review reported content, resolve reports and see hidden posts')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name = 'report.manage'
+WHERE r.name IN ('moderator', 'admin')
+ON CONFLICT DO NOTHING;
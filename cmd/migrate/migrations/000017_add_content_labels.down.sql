@@ -0,0 +1,3 @@
+ALTER TABLE comments DROP COLUMN IF EXISTS labels;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS labels;
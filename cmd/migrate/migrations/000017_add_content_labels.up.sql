@@ -0,0 +1,3 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS labels text[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS labels text[] NOT NULL DEFAULT '{}';
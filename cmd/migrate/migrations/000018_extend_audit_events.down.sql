@@ -0,0 +1,12 @@
+DELETE FROM permissions WHERE name = 'audit.read';
+
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+DROP FUNCTION IF EXISTS audit_events_append_only();
+
+DROP INDEX IF EXISTS idx_audit_events_action;
+DROP INDEX IF EXISTS idx_audit_events_actor;
+
+ALTER TABLE audit_events
+  DROP COLUMN IF EXISTS ip,
+  DROP COLUMN IF EXISTS request_id,
+  DROP COLUMN IF EXISTS diff;
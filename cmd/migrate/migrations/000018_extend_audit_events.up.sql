@@ -0,0 +1,33 @@
+-- audit rows must outlive the users they mention, and the append-only
+-- trigger below would reject the ON DELETE SET NULL update anyway
+ALTER TABLE audit_events DROP CONSTRAINT IF EXISTS audit_events_actor_id_fkey;
+
+ALTER TABLE audit_events
+  ADD COLUMN IF NOT EXISTS ip varchar(64) NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS request_id varchar(255) NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS diff jsonb NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events (actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action, created_at);
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+CREATE TRIGGER audit_events_append_only
+  BEFORE UPDATE OR DELETE ON audit_events
+  FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+INSERT INTO permissions (name, description)
+VALUES ('audit.read', 'Query and export the audit log')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name = 'audit.read'
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
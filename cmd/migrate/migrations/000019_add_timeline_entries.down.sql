@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS timeline_entries;
+
+DROP TRIGGER IF EXISTS followers_count ON followers;
+
+DROP FUNCTION IF EXISTS followers_count();
+
+ALTER TABLE users DROP COLUMN IF EXISTS follower_count;
@@ -0,0 +1,45 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS follower_count integer NOT NULL DEFAULT 0;
+
+UPDATE users u
+SET follower_count = (SELECT COUNT(*) FROM followers f WHERE f.user_id = u.id);
+
+CREATE OR REPLACE FUNCTION followers_count() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    UPDATE users SET follower_count = follower_count + 1 WHERE id = NEW.user_id;
+  ELSE
+    UPDATE users SET follower_count = follower_count - 1 WHERE id = OLD.user_id;
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER followers_count
+AFTER INSERT OR DELETE ON followers
+FOR EACH ROW EXECUTE FUNCTION followers_count();
+
+CREATE TABLE IF NOT EXISTS timeline_entries (
+  user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+  author_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL,
+  PRIMARY KEY (user_id, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_timeline_entries_user_created_at
+  ON timeline_entries (user_id, created_at DESC, post_id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_timeline_entries_user_author
+  ON timeline_entries (user_id, author_id);
+
+-- every author sees their own posts
+INSERT INTO timeline_entries (user_id, post_id, author_id, created_at)
+SELECT p.user_id, p.id, p.user_id, p.created_at
+FROM posts p
+ON CONFLICT DO NOTHING;
+
+INSERT INTO timeline_entries (user_id, post_id, author_id, created_at)
+SELECT f.follower_id, p.id, p.user_id, p.created_at
+FROM posts p
+JOIN followers f ON f.user_id = p.user_id
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,10 @@
+INSERT INTO permissions (name, description)
+VALUES ('feed.debug', 'See the ranking explanation of feed posts')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name = 'feed.debug'
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
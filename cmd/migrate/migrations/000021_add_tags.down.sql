@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS trending_tags;
+
+DROP TABLE IF EXISTS tag_follows;
+
+DROP INDEX IF EXISTS idx_posts_tags;
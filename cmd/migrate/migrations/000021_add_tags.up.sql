@@ -0,0 +1,25 @@
+-- bring existing tags to the normalized form used by the API
+UPDATE posts
+SET tags = ARRAY(
+  SELECT DISTINCT lower(ltrim(btrim(t), '#'))
+  FROM unnest(tags) AS t
+  WHERE ltrim(btrim(t), '#') <> ''
+)
+WHERE tags IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_tags ON posts USING gin (tags);
+
+CREATE TABLE IF NOT EXISTS tag_follows (
+  user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  tag varchar(50) NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, tag)
+);
+
+CREATE TABLE IF NOT EXISTS trending_tags (
+  tag varchar(50) PRIMARY KEY,
+  score double precision NOT NULL,
+  recent_count integer NOT NULL,
+  baseline_count integer NOT NULL,
+  computed_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
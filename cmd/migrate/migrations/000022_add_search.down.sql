@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_posts_search_vector;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS search_vector;
+
+DROP INDEX IF EXISTS idx_users_username_trgm;
+
+ALTER TABLE users DROP COLUMN IF EXISTS is_private;
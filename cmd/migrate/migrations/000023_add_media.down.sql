@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS media_upload_chunks;
+
+DROP TABLE IF EXISTS media;
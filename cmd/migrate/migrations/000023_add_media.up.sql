@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS media (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  -- detached media (post deleted or edited) is swept after a grace period
+  post_id bigint REFERENCES posts (id) ON DELETE SET NULL,
+  position integer,
+  status varchar(20) NOT NULL,
+  content_type varchar(100) NOT NULL DEFAULT '',
+  size bigint NOT NULL,
+  width integer NOT NULL DEFAULT 0,
+  height integer NOT NULL DEFAULT 0,
+  upload_offset bigint NOT NULL DEFAULT 0,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_post_id ON media (post_id, position);
+
+CREATE INDEX IF NOT EXISTS idx_media_unattached ON media (created_at) WHERE post_id IS NULL;
+
+CREATE TABLE IF NOT EXISTS media_upload_chunks (
+  media_id bigint NOT NULL REFERENCES media (id) ON DELETE CASCADE,
+  start_offset bigint NOT NULL,
+  size bigint NOT NULL,
+  PRIMARY KEY (media_id, start_offset)
+);
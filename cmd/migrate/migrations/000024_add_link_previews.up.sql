@@ -0,0 +1,17 @@
+-- previews are cached by URL and shared by every post linking to it
+CREATE TABLE IF NOT EXISTS link_previews (
+  url text PRIMARY KEY,
+  title text NOT NULL DEFAULT '',
+  description text NOT NULL DEFAULT '',
+  image_url text NOT NULL DEFAULT '',
+  site_name text NOT NULL DEFAULT '',
+  failed boolean NOT NULL DEFAULT false,
+  fetched_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS post_links (
+  post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+  url text NOT NULL,
+  position integer NOT NULL,
+  PRIMARY KEY (post_id, url)
+);
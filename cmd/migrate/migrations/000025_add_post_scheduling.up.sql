@@ -0,0 +1,11 @@
+-- existing posts were all published on creation
+ALTER TABLE posts
+  ADD COLUMN IF NOT EXISTS status varchar(20) NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'scheduled', 'published')),
+  ADD COLUMN IF NOT EXISTS publish_at timestamp(0) with time zone,
+  ADD CONSTRAINT posts_scheduled_publish_at CHECK (status <> 'scheduled' OR publish_at IS NOT NULL);
+
+-- the scheduler polls for due posts, authors list their unpublished ones
+CREATE INDEX IF NOT EXISTS idx_posts_scheduled ON posts (publish_at) WHERE status = 'scheduled';
+
+CREATE INDEX IF NOT EXISTS idx_posts_unpublished ON posts (user_id) WHERE status <> 'published';
@@ -0,0 +1,3 @@
+ALTER TABLE posts DROP COLUMN IF EXISTS last_edited_by;
+
+DROP TABLE IF EXISTS post_revisions;
@@ -0,0 +1,21 @@
+-- one row per version of a post, the current one included
+CREATE TABLE IF NOT EXISTS post_revisions (
+  id bigserial PRIMARY KEY,
+  post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+  version integer NOT NULL,
+  title text NOT NULL,
+  content text NOT NULL,
+  tags text[] NOT NULL DEFAULT '{}',
+  editor_id bigint REFERENCES users (id) ON DELETE SET NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  UNIQUE (post_id, version)
+);
+
+-- set on edits made after the post was published
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS last_edited_by bigint REFERENCES users (id) ON DELETE SET NULL;
+
+-- earlier versions are lost; the current one becomes the first revision
+INSERT INTO post_revisions (post_id, version, title, content, tags, editor_id, created_at)
+SELECT id, version, title, content, COALESCE(tags, '{}'), user_id, created_at
+FROM posts
+ON CONFLICT DO NOTHING;
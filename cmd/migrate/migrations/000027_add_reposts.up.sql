@@ -0,0 +1,15 @@
+-- reposts go away with their original
+CREATE TABLE IF NOT EXISTS reposts (
+  user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reposts_post_id ON reposts (post_id);
+
+-- no foreign key: a quote keeps pointing at a deleted original so that it
+-- can show it as unavailable
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS quoted_post_id bigint;
+
+CREATE INDEX IF NOT EXISTS idx_posts_quoted_post_id ON posts (quoted_post_id) WHERE quoted_post_id IS NOT NULL;
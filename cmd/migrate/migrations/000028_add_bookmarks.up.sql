@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS bookmark_collections (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  name varchar(100) NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  CONSTRAINT bookmark_collections_user_name_key UNIQUE (user_id, name)
+);
+
+-- bookmarks go away with their post; deleting a collection keeps its
+-- bookmarks, unfiled
+CREATE TABLE IF NOT EXISTS bookmarks (
+  user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  post_id bigint NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+  collection_id bigint REFERENCES bookmark_collections (id) ON DELETE SET NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bookmarks_collection_id ON bookmarks (collection_id) WHERE collection_id IS NOT NULL;
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS polls (
+  id bigserial PRIMARY KEY,
+  post_id bigint NOT NULL UNIQUE REFERENCES posts (id) ON DELETE CASCADE,
+  multiple_choice boolean NOT NULL DEFAULT false,
+  ends_at timestamp(0) with time zone NOT NULL,
+  -- set with the final tallies by the job closing ended polls
+  closed_at timestamp(0) with time zone,
+  voter_count integer NOT NULL DEFAULT 0,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_polls_open ON polls (ends_at) WHERE closed_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS poll_options (
+  id bigserial PRIMARY KEY,
+  poll_id bigint NOT NULL REFERENCES polls (id) ON DELETE CASCADE,
+  position integer NOT NULL,
+  text varchar(100) NOT NULL,
+  vote_count integer NOT NULL DEFAULT 0,
+  UNIQUE (poll_id, position),
+  UNIQUE (poll_id, id)
+);
+
+-- one ballot per user and poll; a ballot holds one choice, or several for
+-- multiple choice polls
+CREATE TABLE IF NOT EXISTS poll_ballots (
+  poll_id bigint NOT NULL REFERENCES polls (id) ON DELETE CASCADE,
+  user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (poll_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS poll_choices (
+  poll_id bigint NOT NULL,
+  user_id bigint NOT NULL,
+  option_id bigint NOT NULL,
+  PRIMARY KEY (poll_id, user_id, option_id),
+  CONSTRAINT poll_choices_ballot_fkey FOREIGN KEY (poll_id, user_id)
+    REFERENCES poll_ballots (poll_id, user_id) ON DELETE CASCADE,
+  CONSTRAINT poll_choices_option_fkey FOREIGN KEY (poll_id, option_id)
+    REFERENCES poll_options (poll_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_poll_choices_option_id ON poll_choices (option_id);
@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS user_blocks (
+  user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  blocked_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, blocked_id),
+  CONSTRAINT user_blocks_not_self CHECK (user_id <> blocked_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_blocks_blocked_id ON user_blocks (blocked_id);
+
+CREATE TABLE IF NOT EXISTS conversations (
+  id bigserial PRIMARY KEY,
+  title varchar(100),
+  is_group boolean NOT NULL DEFAULT false,
+  -- "<lower user id>:<higher user id>" for 1:1 conversations, so a pair of
+  -- users shares a single one
+  direct_key varchar(41) UNIQUE,
+  created_by bigint REFERENCES users (id) ON DELETE SET NULL,
+  last_message_id bigint,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  CONSTRAINT conversations_direct_key CHECK (is_group = (direct_key IS NULL))
+);
+
+-- read receipts are the newest message each member has read
+CREATE TABLE IF NOT EXISTS conversation_members (
+  conversation_id bigint NOT NULL REFERENCES conversations (id) ON DELETE CASCADE,
+  user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  last_read_message_id bigint NOT NULL DEFAULT 0,
+  last_read_at timestamp(0) with time zone,
+  joined_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (conversation_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_members_user_id ON conversation_members (user_id);
+
+CREATE TABLE IF NOT EXISTS messages (
+  id bigserial PRIMARY KEY,
+  conversation_id bigint NOT NULL REFERENCES conversations (id) ON DELETE CASCADE,
+  sender_id bigint REFERENCES users (id) ON DELETE SET NULL,
+  content varchar(2000) NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_id ON messages (conversation_id, id DESC);
@@ -0,0 +1,21 @@
+-- mentions point at users by ID, so renaming a user keeps them working;
+-- offsets are in code points and cover the @username in the content
+CREATE TABLE IF NOT EXISTS mentions (
+  id bigserial PRIMARY KEY,
+  post_id bigint REFERENCES posts (id) ON DELETE CASCADE,
+  comment_id bigint REFERENCES comments (id) ON DELETE CASCADE,
+  user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  start_offset integer NOT NULL,
+  end_offset integer NOT NULL,
+  CONSTRAINT mentions_target CHECK (num_nonnulls(post_id, comment_id) = 1),
+  CONSTRAINT mentions_offsets CHECK (start_offset >= 0 AND end_offset > start_offset)
+);
+
+CREATE INDEX IF NOT EXISTS idx_mentions_post_id ON mentions (post_id) WHERE post_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_mentions_comment_id ON mentions (comment_id) WHERE comment_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_mentions_user_id ON mentions (user_id);
+
+-- mentions are resolved case-insensitively
+CREATE INDEX IF NOT EXISTS idx_users_username_lower ON users (lower(username));
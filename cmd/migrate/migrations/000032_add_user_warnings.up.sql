@@ -0,0 +1,12 @@
+-- warnings are the "warn" resolution of a report, kept so that the warned
+-- user can see them
+CREATE TABLE IF NOT EXISTS user_warnings (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  report_id bigint REFERENCES reports (id) ON DELETE SET NULL,
+  reason varchar(50) NOT NULL,
+  note text NOT NULL DEFAULT '',
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_warnings_user_id ON user_warnings (user_id, created_at);
@@ -0,0 +1,16 @@
+-- reports filed by the content filter have no reporter, so the per-reporter
+-- index never treats them as duplicates; close all but the oldest open one
+-- per target before keying them on the target alone
+UPDATE reports r
+SET status = 'resolved', resolution = 'dismiss', resolution_note = 'duplicate', resolved_at = NOW()
+WHERE r.reason = 'filter' AND r.status = 'open'
+  AND EXISTS (
+    SELECT 1 FROM reports o
+    WHERE o.reason = 'filter' AND o.status = 'open'
+      AND o.target_type = r.target_type AND o.target_id = r.target_id
+      AND o.id < r.id
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_filter_unique
+  ON reports (target_type, target_id)
+  WHERE status = 'open' AND reason = 'filter';
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_media_unattached;
+
+CREATE INDEX IF NOT EXISTS idx_media_unattached ON media (created_at) WHERE post_id IS NULL;
+
+DROP TRIGGER IF EXISTS media_updated_at ON media;
+
+DROP FUNCTION IF EXISTS media_updated_at();
+
+ALTER TABLE media DROP COLUMN IF EXISTS updated_at;
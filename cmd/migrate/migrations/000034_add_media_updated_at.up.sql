@@ -0,0 +1,20 @@
+-- orphaned media is swept by how long it has been unattached, not by age;
+-- existing rows start their grace period now
+ALTER TABLE media ADD COLUMN IF NOT EXISTS updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW();
+
+-- a trigger also catches posts being deleted, which detach their media
+-- through ON DELETE SET NULL, and uploads receiving chunks
+CREATE OR REPLACE FUNCTION media_updated_at() RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at = NOW();
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER media_updated_at
+BEFORE UPDATE ON media
+FOR EACH ROW EXECUTE FUNCTION media_updated_at();
+
+DROP INDEX IF EXISTS idx_media_unattached;
+
+CREATE INDEX IF NOT EXISTS idx_media_unattached ON media (updated_at) WHERE post_id IS NULL;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS users_username_chars ON users;
+
+DROP FUNCTION IF EXISTS users_username_chars();
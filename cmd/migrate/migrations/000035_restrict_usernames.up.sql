@@ -0,0 +1,23 @@
+-- usernames are mentioned as @name, so new ones may only use the characters
+-- a mention can: ASCII letters, digits and underscores. Existing names are
+-- left alone. A NOT VALID check would still be enforced on every later
+-- update of a legacy row, such as the follower count trigger, so the rule
+-- is checked by a trigger on new and changed usernames only
+CREATE OR REPLACE FUNCTION users_username_chars() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'UPDATE' AND NEW.username = OLD.username THEN
+    RETURN NEW;
+  END IF;
+
+  IF NEW.username !~ '^[A-Za-z0-9_]{1,100}$' THEN
+    RAISE EXCEPTION 'username % may only use letters, digits and underscores', NEW.username
+      USING ERRCODE = 'check_violation', CONSTRAINT = 'users_username_chars';
+  END IF;
+
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_username_chars
+BEFORE INSERT OR UPDATE OF username ON users
+FOR EACH ROW EXECUTE FUNCTION users_username_chars();
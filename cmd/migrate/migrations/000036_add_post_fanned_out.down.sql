@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_reposts_user_created_at;
+
+DROP INDEX IF EXISTS idx_posts_user_created_at;
+
+DROP INDEX IF EXISTS idx_posts_pulled;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS fanned_out;
@@ -0,0 +1,20 @@
+-- posts that were not fanned out are merged into their followers' timelines
+-- when read, even after their author drops below the celebrity threshold.
+-- 000019 fanned out every earlier post; since then a published post without
+-- entries beyond its author's own was skipped as a celebrity post
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS fanned_out boolean NOT NULL DEFAULT true;
+
+UPDATE posts p
+SET fanned_out = false
+WHERE p.status = 'published'
+  AND EXISTS (SELECT 1 FROM followers f WHERE f.user_id = p.user_id)
+  AND NOT EXISTS (
+    SELECT 1 FROM timeline_entries te WHERE te.post_id = p.id AND te.user_id <> p.user_id
+  );
+
+CREATE INDEX IF NOT EXISTS idx_posts_pulled ON posts (user_id, created_at DESC) WHERE NOT fanned_out;
+
+-- every branch of a timeline read walks its source newest first
+CREATE INDEX IF NOT EXISTS idx_posts_user_created_at ON posts (user_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_reposts_user_created_at ON reposts (user_id, created_at DESC);
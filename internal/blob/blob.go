@@ -0,0 +1,26 @@
+// Package blob stores opaque objects such as uploaded media under string
+// keys. Keys are slash-separated paths like "media/42/original".
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store is implemented by every blob backend.
+type Store interface {
+	// Put writes size bytes from r under key, replacing any existing object.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object under key, or returns ErrNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object under key. Missing objects are not an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/babaYaga451/social/internal/store/cache"
+	"gopkg.in/yaml.v3"
+)
+
+// fileSuffix is appended to an environment variable name to read its value
+// from a file instead, e.g. AUTH_TOKEN_SECRET_FILE=/run/secrets/jwt.
+const fileSuffix = "_FILE"
+
+// Load builds the configuration from, in increasing precedence, Default, the
+// file given by -config or CONFIG_FILE, environment variables and the
+// command-line args. Every parse and validation problem is reported in the
+// returned error, not just the first.
+func Load(args []string) (Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+
+	overrides := map[string]string{}
+	for _, f := range fields(&cfg) {
+		name := flagName(f.env)
+		fs.Func(name, "overrides "+f.env, func(value string) error {
+			overrides[f.env] = value
+			return nil
+		})
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *path != "" {
+		if err := loadFile(*path, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	var errs []error
+	if err := applyLegacyEnv(&cfg); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, f := range fields(&cfg) {
+		raw, ok, err := lookupEnv(f.env)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if value, set := overrides[f.env]; set {
+			raw, ok = value, true
+		}
+
+		if !ok {
+			continue
+		}
+
+		if err := setValue(f.value, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.env, err))
+		}
+	}
+
+	errs = append(errs, validate(cfg)...)
+
+	return cfg, errors.Join(errs...)
+}
+
+// applyLegacyEnv maps environment variables that were replaced onto their
+// successors. It runs before the current variables are read, so those win.
+func applyLegacyEnv(cfg *Config) error {
+	if raw, ok := os.LookupEnv("CACHE_ENABLED"); ok {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("CACHE_ENABLED: invalid boolean %q", raw)
+		}
+
+		cfg.Cache.Backend = cache.BackendNone
+		if enabled {
+			cfg.Cache.Backend = cache.BackendRedis
+		}
+		cfg.Deprecations = append(cfg.Deprecations, "CACHE_ENABLED is deprecated, set CACHE_BACKEND instead")
+	}
+
+	return nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		_, err = toml.Decode(string(data), cfg)
+	default:
+		return fmt.Errorf("config file %s: unsupported format %q", path, ext)
+	}
+
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// lookupEnv returns the value of key, or the trimmed contents of the file
+// named by key_FILE. Setting both is an error.
+func lookupEnv(key string) (string, bool, error) {
+	value, ok := os.LookupEnv(key)
+
+	path, fromFile := os.LookupEnv(key + fileSuffix)
+	if !fromFile {
+		return value, ok, nil
+	}
+
+	if ok {
+		return "", false, fmt.Errorf("%s and %s are both set", key, key+fileSuffix)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s: %w", key+fileSuffix, err)
+	}
+
+	return strings.TrimSpace(string(data)), true, nil
+}
+
+type field struct {
+	env       string
+	namespace string
+	value     reflect.Value
+}
+
+// fields lists every leaf of cfg that has an env tag.
+func fields(cfg *Config) []field {
+	var out []field
+
+	var walk func(v reflect.Value, namespace string)
+	walk = func(v reflect.Value, namespace string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf, fv := t.Field(i), v.Field(i)
+
+			if sf.Type.Kind() == reflect.Struct {
+				walk(fv, namespace+"."+sf.Name)
+				continue
+			}
+
+			if env := sf.Tag.Get("env"); env != "" {
+				out = append(out, field{env: env, namespace: namespace + "." + sf.Name, value: fv})
+			}
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "Config")
+
+	return out
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		// comma-separated, dropping empty entries
+		values := []string{}
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+		v.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+
+	return nil
+}
+
+func flagName(env string) string {
+	return strings.ReplaceAll(strings.ToLower(env), "_", "-")
+}
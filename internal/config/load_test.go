@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/babaYaga451/social/internal/store/cache"
+)
+
+// testEnv clears the variables the tests set and provides those without a
+// default, for the duration of the test.
+func testEnv(t *testing.T) {
+	for _, key := range []string{"CONFIG_FILE", "ADDR", "ADDR_FILE", "SHUTDOWN_TIMEOUT", "CACHE_ENABLED", "CACHE_BACKEND"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	t.Setenv("MAILTRAP_API_KEY", "test")
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	yamlFile := writeFile(t, "config.yaml", "addr: \":7000\"\ndb:\n  max_open_conns: 5\n  max_idle_conns: 5\n")
+	tomlFile := writeFile(t, "config.toml", "addr = \":7001\"\n")
+	secret := writeFile(t, "addr", " :7003\n")
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{name: "default", want: ":8080"},
+		{name: "yaml file", args: []string{"-config", yamlFile}, want: ":7000"},
+		{name: "toml file", args: []string{"-config", tomlFile}, want: ":7001"},
+		{name: "file from env", env: map[string]string{"CONFIG_FILE": yamlFile}, want: ":7000"},
+		{name: "env over file", env: map[string]string{"ADDR": ":7002"}, args: []string{"-config", yamlFile}, want: ":7002"},
+		{name: "env from file", env: map[string]string{"ADDR_FILE": secret}, want: ":7003"},
+		{name: "flag over env", env: map[string]string{"ADDR": ":7002"}, args: []string{"-addr", ":7004"}, want: ":7004"},
+		{
+			name:    "env and env file",
+			env:     map[string]string{"ADDR": ":7002", "ADDR_FILE": secret},
+			wantErr: "ADDR and ADDR_FILE are both set",
+		},
+		{name: "unknown file format", args: []string{"-config", secret}, wantErr: "unsupported format"},
+		{name: "invalid value", env: map[string]string{"SHUTDOWN_TIMEOUT": "soon"}, wantErr: "SHUTDOWN_TIMEOUT"},
+		{name: "failed validation", args: []string{"-addr", ""}, wantErr: `ADDR: failed "required" validation`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testEnv(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := Load(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Addr != tt.want {
+				t.Fatalf("Addr = %q, want %q", cfg.Addr, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFileKeepsDefaults(t *testing.T) {
+	testEnv(t)
+	path := writeFile(t, "config.yaml", "db:\n  max_idle_time: 1m\n")
+
+	cfg, err := Load([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DB.MaxIdleTime != time.Minute || cfg.DB.MaxOpenConns != Default().DB.MaxOpenConns {
+		t.Fatalf("DB = %+v, want the file's idle time and default others", cfg.DB)
+	}
+}
+
+func TestLoadLegacyCacheEnabled(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled string
+		backend string
+		want    string
+	}{
+		{name: "unset", want: cache.BackendNone},
+		{name: "enabled", enabled: "true", want: cache.BackendRedis},
+		{name: "disabled", enabled: "false", want: cache.BackendNone},
+		{name: "backend wins", enabled: "true", backend: cache.BackendMemory, want: cache.BackendMemory},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testEnv(t)
+			if tt.enabled != "" {
+				t.Setenv("CACHE_ENABLED", tt.enabled)
+			}
+			if tt.backend != "" {
+				t.Setenv("CACHE_BACKEND", tt.backend)
+			}
+
+			cfg, err := Load(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Cache.Backend != tt.want {
+				t.Fatalf("Cache.Backend = %q, want %q", cfg.Cache.Backend, tt.want)
+			}
+			if deprecated := len(cfg.Deprecations) > 0; deprecated != (tt.enabled != "") {
+				t.Fatalf("Deprecations = %q", cfg.Deprecations)
+			}
+		})
+	}
+}
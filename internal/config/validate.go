@@ -0,0 +1,74 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/babaYaga451/social/internal/blob"
+	"github.com/go-playground/validator/v10"
+)
+
+var structValidator = validator.New(validator.WithRequiredStructEnabled())
+
+// insecure lists development defaults that must never reach production.
+var insecure = []struct {
+	name  string
+	check func(Config) bool
+}{
+	{"AUTH_TOKEN_SECRET must be changed from its default", func(c Config) bool { return c.Auth.Token.Secret == "example" }},
+	{"AUTH_TOKEN_SECRET must be at least 32 characters", func(c Config) bool { return len(c.Auth.Token.Secret) < 32 }},
+	{"AUTH_BASIC_USER/AUTH_BASIC_PASS must be changed from admin/admin", func(c Config) bool {
+		return c.Auth.Basic.User == "admin" && c.Auth.Basic.Pass == "admin"
+	}},
+}
+
+// required lists settings that are only mandatory in combination with
+// others.
+var required = []struct {
+	name  string
+	check func(Config) bool
+}{
+	{"MEDIA_S3_ENDPOINT and MEDIA_S3_BUCKET are required when MEDIA_STORAGE is s3", func(c Config) bool {
+		return c.Media.Storage == blob.BackendS3 && (c.Media.S3.Endpoint == "" || c.Media.S3.Bucket == "")
+	}},
+}
+
+func validate(cfg Config) []error {
+	var errs []error
+
+	if err := structValidator.Struct(cfg); err != nil {
+		var verrs validator.ValidationErrors
+		if !errors.As(err, &verrs) {
+			return []error{err}
+		}
+
+		envs := map[string]string{}
+		for _, f := range fields(&cfg) {
+			envs[f.namespace] = f.env
+		}
+
+		for _, fe := range verrs {
+			name := fe.StructNamespace()
+			if env, ok := envs[name]; ok {
+				name = env
+			}
+			errs = append(errs, fmt.Errorf("%s: failed %q validation", name, fe.Tag()))
+		}
+	}
+
+	for _, rule := range required {
+		if rule.check(cfg) {
+			errs = append(errs, errors.New(rule.name))
+		}
+	}
+
+	if cfg.IsProduction() {
+		for _, rule := range insecure {
+			if rule.check(cfg) {
+				errs = append(errs, errors.New(rule.name))
+			}
+		}
+	}
+
+	return errs
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
 
 	"github.com/babaYaga451/social/internal/store"
@@ -29,7 +30,7 @@ func Seed(store store.Storage, db *sql.DB) {
 
 	posts := generatePosts(200, users)
 	for _, post := range posts {
-		if err := store.Posts.Create(ctx, post); err != nil {
+		if err := store.Posts.Create(ctx, post, math.MaxInt32); err != nil {
 			log.Println("Error creating posts", err)
 			return
 		}
@@ -73,6 +74,7 @@ func generatePosts(num int, users []*store.User) []*store.Post {
 			Title:   faker.Sentence(),
 			Content: faker.Paragraph(),
 			Tags:    generateTags(),
+			Status:  store.PostStatusPublished,
 		}
 	}
 	return posts
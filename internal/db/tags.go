@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/babaYaga451/social/internal/tags"
+	"github.com/lib/pq"
+)
+
+// NormalizeTags brings the tags of existing posts to the form the API stores,
+// which migration 000021 could only approximate in SQL: tags are NFKC
+// normalized, and those tags.Normalize rejects are dropped since they can
+// never be looked up. Posts are rewritten batchSize at a time; it returns
+// how many changed and the tags it dropped.
+func NormalizeTags(ctx context.Context, db *sql.DB, batchSize int) (int, []string, error) {
+	var (
+		changed int
+		dropped []string
+		lastID  int64
+	)
+
+	for {
+		rows, err := db.QueryContext(ctx, `
+    SELECT id, tags FROM posts
+    WHERE id > $1 AND cardinality(tags) > 0
+    ORDER BY id
+    LIMIT $2
+    `, lastID, batchSize)
+		if err != nil {
+			return changed, dropped, err
+		}
+
+		type post struct {
+			id   int64
+			tags []string
+		}
+		var batch []post
+		for rows.Next() {
+			var p post
+			if err := rows.Scan(&p.id, pq.Array(&p.tags)); err != nil {
+				rows.Close()
+				return changed, dropped, err
+			}
+			batch = append(batch, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return changed, dropped, err
+		}
+
+		for _, p := range batch {
+			normalized := make([]string, 0, len(p.tags))
+			for _, tag := range p.tags {
+				n, err := tags.Normalize(tag)
+				if err != nil {
+					dropped = append(dropped, tag)
+					continue
+				}
+				if !slices.Contains(normalized, n) {
+					normalized = append(normalized, n)
+				}
+			}
+
+			if slices.Equal(normalized, p.tags) {
+				continue
+			}
+
+			_, err := db.ExecContext(ctx, `UPDATE posts SET tags = $2 WHERE id = $1`, p.id, pq.Array(normalized))
+			if err != nil {
+				return changed, dropped, err
+			}
+			changed++
+		}
+
+		if len(batch) < batchSize {
+			return changed, dropped, nil
+		}
+		lastID = batch[len(batch)-1].id
+	}
+}
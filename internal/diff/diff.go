@@ -0,0 +1,132 @@
+// Package diff computes word-level differences between two texts, as shown
+// when comparing revisions of a post.
+package diff
+
+import "unicode"
+
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Edit is a run of text that both texts share, or that only the new
+// (Insert) or the old (Delete) one has.
+type Edit struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// Words returns the edits turning a into b, word by word. Joining the Equal
+// and Delete texts gives a back, and joining the Equal and Insert texts
+// gives b. Consecutive edits of the same kind are merged.
+func Words(a, b string) []Edit {
+	x, y := tokenize(a), tokenize(b)
+
+	// common ends are cheap to strip and keep the quadratic part small
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	var edits []Edit
+	for _, t := range x[:prefix] {
+		edits = appendEdit(edits, Equal, t)
+	}
+	edits = append(edits, lcs(x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])...)
+	for _, t := range x[len(x)-suffix:] {
+		edits = appendEdit(edits, Equal, t)
+	}
+
+	return merge(edits)
+}
+
+// lcs diffs x and y through their longest common subsequence.
+func lcs(x, y []string) []Edit {
+	n, m := len(x), len(y)
+
+	// lengths[i][j] is the LCS length of x[i:] and y[j:]
+	lengths := make([][]int, n+1)
+	for i := range lengths {
+		lengths[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var edits []Edit
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case x[i] == y[j]:
+			edits = appendEdit(edits, Equal, x[i])
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			edits = appendEdit(edits, Delete, x[i])
+			i++
+		default:
+			edits = appendEdit(edits, Insert, y[j])
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		edits = appendEdit(edits, Delete, x[i])
+	}
+	for ; j < m; j++ {
+		edits = appendEdit(edits, Insert, y[j])
+	}
+
+	return edits
+}
+
+func appendEdit(edits []Edit, op Op, text string) []Edit {
+	if l := len(edits); l > 0 && edits[l-1].Op == op {
+		edits[l-1].Text += text
+		return edits
+	}
+	return append(edits, Edit{Op: op, Text: text})
+}
+
+// merge joins neighbouring edits of the same kind left over from stitching
+// the common ends to the middle part.
+func merge(edits []Edit) []Edit {
+	merged := []Edit{}
+	for _, e := range edits {
+		merged = appendEdit(merged, e.Op, e.Text)
+	}
+	return merged
+}
+
+// tokenize splits s into alternating runs of words and whitespace.
+func tokenize(s string) []string {
+	var tokens []string
+
+	start := 0
+	inSpace := false
+	for i, r := range s {
+		space := unicode.IsSpace(r)
+		if space != inSpace && i > start {
+			tokens = append(tokens, s[start:i])
+			start = i
+		}
+		inSpace = space
+	}
+	if start < len(s) {
+		tokens = append(tokens, s[start:])
+	}
+
+	return tokens
+}
@@ -0,0 +1,77 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Edit
+	}{
+		{
+			name: "identical",
+			a:    "hello world",
+			b:    "hello world",
+			want: []Edit{{Equal, "hello world"}},
+		},
+		{
+			name: "both empty",
+			want: []Edit{},
+		},
+		{
+			name: "from empty",
+			b:    "hello",
+			want: []Edit{{Insert, "hello"}},
+		},
+		{
+			name: "to empty",
+			a:    "hello",
+			want: []Edit{{Delete, "hello"}},
+		},
+		{
+			name: "word replaced",
+			a:    "the quick fox",
+			b:    "the slow fox",
+			want: []Edit{{Equal, "the "}, {Delete, "quick"}, {Insert, "slow"}, {Equal, " fox"}},
+		},
+		{
+			name: "word appended",
+			a:    "go is fun",
+			b:    "go is really fun",
+			want: []Edit{{Equal, "go is "}, {Insert, "really "}, {Equal, "fun"}},
+		},
+		{
+			name: "whitespace change",
+			a:    "a b",
+			b:    "a  b",
+			want: []Edit{{Equal, "a"}, {Delete, " "}, {Insert, "  "}, {Equal, "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Words(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Words(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+
+			// the edits must give both texts back
+			var a, b strings.Builder
+			for _, e := range got {
+				if e.Op != Insert {
+					a.WriteString(e.Text)
+				}
+				if e.Op != Delete {
+					b.WriteString(e.Text)
+				}
+			}
+			if a.String() != tt.a || b.String() != tt.b {
+				t.Fatalf("edits rebuild %q and %q, want %q and %q", a.String(), b.String(), tt.a, tt.b)
+			}
+		})
+	}
+}
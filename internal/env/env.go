@@ -2,7 +2,6 @@ package env
 
 import (
 	"os"
-	"strconv"
 )
 
 func GetString(key string, fallback string) string {
@@ -13,31 +12,3 @@ func GetString(key string, fallback string) string {
 
 	return val
 }
-
-func GetInt(key string, fallback int) int {
-	val, ok := os.LookupEnv(key)
-	if !ok {
-		return fallback
-	}
-
-	intVal, err := strconv.Atoi(val)
-	if err != nil {
-		return fallback
-	}
-
-	return intVal
-}
-
-func GetBoolean(key string, fallback bool) bool {
-	val, ok := os.LookupEnv(key)
-	if !ok {
-		return fallback
-	}
-
-	boolVal, err := strconv.ParseBool(val)
-	if err != nil {
-		return fallback
-	}
-
-	return boolVal
-}
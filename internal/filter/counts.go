@@ -0,0 +1,62 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@(\w{1,100})`)
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\w#])#(\w{1,100})`)
+)
+
+type maxCount struct {
+	name    string
+	pattern *regexp.Regexp
+	max     int
+	action  Action
+}
+
+// NewMaxMentions matches content with more than max distinct @mentions.
+func NewMaxMentions(max int, action Action) Filter {
+	return &maxCount{name: "max_mentions", pattern: mentionPattern, max: max, action: action}
+}
+
+// NewMaxHashtags matches content with more than max distinct hashtags,
+// counting both #tags in the text and the post's tags.
+func NewMaxHashtags(max int, action Action) Filter {
+	return &maxCount{name: "max_hashtags", pattern: hashtagPattern, max: max, action: action}
+}
+
+func (f *maxCount) Name() string {
+	return f.name
+}
+
+func (f *maxCount) Check(ctx context.Context, c Content) (*Match, error) {
+	if f.max <= 0 {
+		return nil, nil
+	}
+
+	seen := map[string]struct{}{}
+	for _, m := range f.pattern.FindAllStringSubmatch(c.Text(), -1) {
+		seen[strings.ToLower(m[1])] = struct{}{}
+	}
+
+	if f.pattern == hashtagPattern {
+		for _, t := range c.Tags {
+			seen[strings.ToLower(strings.TrimPrefix(t, "#"))] = struct{}{}
+		}
+	}
+
+	if len(seen) <= f.max {
+		return nil, nil
+	}
+
+	return &Match{
+		Filter: f.Name(),
+		Action: f.action,
+		Reason: fmt.Sprintf("%d distinct entries exceed the limit of %d", len(seen), f.max),
+	}, nil
+}
@@ -0,0 +1,97 @@
+package filter
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+type duplicateKey struct {
+	authorID int64
+	sum      [sha256.Size]byte
+}
+
+type duplicateSpam struct {
+	window time.Duration
+	max    int
+	action Action
+	mu     sync.Mutex
+	seen   map[duplicateKey][]time.Time
+	lastGC time.Time
+}
+
+// NewDuplicateSpam matches an author submitting the same normalised text more
+// than max times within window. State is kept in memory per instance.
+func NewDuplicateSpam(window time.Duration, max int, action Action) Filter {
+	return &duplicateSpam{
+		window: window,
+		max:    max,
+		action: action,
+		seen:   make(map[duplicateKey][]time.Time),
+	}
+}
+
+func (f *duplicateSpam) Name() string {
+	return "duplicate_spam"
+}
+
+func (f *duplicateSpam) Check(ctx context.Context, c Content) (*Match, error) {
+	if f.window <= 0 || f.max <= 0 {
+		return nil, nil
+	}
+
+	text := strings.Join(words(Normalize(c.Text())), " ")
+	if text == "" {
+		return nil, nil
+	}
+
+	key := duplicateKey{authorID: c.AuthorID, sum: sha256.Sum256([]byte(text))}
+	now := time.Now()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.gc(now)
+
+	recent := f.recent(f.seen[key], now)
+	recent = append(recent, now)
+	f.seen[key] = recent
+
+	if len(recent) <= f.max {
+		return nil, nil
+	}
+
+	return &Match{
+		Filter: f.Name(),
+		Action: f.action,
+		Reason: fmt.Sprintf("same content submitted %d times within %s", len(recent), f.window),
+	}, nil
+}
+
+func (f *duplicateSpam) recent(times []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-f.window)
+	for len(times) > 0 && times[0].Before(cutoff) {
+		times = times[1:]
+	}
+	return times
+}
+
+// gc drops expired entries at most once per window so the map stays bounded
+// by the amount of content submitted in a single window.
+func (f *duplicateSpam) gc(now time.Time) {
+	if now.Sub(f.lastGC) < f.window {
+		return
+	}
+	f.lastGC = now
+
+	for key, times := range f.seen {
+		if times = f.recent(times, now); len(times) == 0 {
+			delete(f.seen, key)
+		} else {
+			f.seen[key] = times
+		}
+	}
+}
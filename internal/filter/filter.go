@@ -0,0 +1,147 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+)
+
+// Action is what a filter asks the caller to do with the content it matched.
+// Actions are ordered by severity so the chain can keep the strictest one.
+type Action int
+
+const (
+	Allow Action = iota
+	Annotate
+	Hold
+	Reject
+)
+
+func (a Action) String() string {
+	switch a {
+	case Annotate:
+		return "annotate"
+	case Hold:
+		return "hold"
+	case Reject:
+		return "reject"
+	default:
+		return "allow"
+	}
+}
+
+func (a Action) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// ParseAction returns the action named s, as written by Action.String.
+func ParseAction(s string) (Action, error) {
+	switch s {
+	case "allow":
+		return Allow, nil
+	case "annotate":
+		return Annotate, nil
+	case "hold":
+		return Hold, nil
+	case "reject":
+		return Reject, nil
+	default:
+		return Allow, fmt.Errorf("unknown filter action %q", s)
+	}
+}
+
+const (
+	KindPost    = "post"
+	KindComment = "comment"
+)
+
+type Content struct {
+	Kind     string
+	AuthorID int64
+	Title    string
+	Body     string
+	Tags     []string
+}
+
+// Text is everything a filter should inspect, title first.
+func (c Content) Text() string {
+	if c.Title == "" {
+		return c.Body
+	}
+	return c.Title + "\n" + c.Body
+}
+
+type Match struct {
+	Filter string `json:"filter"`
+	Action Action `json:"action"`
+	Reason string `json:"reason"`
+}
+
+type Filter interface {
+	Name() string
+	// Check returns nil when the content passes the filter.
+	Check(ctx context.Context, c Content) (*Match, error)
+}
+
+type Verdict struct {
+	Action  Action
+	Matches []Match
+}
+
+// Labels are the names of the filters that matched, used to annotate content.
+func (v Verdict) Labels() []string {
+	labels := make([]string, 0, len(v.Matches))
+	for _, m := range v.Matches {
+		labels = append(labels, m.Filter)
+	}
+	return labels
+}
+
+// Reason is the reason given by the first filter with the verdict's action.
+func (v Verdict) Reason() string {
+	for _, m := range v.Matches {
+		if m.Action == v.Action {
+			return m.Reason
+		}
+	}
+	return ""
+}
+
+type Chain struct {
+	filters []Filter
+}
+
+func NewChain(filters ...Filter) *Chain {
+	return &Chain{filters: filters}
+}
+
+// Run passes the content through every filter in order and stops at the first
+// rejection. A nil chain allows everything.
+func (c *Chain) Run(ctx context.Context, content Content) (Verdict, error) {
+	verdict := Verdict{Action: Allow}
+	if c == nil {
+		return verdict, nil
+	}
+
+	for _, f := range c.filters {
+		match, err := f.Check(ctx, content)
+		if err != nil {
+			return verdict, fmt.Errorf("filter %s: %w", f.Name(), err)
+		}
+
+		if match == nil {
+			continue
+		}
+
+		verdict.Matches = append(verdict.Matches, *match)
+
+		if match.Action > verdict.Action {
+			verdict.Action = match.Action
+		}
+
+		if verdict.Action == Reject {
+			break
+		}
+	}
+
+	return verdict, nil
+}
@@ -0,0 +1,75 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+`)
+
+// ExtractURLs returns every http(s) or www. link found in text, in order.
+func ExtractURLs(text string) []string {
+	return urlPattern.FindAllString(text, -1)
+}
+
+type deniedDomains struct {
+	domains []string
+	action  Action
+}
+
+// NewDeniedDomains matches content linking to any of domains or their
+// subdomains.
+func NewDeniedDomains(domains []string, action Action) Filter {
+	f := &deniedDomains{action: action}
+	for _, d := range domains {
+		if d = strings.Trim(strings.ToLower(strings.TrimSpace(d)), "."); d != "" {
+			f.domains = append(f.domains, d)
+		}
+	}
+	return f
+}
+
+func (f *deniedDomains) Name() string {
+	return "denied_domains"
+}
+
+func (f *deniedDomains) Check(ctx context.Context, c Content) (*Match, error) {
+	if len(f.domains) == 0 {
+		return nil, nil
+	}
+
+	for _, link := range ExtractURLs(c.Text()) {
+		host := linkHost(link)
+		if host == "" {
+			continue
+		}
+
+		for _, d := range f.domains {
+			if host == d || strings.HasSuffix(host, "."+d) {
+				return &Match{
+					Filter: f.Name(),
+					Action: f.action,
+					Reason: fmt.Sprintf("links to denied domain %q", d),
+				}, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func linkHost(link string) string {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
@@ -0,0 +1,54 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// leet maps common look-alike substitutions back to letters so that
+// "h4te" and "hate" normalise to the same word.
+var leet = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'@': 'a',
+	'$': 's',
+}
+
+// Normalize folds text to a canonical form for matching: compatibility
+// decomposition (so full-width and styled letters become plain ones),
+// diacritics and invisible characters removed, lower-cased, and look-alike
+// digits and symbols mapped back to letters.
+func Normalize(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+
+	for _, r := range norm.NFKD.String(text) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case unicode.Is(unicode.Cf, r):
+			continue
+		}
+
+		if l, ok := leet[r]; ok {
+			r = l
+		}
+
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+// words splits normalised text on anything that is not a letter or a digit.
+func words(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,81 @@
+package filter
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"hate", "hate"},
+		{"HATE", "hate"},
+		{"h4t3", "hate"},
+		{"$p@m", "spam"},
+		{"ｈａｔｅ", "hate"},
+		{"𝐡𝐚𝐭𝐞", "hate"},
+		{"hâté", "hate"},
+		{"ha\u200bte", "hate"},
+		{"ha\u00adte", "hate"},
+		{"café 10", "cafe io"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := Normalize(tt.text); got != tt.want {
+				t.Fatalf("Normalize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"one two", []string{"one", "two"}},
+		{"  one,two!\nthree ", []string{"one", "two", "three"}},
+		{"don't", []string{"don", "t"}},
+		{"日本 語", []string{"日本", "語"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := words(tt.text)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("words(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBannedWordsNormalised(t *testing.T) {
+	f := NewBannedWords([]string{"Hate", " ", "spam"}, Reject)
+
+	tests := []struct {
+		content Content
+		matched bool
+	}{
+		{Content{Body: "I h4te mondays"}, true},
+		{Content{Title: "ＳＰＡＭ", Body: "buy now"}, true},
+		{Content{Body: "h a t e"}, false},
+		{Content{Body: "whatever"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.content.Text(), func(t *testing.T) {
+			match, err := f.Check(context.Background(), tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (match != nil) != tt.matched {
+				t.Fatalf("Check(%q) = %v, want matched %t", tt.content.Text(), match, tt.matched)
+			}
+		})
+	}
+}
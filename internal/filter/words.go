@@ -0,0 +1,51 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type bannedWords struct {
+	words  map[string]struct{}
+	action Action
+}
+
+// NewBannedWords matches content containing any of words, compared after
+// Unicode normalisation so homoglyph and accent tricks do not slip through.
+func NewBannedWords(words []string, action Action) Filter {
+	f := &bannedWords{
+		words:  make(map[string]struct{}, len(words)),
+		action: action,
+	}
+
+	for _, w := range words {
+		if w = strings.TrimSpace(Normalize(w)); w != "" {
+			f.words[w] = struct{}{}
+		}
+	}
+
+	return f
+}
+
+func (f *bannedWords) Name() string {
+	return "banned_words"
+}
+
+func (f *bannedWords) Check(ctx context.Context, c Content) (*Match, error) {
+	if len(f.words) == 0 {
+		return nil, nil
+	}
+
+	for _, w := range words(Normalize(c.Text())) {
+		if _, ok := f.words[w]; ok {
+			return &Match{
+				Filter: f.Name(),
+				Action: f.action,
+				Reason: fmt.Sprintf("contains banned word %q", w),
+			}, nil
+		}
+	}
+
+	return nil, nil
+}
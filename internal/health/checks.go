@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Postgres pings the database.
+func Postgres(db *sql.DB) Check {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// Redis pings the cache server.
+func Redis(rdb *redis.Client) Check {
+	return func(ctx context.Context) error {
+		return rdb.Ping(ctx).Err()
+	}
+}
+
+// Migrations fails unless the schema recorded by golang-migrate is clean and
+// at least at version want, the newest migration this build relies on.
+func Migrations(db *sql.DB, want int64) Check {
+	return func(ctx context.Context) error {
+		var (
+			version int64
+			dirty   bool
+		)
+		err := db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New("no migrations applied")
+			}
+			return err
+		}
+
+		if dirty {
+			return fmt.Errorf("migration %d is dirty", version)
+		}
+
+		if version < want {
+			return fmt.Errorf("schema version %d, want at least %d", version, want)
+		}
+
+		return nil
+	}
+}
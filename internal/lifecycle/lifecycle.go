@@ -0,0 +1,91 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+type component struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// Manager owns the background components of the process. Components are
+// stopped in reverse order of registration, like deferred calls, so
+// something registered after the database is stopped before it.
+type Manager struct {
+	logger *zap.SugaredLogger
+
+	mu         sync.Mutex
+	components []component
+	stopping   atomic.Bool
+}
+
+func New(logger *zap.SugaredLogger) *Manager {
+	return &Manager{logger: logger}
+}
+
+// OnStop registers stop to be called during Shutdown.
+func (m *Manager) OnStop(name string, stop func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.components = append(m.components, component{name: name, stop: stop})
+}
+
+// Go runs worker in its own goroutine until Shutdown cancels its context.
+// Shutdown waits for worker to return, or for its own context to expire.
+func (m *Manager) Go(name string, worker func(ctx context.Context) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		if err := worker(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			m.logger.Errorw("background worker stopped", "worker", name, "error", err)
+		}
+	}()
+
+	m.OnStop(name, func(stopCtx context.Context) error {
+		cancel()
+
+		select {
+		case <-done:
+			return nil
+		case <-stopCtx.Done():
+			return fmt.Errorf("worker did not stop: %w", stopCtx.Err())
+		}
+	})
+}
+
+// Shutdown stops every registered component, newest first. A failing
+// component does not prevent the rest from stopping; all errors are joined.
+// Only the first call has any effect.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	if !m.stopping.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	m.mu.Lock()
+	components := m.components
+	m.components = nil
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+
+		m.logger.Infow("stopping component", "component", c.name)
+		if err := c.stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/babaYaga451/social/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracedClient struct {
+	next Client
+}
+
+// WithTracing wraps next so that every Send runs in its own span.
+func WithTracing(next Client) Client {
+	return &tracedClient{next: next}
+}
+
+func (c *tracedClient) Send(ctx context.Context, templateFile, username, email string, data any, isSandbox bool) (int, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "mailer.Send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("mailer.template", templateFile),
+			attribute.Bool("mailer.sandbox", isSandbox),
+		),
+	)
+
+	status, err := c.next.Send(ctx, templateFile, username, email, data, isSandbox)
+	span.SetAttributes(attribute.Int("mailer.status", status))
+	tracing.End(span, err)
+
+	return status, err
+}
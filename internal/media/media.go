@@ -0,0 +1,121 @@
+// Package media validates and prepares uploaded images: the type is sniffed
+// from the bytes rather than trusted from the client, metadata such as EXIF
+// (camera details, GPS position) is removed, and a JPEG thumbnail is
+// rendered.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	TypeJPEG = "image/jpeg"
+	TypePNG  = "image/png"
+	TypeGIF  = "image/gif"
+	TypeWebP = "image/webp"
+
+	// MaxPixels bounds width*height so small files cannot decode into huge
+	// bitmaps.
+	MaxPixels = 50_000_000
+
+	jpegQuality = 90
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported media type, allowed are JPEG, PNG, GIF and WebP images")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+	ErrMalformed       = errors.New("malformed image")
+)
+
+var allowed = map[string]bool{
+	TypeJPEG: true,
+	TypePNG:  true,
+	TypeGIF:  true,
+	TypeWebP: true,
+}
+
+// Detect returns the content type of data judged by its leading magic
+// bytes, or ErrUnsupportedType.
+func Detect(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if !allowed[contentType] {
+		return "", ErrUnsupportedType
+	}
+	return contentType, nil
+}
+
+// Image is an upload ready to be stored.
+type Image struct {
+	ContentType string
+	Width       int
+	Height      int
+	// Data is the original image with its metadata removed.
+	Data []byte
+	// Thumbnail is a JPEG fitting in a thumbSize square.
+	Thumbnail []byte
+}
+
+// Process validates data and prepares it for storage. JPEGs carrying an
+// EXIF orientation are rotated into place before the orientation is
+// stripped along with the rest of the metadata.
+func Process(data []byte, thumbSize int) (*Image, error) {
+	contentType, err := Detect(data)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	orientation := 1
+	if contentType == TypeJPEG {
+		orientation = jpegOrientation(data)
+	}
+
+	if orientation > 1 {
+		img = orient(img, orientation)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		data = buf.Bytes()
+	} else {
+		data, err = StripMetadata(contentType, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	thumb, err := Thumbnail(img, thumbSize)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	return &Image{
+		ContentType: contentType,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		Data:        data,
+		Thumbnail:   thumb,
+	}, nil
+}
@@ -0,0 +1,115 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// exifPayload builds an APP1 EXIF payload whose first IFD holds only the
+// orientation tag.
+func exifPayload(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 26)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+
+	order.PutUint16(tiff[8:], 1)       // entries
+	order.PutUint16(tiff[10:], 0x0112) // orientation
+	order.PutUint16(tiff[12:], 3)      // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+	// the value is padded to 4 bytes, then the next IFD offset is 0
+
+	return append([]byte("Exif\x00\x00"), tiff...)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	app0 := segment(0xE0, []byte("JFIF\x00"))
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"little endian", jpegFile(app0, segment(0xE1, exifPayload(binary.LittleEndian, 6))), 6},
+		{"big endian", jpegFile(segment(0xE1, exifPayload(binary.BigEndian, 8))), 8},
+		{"no exif", jpegFile(app0), 1},
+		{"xmp only", jpegFile(segment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00"))), 1},
+		{"out of range", jpegFile(segment(0xE1, exifPayload(binary.LittleEndian, 9))), 1},
+		{"truncated", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x40, 'E', 'x'}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Fatalf("jpegOrientation = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// a 3x2 image with a marked top-left corner
+	red := color.RGBA{R: 255, A: 255}
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	src.SetRGBA(0, 0, red)
+
+	tests := []struct {
+		orientation int
+		w, h        int
+		// where the top-left corner ends up
+		x, y int
+	}{
+		{1, 3, 2, 0, 0},
+		{2, 3, 2, 2, 0},
+		{3, 3, 2, 2, 1},
+		{4, 3, 2, 0, 1},
+		{5, 2, 3, 0, 0},
+		{6, 2, 3, 1, 0},
+		{7, 2, 3, 1, 2},
+		{8, 2, 3, 0, 2},
+	}
+
+	for _, tt := range tests {
+		got := orient(src, tt.orientation).(*image.RGBA)
+		if b := got.Bounds(); b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("orientation %d: size %dx%d, want %dx%d", tt.orientation, b.Dx(), b.Dy(), tt.w, tt.h)
+			continue
+		}
+		if got.RGBAAt(tt.x, tt.y) != red {
+			t.Errorf("orientation %d: corner not at (%d, %d)", tt.orientation, tt.x, tt.y)
+		}
+	}
+}
+
+func TestProcessRotatesAndStrips(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 20)), nil); err != nil {
+		t.Fatal(err)
+	}
+	// insert the EXIF segment right after SOI
+	data := append([]byte{0xFF, 0xD8}, segment(0xE1, exifPayload(binary.BigEndian, 6))...)
+	data = append(data, buf.Bytes()[2:]...)
+
+	img, err := Process(data, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.ContentType != TypeJPEG || img.Width != 20 || img.Height != 40 {
+		t.Fatalf("Process = %s %dx%d, want %s 20x40", img.ContentType, img.Width, img.Height, TypeJPEG)
+	}
+	if got := jpegOrientation(img.Data); got != 1 {
+		t.Fatalf("processed image still has orientation %d", got)
+	}
+	if len(img.Thumbnail) == 0 {
+		t.Fatal("no thumbnail")
+	}
+}
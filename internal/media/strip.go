@@ -0,0 +1,146 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// StripMetadata removes EXIF, XMP, IPTC, comments and text chunks from
+// data without re-encoding the pixels. GIFs are returned unchanged.
+func StripMetadata(contentType string, data []byte) ([]byte, error) {
+	switch contentType {
+	case TypeJPEG:
+		return stripJPEG(data)
+	case TypePNG:
+		return stripPNG(data)
+	case TypeWebP:
+		return stripWebP(data)
+	default:
+		return data, nil
+	}
+}
+
+// stripJPEG drops APP1 (EXIF, XMP), APP13 (IPTC) and COM segments. Everything
+// from the start of scan on is image data and copied as is.
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, ErrMalformed
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+
+	for pos := 2; ; {
+		if pos+1 >= len(data) || data[pos] != 0xFF {
+			return nil, ErrMalformed
+		}
+
+		marker := data[pos+1]
+		switch {
+		case marker == 0xFF:
+			// fill byte before a marker
+			pos++
+			continue
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			out = append(out, data[pos:pos+2]...)
+			pos += 2
+			continue
+		case marker == 0xDA:
+			return append(out, data[pos:]...), nil
+		}
+
+		if pos+4 > len(data) {
+			return nil, ErrMalformed
+		}
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+		if end > len(data) {
+			return nil, ErrMalformed
+		}
+
+		if marker != 0xE1 && marker != 0xED && marker != 0xFE {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadata lists the ancillary chunks dropped from PNGs.
+var pngMetadata = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrMalformed
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+
+	for pos := len(pngSignature); pos < len(data); {
+		if pos+8 > len(data) {
+			return nil, ErrMalformed
+		}
+		// length, type, data and CRC
+		end := pos + 12 + int(binary.BigEndian.Uint32(data[pos:]))
+		if end > len(data) || end < pos {
+			return nil, ErrMalformed
+		}
+
+		if !pngMetadata[string(data[pos+4:pos+8])] {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+
+	return out, nil
+}
+
+// VP8X feature flags announcing metadata chunks.
+const (
+	webpFlagXMP  = 0x04
+	webpFlagEXIF = 0x08
+)
+
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrMalformed
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:12]...)
+
+	for pos := 12; pos < len(data); {
+		if pos+8 > len(data) {
+			return nil, ErrMalformed
+		}
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		// chunks are padded to an even size
+		end := pos + 8 + size + size%2
+		if end > len(data) || end < pos {
+			return nil, ErrMalformed
+		}
+
+		switch fourCC := string(data[pos : pos+4]); fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[pos:end]...)
+			if size > 0 {
+				out[start+8] &^= webpFlagEXIF | webpFlagXMP
+			}
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
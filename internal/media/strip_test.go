@@ -0,0 +1,165 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+)
+
+// segment builds a JPEG marker segment carrying payload.
+func segment(marker byte, payload []byte) []byte {
+	out := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(out[2:], uint16(len(payload)+2))
+	return append(out, payload...)
+}
+
+func jpegFile(segments ...[]byte) []byte {
+	out := []byte{0xFF, 0xD8}
+	for _, s := range segments {
+		out = append(out, s...)
+	}
+	// start of scan and the image data are copied verbatim
+	return append(out, 0xFF, 0xDA, 0x00, 0x02, 0x12, 0x34, 0xFF, 0xD9)
+}
+
+func pngChunk(typ string, data []byte) []byte {
+	out := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(out, uint32(len(data)))
+	copy(out[4:], typ)
+	out = append(out, data...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(out[4:]))
+}
+
+func pngFile(chunks ...[]byte) []byte {
+	out := append([]byte{}, pngSignature...)
+	for _, c := range chunks {
+		out = append(out, c...)
+	}
+	return out
+}
+
+func webpChunk(fourCC string, data []byte) []byte {
+	out := make([]byte, 8, 8+len(data)+1)
+	copy(out, fourCC)
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(data)))
+	out = append(out, data...)
+	if len(data)%2 == 1 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+func webpFile(chunks ...[]byte) []byte {
+	out := []byte("RIFF\x00\x00\x00\x00WEBP")
+	for _, c := range chunks {
+		out = append(out, c...)
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out
+}
+
+func TestStripMetadata(t *testing.T) {
+	app0 := segment(0xE0, []byte("JFIF\x00\x01\x02"))
+	dqt := segment(0xDB, []byte{0, 1, 2, 3})
+	exif := segment(0xE1, exifPayload(binary.LittleEndian, 6))
+	iptc := segment(0xED, []byte("Photoshop 3.0\x00"))
+	comment := segment(0xFE, []byte("taken at home"))
+
+	ihdr := pngChunk("IHDR", make([]byte, 13))
+	idat := pngChunk("IDAT", []byte{1, 2, 3})
+	iend := pngChunk("IEND", nil)
+
+	vp8x := webpChunk("VP8X", []byte{webpFlagEXIF | webpFlagXMP | 0x10, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	vp8xStripped := webpChunk("VP8X", []byte{0x10, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	vp8 := webpChunk("VP8 ", []byte{1, 2, 3})
+
+	tests := []struct {
+		name        string
+		contentType string
+		data        []byte
+		want        []byte
+		wantErr     error
+	}{
+		{
+			name:        "jpeg",
+			contentType: TypeJPEG,
+			data:        jpegFile(app0, exif, dqt, iptc, comment),
+			want:        jpegFile(app0, dqt),
+		},
+		{
+			name:        "jpeg with fill bytes and restart markers",
+			contentType: TypeJPEG,
+			data:        jpegFile([]byte{0xFF}, app0, []byte{0xFF, 0xD0}, comment),
+			want:        jpegFile(app0, []byte{0xFF, 0xD0}),
+		},
+		{
+			name:        "jpeg without metadata",
+			contentType: TypeJPEG,
+			data:        jpegFile(app0, dqt),
+			want:        jpegFile(app0, dqt),
+		},
+		{
+			name:        "jpeg truncated segment",
+			contentType: TypeJPEG,
+			data:        []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x10, 0x00},
+			wantErr:     ErrMalformed,
+		},
+		{
+			name:        "not a jpeg",
+			contentType: TypeJPEG,
+			data:        []byte("GIF89a"),
+			wantErr:     ErrMalformed,
+		},
+		{
+			name:        "png",
+			contentType: TypePNG,
+			data: pngFile(ihdr, pngChunk("tEXt", []byte("Author\x00me")), pngChunk("eXIf", []byte("MM")),
+				idat, pngChunk("tIME", make([]byte, 7)), iend),
+			want: pngFile(ihdr, idat, iend),
+		},
+		{
+			name:        "png truncated chunk",
+			contentType: TypePNG,
+			data:        pngFile(ihdr, []byte{0, 0, 1, 0, 'I', 'D', 'A', 'T'}),
+			wantErr:     ErrMalformed,
+		},
+		{
+			name:        "webp",
+			contentType: TypeWebP,
+			data:        webpFile(vp8x, vp8, webpChunk("EXIF", []byte("II*")), webpChunk("XMP ", []byte("<x/>"))),
+			want:        webpFile(vp8xStripped, vp8),
+		},
+		{
+			name:        "webp truncated chunk",
+			contentType: TypeWebP,
+			data:        webpFile([]byte("VP8 \xff\x00\x00\x00")),
+			wantErr:     ErrMalformed,
+		},
+		{
+			name:        "gif unchanged",
+			contentType: TypeGIF,
+			data:        []byte("GIF89a anything"),
+			want:        []byte("GIF89a anything"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := StripMetadata(tt.contentType, tt.data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("StripMetadata error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Fatalf("StripMetadata = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Package mentions finds @username mentions in text, along with where they
+// are, so that they can be stored as entities pointing at users.
+package mentions
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// userName is what a username may consist of, as enforced at registration
+// and by the users table: up to 100 ASCII letters, digits and underscores.
+const userName = `[A-Za-z0-9_]{1,100}`
+
+var (
+	// pattern matches an @ not preceded by a username character or another
+	// @, so e-mail addresses and "@@name" are not mentions.
+	pattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_@])(@(` + userName + `))`)
+
+	userNamePattern = regexp.MustCompile(`^` + userName + `$`)
+)
+
+// ValidUserName reports whether name can be used as a username, i.e.
+// mentioned as @name.
+func ValidUserName(name string) bool {
+	return userNamePattern.MatchString(name)
+}
+
+// Mention is a mention of UserName spanning [Start, End) in the text.
+// Offsets count Unicode code points, not bytes, and include the @.
+type Mention struct {
+	UserName string
+	Start    int
+	End      int
+}
+
+// Extract returns the mentions in text in order of appearance, up to max of
+// them. The same user may be mentioned more than once.
+func Extract(text string, max int) []Mention {
+	matches := pattern.FindAllStringSubmatchIndex(text, max)
+
+	mentions := make([]Mention, 0, len(matches))
+	offset, runes := 0, 0
+	for _, m := range matches {
+		start, end := m[2], m[3]
+
+		// count code points incrementally, as matches come in order
+		runes += utf8.RuneCountInString(text[offset:start])
+		length := utf8.RuneCountInString(text[start:end])
+
+		mentions = append(mentions, Mention{
+			UserName: text[m[4]:m[5]],
+			Start:    runes,
+			End:      runes + length,
+		})
+
+		runes += length
+		offset = end
+	}
+
+	return mentions
+}
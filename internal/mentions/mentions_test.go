@@ -0,0 +1,106 @@
+package mentions
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		max  int
+		want []Mention
+	}{
+		{
+			name: "start of text",
+			text: "@alice hi",
+			max:  10,
+			want: []Mention{{UserName: "alice", Start: 0, End: 6}},
+		},
+		{
+			name: "several",
+			text: "hi @alice and @bob_2!",
+			max:  10,
+			want: []Mention{{UserName: "alice", Start: 3, End: 9}, {UserName: "bob_2", Start: 14, End: 20}},
+		},
+		{
+			name: "offsets count code points",
+			text: "héllo 👋 @alice",
+			max:  10,
+			want: []Mention{{UserName: "alice", Start: 8, End: 14}},
+		},
+		{
+			name: "repeated",
+			text: "@bob @bob",
+			max:  10,
+			want: []Mention{{UserName: "bob", Start: 0, End: 4}, {UserName: "bob", Start: 5, End: 9}},
+		},
+		{
+			name: "e-mail address",
+			text: "mail me at bob@example.com",
+			max:  10,
+			want: []Mention{},
+		},
+		{
+			name: "double at",
+			text: "@@bob",
+			max:  10,
+			want: []Mention{},
+		},
+		{
+			name: "bare at",
+			text: "meet @ noon",
+			max:  10,
+			want: []Mention{},
+		},
+		{
+			name: "stops at characters usernames cannot have",
+			text: "@josé and @bob-smith",
+			max:  10,
+			want: []Mention{{UserName: "jos", Start: 0, End: 4}, {UserName: "bob", Start: 10, End: 14}},
+		},
+		{
+			name: "capped",
+			text: "@a @b @c",
+			max:  2,
+			want: []Mention{{UserName: "a", Start: 0, End: 2}, {UserName: "b", Start: 3, End: 5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Extract(tt.text, tt.max)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Extract(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidUserName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"alice", true},
+		{"Bob_2", true},
+		{"_", true},
+		{strings.Repeat("a", 100), true},
+		{"", false},
+		{strings.Repeat("a", 101), false},
+		{"josé", false},
+		{"bob smith", false},
+		{"bob-smith", false},
+		{"@bob", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidUserName(tt.name); got != tt.want {
+				t.Fatalf("ValidUserName(%q) = %t, want %t", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/babaYaga451/social/internal/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "social"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, chi route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and chi route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	StoreDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "store_query_duration_seconds",
+		Help:      "Store method latency.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"method"})
+
+	StoreErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "store_query_errors_total",
+		Help:      "Store method errors, not counting record-not-found.",
+	}, []string{"method"})
+
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Cache lookups by cache and result (hit, miss, error).",
+	}, []string{"cache", "result"})
+)
+
+// Handler serves every registered metric in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RegisterDB exports the connection pool statistics of db.
+func RegisterDB(db *sql.DB, name string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveStore is a store.Interceptor recording the duration and errors of
+// every store method.
+func ObserveStore(ctx context.Context, method string, call func(context.Context) error) error {
+	start := time.Now()
+	err := call(ctx)
+	StoreDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+
+	if err != nil && !errors.Is(err, store.ErrorNotFound) {
+		StoreErrors.WithLabelValues(method).Inc()
+	}
+
+	return err
+}
+
+// ObserveCache records the result of a cache lookup.
+func ObserveCache(cache string, hit bool, err error) {
+	switch {
+	case err != nil:
+		CacheRequests.WithLabelValues(cache, "error").Inc()
+	case hit:
+		CacheRequests.WithLabelValues(cache, "hit").Inc()
+	default:
+		CacheRequests.WithLabelValues(cache, "miss").Inc()
+	}
+}
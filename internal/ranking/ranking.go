@@ -0,0 +1,137 @@
+// Package ranking orders feed candidates for the "For You" feed.
+package ranking
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/babaYaga451/social/internal/store"
+)
+
+// Weights controls how much each signal contributes to a post's score.
+// Engagement counts comments and reposts alike.
+type Weights struct {
+	Recency    float64
+	Affinity   float64
+	Engagement float64
+	Tags       float64
+
+	// RecencyHalfLife is the age at which the recency signal drops to half.
+	RecencyHalfLife time.Duration
+	// DiversityPenalty multiplies the score of each further post by an
+	// author already placed higher, so one author cannot fill the page.
+	DiversityPenalty float64
+}
+
+// Explanation breaks a score down into the weighted contribution of each
+// signal.
+type Explanation struct {
+	Recency    float64 `json:"recency"`
+	Affinity   float64 `json:"affinity"`
+	Engagement float64 `json:"engagement"`
+	Tags       float64 `json:"tags"`
+	Diversity  float64 `json:"diversity"`
+	Score      float64 `json:"score"`
+}
+
+type Ranked struct {
+	Post        store.PostWithMetaData
+	Explanation Explanation
+}
+
+// Rank scores candidates and orders them best first. Duplicate posts are
+// dropped and consecutive picks from the same author are penalized.
+func Rank(now time.Time, candidates []store.PostWithMetaData, signals store.EngagementSignals, w Weights) []Ranked {
+	seen := make(map[int64]bool, len(candidates))
+	scored := make([]Ranked, 0, len(candidates))
+	for _, post := range candidates {
+		if seen[post.ID] {
+			continue
+		}
+		seen[post.ID] = true
+
+		scored = append(scored, Ranked{Post: post, Explanation: explain(now, post, signals, w)})
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Explanation.Score > scored[j].Explanation.Score
+	})
+
+	return diversify(scored, w.DiversityPenalty)
+}
+
+func explain(now time.Time, post store.PostWithMetaData, signals store.EngagementSignals, w Weights) Explanation {
+	e := Explanation{
+		Recency:    w.Recency * recency(now, post.CreatedAt, w.RecencyHalfLife),
+		Affinity:   w.Affinity * math.Log1p(float64(signals.AuthorAffinity[post.UserID])),
+		Engagement: w.Engagement * math.Log1p(float64(post.CommentCount+post.RepostCount)),
+		Tags:       w.Tags * tagOverlap(post.Tags, signals.Tags),
+		Diversity:  1,
+	}
+	e.Score = e.Recency + e.Affinity + e.Engagement + e.Tags
+
+	return e
+}
+
+// recency decays exponentially from 1 for a brand new post.
+func recency(now time.Time, createdAt string, halfLife time.Duration) float64 {
+	t, err := time.Parse(time.RFC3339, createdAt)
+	if err != nil || halfLife <= 0 {
+		return 0
+	}
+
+	age := now.Sub(t)
+	if age < 0 {
+		age = 0
+	}
+
+	return math.Pow(0.5, float64(age)/float64(halfLife))
+}
+
+// tagOverlap is the share of the post's tags the reader engaged with before.
+func tagOverlap(tags []string, engaged map[string]int) float64 {
+	if len(tags) == 0 {
+		return 0
+	}
+
+	matched := 0
+	for _, tag := range tags {
+		if engaged[tag] > 0 {
+			matched++
+		}
+	}
+
+	return float64(matched) / float64(len(tags))
+}
+
+// diversify reorders scored, which is sorted best first, greedily picking
+// the best post after applying penalty once per post of the same author
+// already picked.
+func diversify(scored []Ranked, penalty float64) []Ranked {
+	if penalty <= 0 || penalty >= 1 {
+		return scored
+	}
+
+	picked := make(map[int64]int)
+	out := make([]Ranked, 0, len(scored))
+	for len(scored) > 0 {
+		best, bestScore := 0, math.Inf(-1)
+		for i, r := range scored {
+			score := r.Explanation.Score * math.Pow(penalty, float64(picked[r.Post.UserID]))
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		r := scored[best]
+		r.Explanation.Diversity = math.Pow(penalty, float64(picked[r.Post.UserID]))
+		r.Explanation.Score = bestScore
+		picked[r.Post.UserID]++
+
+		out = append(out, r)
+		scored = append(scored[:best], scored[best+1:]...)
+	}
+
+	return out
+}
@@ -0,0 +1,148 @@
+package ranking
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/babaYaga451/social/internal/store"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func post(id, userID int64, age time.Duration, comments, reposts int, tags ...string) store.PostWithMetaData {
+	return store.PostWithMetaData{
+		Post: store.Post{
+			ID:          id,
+			UserID:      userID,
+			CreatedAt:   now.Add(-age).Format(time.RFC3339),
+			Tags:        tags,
+			RepostCount: reposts,
+		},
+		CommentCount: comments,
+	}
+}
+
+func ids(ranked []Ranked) []int64 {
+	out := make([]int64, len(ranked))
+	for i, r := range ranked {
+		out[i] = r.Post.ID
+	}
+	return out
+}
+
+func TestRank(t *testing.T) {
+	noSignals := store.EngagementSignals{}
+
+	tests := []struct {
+		name       string
+		candidates []store.PostWithMetaData
+		signals    store.EngagementSignals
+		weights    Weights
+		want       []int64
+	}{
+		{
+			name: "newest first on recency alone",
+			candidates: []store.PostWithMetaData{
+				post(1, 1, 3*time.Hour, 0, 0),
+				post(2, 2, time.Hour, 0, 0),
+				post(3, 3, 2*time.Hour, 0, 0),
+			},
+			signals: noSignals,
+			weights: Weights{Recency: 1, RecencyHalfLife: time.Hour},
+			want:    []int64{2, 3, 1},
+		},
+		{
+			name: "reposts count as engagement",
+			candidates: []store.PostWithMetaData{
+				post(1, 1, time.Hour, 0, 0),
+				post(2, 2, time.Hour, 0, 5),
+				post(3, 3, time.Hour, 2, 0),
+			},
+			signals: noSignals,
+			weights: Weights{Engagement: 1},
+			want:    []int64{2, 3, 1},
+		},
+		{
+			name: "affinity and tags",
+			candidates: []store.PostWithMetaData{
+				post(1, 1, time.Hour, 0, 0),
+				post(2, 2, time.Hour, 0, 0, "go"),
+				post(3, 3, time.Hour, 0, 0),
+			},
+			signals: store.EngagementSignals{
+				AuthorAffinity: map[int64]int{3: 10},
+				Tags:           map[string]int{"go": 1},
+			},
+			weights: Weights{Affinity: 1, Tags: 1},
+			want:    []int64{3, 2, 1},
+		},
+		{
+			name: "duplicates dropped",
+			candidates: []store.PostWithMetaData{
+				post(1, 1, time.Hour, 0, 0),
+				post(1, 1, time.Hour, 0, 0),
+				post(2, 2, 2*time.Hour, 0, 0),
+			},
+			signals: noSignals,
+			weights: Weights{Recency: 1, RecencyHalfLife: time.Hour},
+			want:    []int64{1, 2},
+		},
+		{
+			name: "one author cannot fill the page",
+			candidates: []store.PostWithMetaData{
+				post(1, 1, 0, 0, 0),
+				post(2, 1, time.Minute, 0, 0),
+				post(3, 2, 10*time.Minute, 0, 0),
+			},
+			signals: noSignals,
+			weights: Weights{Recency: 1, RecencyHalfLife: time.Hour, DiversityPenalty: 0.5},
+			want:    []int64{1, 3, 2},
+		},
+		{
+			name: "penalty outside (0, 1) is ignored",
+			candidates: []store.PostWithMetaData{
+				post(1, 1, 0, 0, 0),
+				post(2, 1, time.Minute, 0, 0),
+				post(3, 2, 10*time.Minute, 0, 0),
+			},
+			signals: noSignals,
+			weights: Weights{Recency: 1, RecencyHalfLife: time.Hour, DiversityPenalty: 1},
+			want:    []int64{1, 2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ids(Rank(now, tt.candidates, tt.signals, tt.weights))
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("Rank = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecency(t *testing.T) {
+	tests := []struct {
+		name      string
+		createdAt string
+		halfLife  time.Duration
+		want      float64
+	}{
+		{"new", now.Format(time.RFC3339), time.Hour, 1},
+		{"one half-life", now.Add(-time.Hour).Format(time.RFC3339), time.Hour, 0.5},
+		{"two half-lives", now.Add(-2 * time.Hour).Format(time.RFC3339), time.Hour, 0.25},
+		{"future", now.Add(time.Hour).Format(time.RFC3339), time.Hour, 1},
+		{"no half-life", now.Format(time.RFC3339), 0, 0},
+		{"unparsable", "yesterday", time.Hour, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recency(now, tt.createdAt, tt.halfLife); math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("recency = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
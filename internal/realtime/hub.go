@@ -0,0 +1,139 @@
+// Package realtime delivers events to the users connected to an API
+// instance, such as new messages to the members of a conversation.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// channel carries events between instances when the hub runs on Redis.
+const channel = "realtime-events"
+
+// Event is something that happened for a user, named by Type and described
+// by Data, as written to their streams.
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type delivery struct {
+	UserIDs []int64 `json:"user_ids"`
+	Event   Event   `json:"event"`
+}
+
+// Hub tracks the streams open on this instance per user. Without Redis,
+// events only reach users connected to the instance publishing them; with
+// it, they go through pub/sub and every instance delivers to its own
+// streams.
+type Hub struct {
+	rdb    *redis.Client
+	buffer int
+
+	mu      sync.RWMutex
+	streams map[int64]map[chan Event]struct{}
+}
+
+// NewHub returns a hub buffering up to buffer events per stream. rdb may be
+// nil to deliver in-process only.
+func NewHub(rdb *redis.Client, buffer int) *Hub {
+	return &Hub{
+		rdb:     rdb,
+		buffer:  buffer,
+		streams: map[int64]map[chan Event]struct{}{},
+	}
+}
+
+// Subscribe opens a stream of the events of userID. The returned function
+// closes it and must be called once the stream is no longer read.
+func (h *Hub) Subscribe(userID int64) (<-chan Event, func()) {
+	ch := make(chan Event, h.buffer)
+
+	h.mu.Lock()
+	if h.streams[userID] == nil {
+		h.streams[userID] = map[chan Event]struct{}{}
+	}
+	h.streams[userID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		delete(h.streams[userID], ch)
+		if len(h.streams[userID]) == 0 {
+			delete(h.streams, userID)
+		}
+	}
+}
+
+// Publish delivers an event of the given type to the streams of userIDs,
+// wherever they are connected. Users without an open stream miss it.
+func (h *Hub) Publish(ctx context.Context, userIDs []int64, eventType string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	d := delivery{UserIDs: userIDs, Event: Event{Type: eventType, Data: raw}}
+
+	if h.rdb == nil {
+		h.deliver(d)
+		return nil
+	}
+
+	payload, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	return h.rdb.Publish(ctx, channel, payload).Err()
+}
+
+// Listen delivers the events published by any instance to the streams open
+// on this one until ctx is cancelled. It is only needed on Redis.
+func (h *Hub) Listen(ctx context.Context) error {
+	if h.rdb == nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	sub := h.rdb.Subscribe(ctx, channel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+
+			var d delivery
+			if err := json.Unmarshal([]byte(msg.Payload), &d); err != nil {
+				continue
+			}
+			h.deliver(d)
+		}
+	}
+}
+
+// deliver hands an event to the local streams of its users. A stream too
+// slow to keep up drops the event rather than holding up the others.
+func (h *Hub) deliver(d delivery) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, userID := range d.UserIDs {
+		for ch := range h.streams[userID] {
+			select {
+			case ch <- d.Event:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package realtime
+
+import (
+	"context"
+	"strconv"
+	"testing"
+)
+
+func TestHubDropsForSlowStreams(t *testing.T) {
+	ctx := context.Background()
+	h := NewHub(nil, 2)
+
+	slow, closeSlow := h.Subscribe(1)
+	defer closeSlow()
+	fast, closeFast := h.Subscribe(2)
+	defer closeFast()
+
+	for i := range 5 {
+		// nobody reads the slow stream; publishing must not block on it
+		if err := h.Publish(ctx, []int64{1, 2}, "message", i); err != nil {
+			t.Fatal(err)
+		}
+
+		event := <-fast
+		if event.Type != "message" || string(event.Data) != strconv.Itoa(i) {
+			t.Fatalf("fast stream got %s %s, want message %d", event.Type, event.Data, i)
+		}
+	}
+
+	if len(slow) != 2 {
+		t.Fatalf("slow stream holds %d events, want its buffer of 2", len(slow))
+	}
+	// the slow stream keeps the oldest events and drops the rest
+	if event := <-slow; string(event.Data) != "0" {
+		t.Fatalf("slow stream first event %s, want 0", event.Data)
+	}
+}
+
+func TestHubDelivery(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		subscribed []int64
+		publishTo  []int64
+		// want is how many events each subscription receives
+		want []int
+	}{
+		{name: "addressed user", subscribed: []int64{1}, publishTo: []int64{1}, want: []int{1}},
+		{name: "other user", subscribed: []int64{1}, publishTo: []int64{2}, want: []int{0}},
+		{name: "every stream of a user", subscribed: []int64{1, 1}, publishTo: []int64{1}, want: []int{1, 1}},
+		{name: "nobody connected", publishTo: []int64{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHub(nil, 4)
+
+			streams := make([]<-chan Event, len(tt.subscribed))
+			for i, userID := range tt.subscribed {
+				ch, unsubscribe := h.Subscribe(userID)
+				defer unsubscribe()
+				streams[i] = ch
+			}
+
+			if err := h.Publish(ctx, tt.publishTo, "message", "hi"); err != nil {
+				t.Fatal(err)
+			}
+
+			for i, ch := range streams {
+				if len(ch) != tt.want[i] {
+					t.Fatalf("stream %d got %d events, want %d", i, len(ch), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHubUnsubscribe(t *testing.T) {
+	h := NewHub(nil, 1)
+
+	ch, unsubscribe := h.Subscribe(1)
+	unsubscribe()
+
+	if err := h.Publish(context.Background(), []int64{1}, "message", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if len(ch) != 0 {
+		t.Fatal("closed stream received an event")
+	}
+	if len(h.streams) != 0 {
+		t.Fatalf("hub still tracks %d users", len(h.streams))
+	}
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+const (
+	AuditActionRoleCreate           = "role.create"
+	AuditActionRolePermissionsSet   = "role.permissions.set"
+	AuditActionUserRoleAssign       = "user.role.assign"
+	AuditActionUserPermissionGrant  = "user.permission.grant"
+	AuditActionUserPermissionRevoke = "user.permission.revoke"
+	AuditActionUserDeactivate       = "user.deactivate"
+	AuditActionUserBan              = "user.ban"
+	AuditActionUserUnban            = "user.unban"
+	AuditActionUserLogout           = "user.logout"
+	AuditActionUserImpersonate      = "user.impersonate"
+	AuditActionUserRegister         = "user.register"
+	AuditActionLoginSuccess         = "auth.login.success"
+	AuditActionLoginFailure         = "auth.login.failure"
+	AuditActionPostCreate           = "post.create"
+	AuditActionPostUpdate           = "post.update"
+	AuditActionPostDelete           = "post.delete"
+	AuditActionReportResolve        = "report.resolve"
+
+	AuditTargetRole   = "role"
+	AuditTargetUser   = "user"
+	AuditTargetPost   = "post"
+	AuditTargetReport = "report"
+)
+
+type AuditEvent struct {
+	ID         int64          `json:"id"`
+	ActorID    int64          `json:"actor_id"`
+	Action     string         `json:"action"`
+	TargetType string         `json:"target_type"`
+	TargetID   int64          `json:"target_id"`
+	IP         string         `json:"ip"`
+	RequestID  string         `json:"request_id"`
+	Metadata   map[string]any `json:"metadata"`
+	Diff       map[string]any `json:"diff"`
+	CreatedAt  string         `json:"created_at"`
+}
+
+type AuditStore struct {
+	db *sql.DB
+}
+
+func (s *AuditStore) Create(ctx context.Context, event *AuditEvent) error {
+	query := `
+  INSERT INTO audit_events (actor_id, action, target_type, target_id, ip, request_id, metadata, diff)
+  VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7, $8)
+  RETURNING id, created_at
+  `
+	metadata, err := marshalObject(event.Metadata)
+	if err != nil {
+		return err
+	}
+
+	diff, err := marshalObject(event.Diff)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	return s.db.QueryRowContext(
+		ctx,
+		query,
+		event.ActorID,
+		event.Action,
+		event.TargetType,
+		event.TargetID,
+		event.IP,
+		event.RequestID,
+		metadata,
+		diff,
+	).Scan(
+		&event.ID,
+		&event.CreatedAt,
+	)
+}
+
+// List returns the events matching aq, newest first.
+func (s *AuditStore) List(ctx context.Context, aq PaginatedAuditQuery) ([]AuditEvent, error) {
+	query := `
+  SELECT id, COALESCE(actor_id, 0), action, target_type, target_id, ip, request_id,
+    metadata, diff, created_at
+  FROM audit_events
+  WHERE ($1 = 0 OR actor_id = $1)
+    AND ($2 = '' OR action = $2)
+    AND ($3 = '' OR target_type = $3)
+    AND ($4 = 0 OR target_id = $4)
+    AND ($5::timestamptz IS NULL OR created_at >= $5)
+    AND ($6::timestamptz IS NULL OR created_at < $6)
+  ORDER BY created_at ` + aq.Sort + `, id ` + aq.Sort + `
+  LIMIT $7 OFFSET $8
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		aq.ActorID,
+		aq.Action,
+		aq.TargetType,
+		aq.TargetID,
+		aq.From,
+		aq.To,
+		aq.Limit,
+		aq.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []AuditEvent{}
+	for rows.Next() {
+		var (
+			e        AuditEvent
+			metadata []byte
+			diff     []byte
+		)
+		err := rows.Scan(
+			&e.ID,
+			&e.ActorID,
+			&e.Action,
+			&e.TargetType,
+			&e.TargetID,
+			&e.IP,
+			&e.RequestID,
+			&metadata,
+			&diff,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(metadata, &e.Metadata); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(diff, &e.Diff); err != nil {
+			return nil, err
+		}
+
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+func marshalObject(v map[string]any) ([]byte, error) {
+	if v == nil {
+		v = map[string]any{}
+	}
+	return json.Marshal(v)
+}
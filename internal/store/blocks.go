@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// BlockStore keeps the users each user blocked. A block works both ways:
+// neither user can message the other, nor share a new conversation with
+// them.
+type BlockStore struct {
+	db *sql.DB
+}
+
+func (s *BlockStore) Block(ctx context.Context, userID, blockedID int64) error {
+	query := `
+  INSERT INTO user_blocks (user_id, blocked_id)
+  VALUES ($1, $2)
+  ON CONFLICT DO NOTHING
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, blockedID)
+	return err
+}
+
+func (s *BlockStore) Unblock(ctx context.Context, userID, blockedID int64) error {
+	query := `
+  DELETE FROM user_blocks
+  WHERE user_id = $1 AND blocked_id = $2
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, blockedID)
+	return err
+}
+
+// Blocked reports whether userID blocked, or was blocked by, any of others.
+func (s *BlockStore) Blocked(ctx context.Context, userID int64, others []int64) (bool, error) {
+	query := `
+  SELECT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE (user_id = $1 AND blocked_id = ANY($2))
+      OR (blocked_id = $1 AND user_id = ANY($2))
+  )
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	var blocked bool
+	err := s.db.QueryRowContext(ctx, query, userID, pq.Array(others)).Scan(&blocked)
+	return blocked, err
+}
+
+// AnyBlocked reports whether any two of userIDs blocked each other.
+func (s *BlockStore) AnyBlocked(ctx context.Context, userIDs []int64) (bool, error) {
+	query := `
+  SELECT EXISTS (
+    SELECT 1 FROM user_blocks
+    WHERE user_id = ANY($1) AND blocked_id = ANY($1)
+  )
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	var blocked bool
+	err := s.db.QueryRowContext(ctx, query, pq.Array(userIDs)).Scan(&blocked)
+	return blocked, err
+}
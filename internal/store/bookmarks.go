@@ -0,0 +1,240 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var ErrDuplicateCollection = errors.New("a collection with this name already exists")
+
+// BookmarkStore keeps the private bookmarks of users and the named
+// collections they file them in. A bookmark is in at most one collection.
+type BookmarkStore struct {
+	db *sql.DB
+}
+
+type BookmarkCollection struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"user_id"`
+	Name      string `json:"name"`
+	PostCount int    `json:"post_count"`
+	CreatedAt string `json:"created_at"`
+}
+
+// Save bookmarks a post for userID, filed in collectionID unless it is nil.
+// Saving an existing bookmark moves it. It fails with ErrorNotFound when
+// the collection is not one of userID's.
+func (s *BookmarkStore) Save(ctx context.Context, userID, postID int64, collectionID *int64) error {
+	query := `
+  INSERT INTO bookmarks (user_id, post_id, collection_id)
+  SELECT $1, $2, $3
+  WHERE $3::bigint IS NULL OR EXISTS (
+    SELECT 1 FROM bookmark_collections WHERE id = $3 AND user_id = $1
+  )
+  ON CONFLICT (user_id, post_id) DO UPDATE SET collection_id = EXCLUDED.collection_id
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, userID, postID, collectionID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+func (s *BookmarkStore) Delete(ctx context.Context, userID, postID int64) error {
+	query := `
+  DELETE FROM bookmarks
+  WHERE user_id = $1 AND post_id = $2
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, postID)
+	return err
+}
+
+// GetPosts returns a page of the posts bookmarked by userID, in
+// collectionID or in any collection if it is nil, ordered by when they were
+// bookmarked. Posts that have since been unpublished or hidden are left
+// out, unless fq.IncludeHidden is set or they are userID's own, and so are
+// those of private users userID no longer follows.
+func (s *BookmarkStore) GetPosts(ctx context.Context, userID int64, collectionID *int64, fq PaginatedFeedQuery) ([]PostWithMetaData, error) {
+	query := `
+  SELECT
+    p.id,
+    p.user_id,
+    u.username,
+    p.title,
+    p.created_at,
+    p.version,
+    p.tags,
+    p.quoted_post_id,
+    (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.hidden_at IS NULL),
+    (SELECT COUNT(*) FROM reposts r WHERE r.post_id = p.id)
+  FROM bookmarks b
+  JOIN posts p ON p.id = b.post_id
+  JOIN users u ON u.id = p.user_id
+  WHERE b.user_id = $1 AND ($2::bigint IS NULL OR b.collection_id = $2)
+    AND (p.user_id = $1 OR (p.status = 'published' AND (p.hidden_at IS NULL OR $5::boolean)
+      AND (NOT u.is_private OR EXISTS (
+        SELECT 1 FROM followers f WHERE f.user_id = u.id AND f.follower_id = $1
+      ))))
+  ORDER BY b.created_at ` + fq.Sort + `, p.id ` + fq.Sort + `
+  LIMIT $3 OFFSET $4
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, collectionID, fq.Limit, fq.Offset, fq.IncludeHidden)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []PostWithMetaData{}
+	for rows.Next() {
+		var p PostWithMetaData
+		err := rows.Scan(
+			&p.ID,
+			&p.UserID,
+			&p.User.UserName,
+			&p.Title,
+			&p.CreatedAt,
+			&p.Version,
+			pq.Array(&p.Tags),
+			&p.QuotedPostID,
+			&p.CommentCount,
+			&p.RepostCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+
+	return posts, rows.Err()
+}
+
+func (s *BookmarkStore) CreateCollection(ctx context.Context, collection *BookmarkCollection) error {
+	query := `
+  INSERT INTO bookmark_collections (user_id, name)
+  VALUES ($1, $2)
+  RETURNING id, created_at
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, collection.UserID, collection.Name).Scan(&collection.ID, &collection.CreatedAt)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), `"bookmark_collections_user_name_key"`):
+			return ErrDuplicateCollection
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *BookmarkStore) GetCollection(ctx context.Context, id int64) (*BookmarkCollection, error) {
+	query := `
+  SELECT bc.id, bc.user_id, bc.name, bc.created_at,
+    (SELECT COUNT(*) FROM bookmarks b WHERE b.collection_id = bc.id)
+  FROM bookmark_collections bc
+  WHERE bc.id = $1
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	var c BookmarkCollection
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&c.ID, &c.UserID, &c.Name, &c.CreatedAt, &c.PostCount)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &c, nil
+}
+
+// Collections lists the collections of userID by name.
+func (s *BookmarkStore) Collections(ctx context.Context, userID int64) ([]BookmarkCollection, error) {
+	query := `
+  SELECT bc.id, bc.user_id, bc.name, bc.created_at,
+    (SELECT COUNT(*) FROM bookmarks b WHERE b.collection_id = bc.id)
+  FROM bookmark_collections bc
+  WHERE bc.user_id = $1
+  ORDER BY bc.name
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []BookmarkCollection{}
+	for rows.Next() {
+		var c BookmarkCollection
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.CreatedAt, &c.PostCount); err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+
+	return collections, rows.Err()
+}
+
+// RenameCollection renames a collection, failing with
+// ErrDuplicateCollection if its owner already has one by that name.
+func (s *BookmarkStore) RenameCollection(ctx context.Context, collection *BookmarkCollection) error {
+	query := `
+  UPDATE bookmark_collections SET name = $2
+  WHERE id = $1
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, collection.ID, collection.Name)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), `"bookmark_collections_user_name_key"`):
+			return ErrDuplicateCollection
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteCollection deletes a collection; its bookmarks are kept, unfiled.
+func (s *BookmarkStore) DeleteCollection(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM bookmark_collections WHERE id = $1`, id)
+	return err
+}
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	BackendNone   = "none"
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+	BackendTiered = "tiered"
+)
+
+// Backend stores raw cache entries. Entries live under a key and an optional
+// field; deleting a key drops all of its fields, which lets a group of
+// entries (e.g. every feed page of a user, or every cached version of a
+// post) be invalidated together.
+type Backend interface {
+	// Get returns the value under key and field, or nil on a miss.
+	Get(ctx context.Context, key, field string) ([]byte, error)
+	// GetAll returns every field under key, or nil on a miss.
+	GetAll(ctx context.Context, key string) (map[string][]byte, error)
+	Set(ctx context.Context, key, field string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// NoopBackend caches nothing; every read is a miss.
+type NoopBackend struct{}
+
+func (NoopBackend) Get(context.Context, string, string) ([]byte, error) {
+	return nil, nil
+}
+
+func (NoopBackend) GetAll(context.Context, string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+func (NoopBackend) Set(context.Context, string, string, []byte, time.Duration) error {
+	return nil
+}
+
+func (NoopBackend) Delete(context.Context, ...string) error {
+	return nil
+}
+
+type RedisBackend struct {
+	rdb *redis.Client
+}
+
+func NewRedisBackend(rdb *redis.Client) *RedisBackend {
+	return &RedisBackend{rdb: rdb}
+}
+
+func (b *RedisBackend) Get(ctx context.Context, key, field string) ([]byte, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if field == "" {
+		data, err = b.rdb.Get(ctx, key).Bytes()
+	} else {
+		data, err = b.rdb.HGet(ctx, key, field).Bytes()
+	}
+
+	if err == redis.Nil {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+func (b *RedisBackend) GetAll(ctx context.Context, key string) (map[string][]byte, error) {
+	fields, err := b.rdb.HGetAll(ctx, key).Result()
+	if len(fields) == 0 || err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]byte, len(fields))
+	for field, value := range fields {
+		values[field] = []byte(value)
+	}
+
+	return values, nil
+}
+
+func (b *RedisBackend) Set(ctx context.Context, key, field string, value []byte, ttl time.Duration) error {
+	if field != "" {
+		// fields share the key's TTL; fetch still expires each one on its own
+		pipe := b.rdb.TxPipeline()
+		pipe.HSet(ctx, key, field, value)
+		pipe.Expire(ctx, key, ttl)
+		_, err := pipe.Exec(ctx)
+		return err
+	}
+
+	return b.rdb.Set(ctx, key, value, ttl).Err()
+}
+
+func (b *RedisBackend) Delete(ctx context.Context, keys ...string) error {
+	return b.rdb.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,28 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/babaYaga451/social/internal/store"
+)
+
+type CommentsStore struct {
+	loader *loader
+	ttl    time.Duration
+}
+
+func commentsKey(postID int64) string {
+	return fmt.Sprintf("comments-post-%v", postID)
+}
+
+// GetByPostID returns the cached comment list of a post, loading it with
+// load on a miss.
+func (s *CommentsStore) GetByPostID(ctx context.Context, postID int64, load func(context.Context) ([]store.Comment, error)) ([]store.Comment, error) {
+	return fetch(ctx, s.loader, "comments", commentsKey(postID), "", s.ttl, nil, load)
+}
+
+func (s *CommentsStore) DeleteByPostID(ctx context.Context, postID int64) {
+	s.loader.backend.Delete(ctx, commentsKey(postID))
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/babaYaga451/social/internal/store"
+)
+
+// FeedsStore caches first feed pages. All pages of a user live in one hash,
+// keyed by the query, so they can be dropped together. Publishing a post
+// drops the feeds of the followers it is fanned out to; posts of celebrities
+// and edits by followed users show up once the short TTL expires.
+type FeedsStore struct {
+	loader *loader
+	ttl    time.Duration
+}
+
+func feedKey(userID int64) string {
+	return fmt.Sprintf("feed-%v", userID)
+}
+
+func feedField(fq store.PaginatedFeedQuery) string {
+	return fmt.Sprintf("%s:%d:%s:%t", fq.Mode, fq.Limit, fq.Sort, fq.IncludeHidden)
+}
+
+// Cacheable reports whether fq asks for a first page.
+func (s *FeedsStore) Cacheable(fq store.PaginatedFeedQuery) bool {
+	return fq.Offset == 0
+}
+
+// Get returns the cached feed page, loading it with load on a miss.
+func (s *FeedsStore) Get(ctx context.Context, userID int64, fq store.PaginatedFeedQuery, load func(context.Context) ([]store.PostWithMetaData, error)) ([]store.PostWithMetaData, error) {
+	return fetch(ctx, s.loader, "feed", feedKey(userID), feedField(fq), s.ttl, nil, load)
+}
+
+// Delete drops the cached feeds of userIDs.
+func (s *FeedsStore) Delete(ctx context.Context, userIDs ...int64) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	keys := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		keys[i] = feedKey(id)
+	}
+	s.loader.backend.Delete(ctx, keys...)
+}
@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/babaYaga451/social/internal/metrics"
+	"golang.org/x/sync/singleflight"
+)
+
+// earlyRefreshBeta scales how eagerly entries are recomputed before they
+// expire. 1 is the value recommended by the XFetch paper; larger values
+// refresh earlier.
+const earlyRefreshBeta = 1.0
+
+// entry wraps every cached value with what is needed for early refresh.
+type entry struct {
+	Data   json.RawMessage `json:"data"`
+	Delta  time.Duration   `json:"delta"`
+	Expiry time.Time       `json:"expiry"`
+}
+
+// refreshDue implements probabilistic early expiration: the closer the entry
+// is to expiring and the longer it took to compute, the likelier a reader is
+// to recompute it, so a hot key is refreshed by one caller before it expires
+// instead of by all of them after.
+func (e *entry) refreshDue(now time.Time) bool {
+	gap := time.Duration(float64(e.Delta) * earlyRefreshBeta * -math.Log(1-rand.Float64()))
+	return !now.Add(gap).Before(e.Expiry)
+}
+
+// loader does cache-aside reads. Concurrent misses for the same key in this
+// process share a single load.
+type loader struct {
+	backend Backend
+	group   singleflight.Group
+}
+
+func (l *loader) read(ctx context.Context, key, field string) (*entry, error) {
+	data, err := l.backend.Get(ctx, key, field)
+	if data == nil || err != nil {
+		return nil, err
+	}
+
+	e := &entry{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// readLatest returns the entry of the highest version cached under key, as
+// written by versioned fetches.
+func (l *loader) readLatest(ctx context.Context, key string) (*entry, error) {
+	values, err := l.backend.GetAll(ctx, key)
+	if values == nil || err != nil {
+		return nil, err
+	}
+
+	latest, data := -1, []byte(nil)
+	for field, value := range values {
+		version, err := strconv.Atoi(field)
+		if err != nil || version <= latest {
+			continue
+		}
+		latest, data = version, value
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	e := &entry{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (l *loader) write(ctx context.Context, key, field string, e *entry, ttl time.Duration) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return l.backend.Set(ctx, key, field, data, ttl)
+}
+
+// versionField names the field a version of a value is cached under.
+func versionField(version int) string {
+	return strconv.Itoa(version)
+}
+
+// fetch returns the value cached under key (and field, for hash entries),
+// calling load on a miss or when an early refresh is due. If an early
+// refresh fails the cached value is still served.
+//
+// version may be nil. Otherwise each version of the value is cached in its
+// own field, named by version, and the highest one is read, so a slow
+// reader that loaded an old row cannot hide a fresh write.
+func fetch[T any](ctx context.Context, l *loader, name, key, field string, ttl time.Duration, version func(T) int, load func(context.Context) (T, error)) (T, error) {
+	var value T
+
+	var (
+		cached *entry
+		err    error
+	)
+	if version != nil {
+		cached, err = l.readLatest(ctx, key)
+	} else {
+		cached, err = l.read(ctx, key, field)
+	}
+	if err != nil {
+		metrics.ObserveCache(name, false, err)
+		return value, err
+	}
+
+	if cached != nil && !cached.refreshDue(time.Now()) {
+		metrics.ObserveCache(name, true, nil)
+		return value, json.Unmarshal(cached.Data, &value)
+	}
+	metrics.ObserveCache(name, false, nil)
+
+	data, err, _ := l.group.Do(key+"|"+field, func() (any, error) {
+		start := time.Now()
+
+		loaded, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(loaded)
+		if err != nil {
+			return nil, err
+		}
+
+		e := &entry{
+			Data:   data,
+			Delta:  time.Since(start),
+			Expiry: time.Now().Add(ttl),
+		}
+
+		field := field
+		if version != nil {
+			field = versionField(version(loaded))
+		}
+
+		if err := l.write(ctx, key, field, e, ttl); err != nil {
+			return nil, err
+		}
+
+		return data, nil
+	})
+	if err != nil {
+		if cached != nil {
+			return value, json.Unmarshal(cached.Data, &value)
+		}
+		return value, err
+	}
+
+	// every caller decodes its own copy, so handlers can modify the result
+	return value, json.Unmarshal(data.([]byte), &value)
+}
+
+// setVersion writes a version of a value directly, e.g. after an update, so
+// the next read does not have to go to the database.
+func setVersion(ctx context.Context, l *loader, key string, value any, version int, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return l.write(ctx, key, versionField(version), &entry{
+		Data:   data,
+		Expiry: time.Now().Add(ttl),
+	}, ttl)
+}
@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type lruItem struct {
+	value  []byte
+	expiry time.Time
+}
+
+type lruEntry struct {
+	key    string
+	fields map[string]lruItem
+}
+
+// LRUBackend is an in-process cache bounded to maxEntries keys. The least
+// recently used key is evicted first; expired entries are dropped when read.
+type LRUBackend struct {
+	maxEntries int
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+func NewLRUBackend(maxEntries int) *LRUBackend {
+	return &LRUBackend{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+func (b *LRUBackend) Get(_ context.Context, key, field string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	el, ok := b.items[key]
+	if !ok {
+		return nil, nil
+	}
+
+	e := el.Value.(*lruEntry)
+	item, ok := e.fields[field]
+	if !ok {
+		return nil, nil
+	}
+
+	if time.Now().After(item.expiry) {
+		delete(e.fields, field)
+		if len(e.fields) == 0 {
+			b.remove(el)
+		}
+		return nil, nil
+	}
+
+	b.ll.MoveToFront(el)
+	return item.value, nil
+}
+
+func (b *LRUBackend) GetAll(_ context.Context, key string) (map[string][]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	el, ok := b.items[key]
+	if !ok {
+		return nil, nil
+	}
+
+	e := el.Value.(*lruEntry)
+	now := time.Now()
+	values := make(map[string][]byte, len(e.fields))
+	for field, item := range e.fields {
+		if now.After(item.expiry) {
+			delete(e.fields, field)
+			continue
+		}
+		values[field] = item.value
+	}
+
+	if len(values) == 0 {
+		b.remove(el)
+		return nil, nil
+	}
+
+	b.ll.MoveToFront(el)
+	return values, nil
+}
+
+func (b *LRUBackend) Set(_ context.Context, key, field string, value []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	item := lruItem{value: value, expiry: time.Now().Add(ttl)}
+
+	if el, ok := b.items[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.fields[field] = item
+		b.ll.MoveToFront(el)
+		return nil
+	}
+
+	e := &lruEntry{key: key, fields: map[string]lruItem{field: item}}
+	b.items[key] = b.ll.PushFront(e)
+
+	for b.maxEntries > 0 && b.ll.Len() > b.maxEntries {
+		b.remove(b.ll.Back())
+	}
+
+	return nil
+}
+
+func (b *LRUBackend) Delete(_ context.Context, keys ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := b.items[key]; ok {
+			b.remove(el)
+		}
+	}
+
+	return nil
+}
+
+func (b *LRUBackend) remove(el *list.Element) {
+	b.ll.Remove(el)
+	delete(b.items, el.Value.(*lruEntry).key)
+}
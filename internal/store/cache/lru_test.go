@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLRUBackend(t *testing.T) {
+	ctx := context.Background()
+
+	type op struct {
+		do    string // set, get, getall or delete
+		key   string
+		field string
+		value string
+		ttl   time.Duration
+		// want is the value get returns, or "" for a miss
+		want string
+		// wantAll is what getall returns
+		wantAll map[string]string
+	}
+
+	tests := []struct {
+		name       string
+		maxEntries int
+		ops        []op
+	}{
+		{
+			name:       "hit and miss",
+			maxEntries: 10,
+			ops: []op{
+				{do: "set", key: "a", field: "f", value: "1", ttl: time.Minute},
+				{do: "get", key: "a", field: "f", want: "1"},
+				{do: "get", key: "a", field: "g"},
+				{do: "get", key: "b", field: "f"},
+			},
+		},
+		{
+			name:       "fields of a key",
+			maxEntries: 10,
+			ops: []op{
+				{do: "set", key: "a", field: "1", value: "one", ttl: time.Minute},
+				{do: "set", key: "a", field: "2", value: "two", ttl: time.Minute},
+				{do: "getall", key: "a", wantAll: map[string]string{"1": "one", "2": "two"}},
+				{do: "getall", key: "b"},
+			},
+		},
+		{
+			name:       "expired entries are dropped",
+			maxEntries: 10,
+			ops: []op{
+				{do: "set", key: "a", field: "f", value: "1", ttl: -time.Second},
+				{do: "set", key: "a", field: "g", value: "2", ttl: time.Minute},
+				{do: "get", key: "a", field: "f"},
+				{do: "getall", key: "a", wantAll: map[string]string{"g": "2"}},
+				{do: "set", key: "b", field: "f", value: "1", ttl: -time.Second},
+				{do: "getall", key: "b"},
+			},
+		},
+		{
+			name:       "least recently used key is evicted",
+			maxEntries: 2,
+			ops: []op{
+				{do: "set", key: "a", field: "f", value: "1", ttl: time.Minute},
+				{do: "set", key: "b", field: "f", value: "2", ttl: time.Minute},
+				{do: "get", key: "a", field: "f", want: "1"},
+				{do: "set", key: "c", field: "f", value: "3", ttl: time.Minute},
+				{do: "get", key: "b", field: "f"},
+				{do: "get", key: "a", field: "f", want: "1"},
+				{do: "get", key: "c", field: "f", want: "3"},
+			},
+		},
+		{
+			name:       "new fields do not count as entries",
+			maxEntries: 1,
+			ops: []op{
+				{do: "set", key: "a", field: "f", value: "1", ttl: time.Minute},
+				{do: "set", key: "a", field: "g", value: "2", ttl: time.Minute},
+				{do: "get", key: "a", field: "f", want: "1"},
+			},
+		},
+		{
+			name:       "delete drops every field",
+			maxEntries: 10,
+			ops: []op{
+				{do: "set", key: "a", field: "f", value: "1", ttl: time.Minute},
+				{do: "set", key: "a", field: "g", value: "2", ttl: time.Minute},
+				{do: "set", key: "b", field: "f", value: "3", ttl: time.Minute},
+				{do: "delete", key: "a"},
+				{do: "getall", key: "a"},
+				{do: "get", key: "b", field: "f", want: "3"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewLRUBackend(tt.maxEntries)
+
+			for i, o := range tt.ops {
+				switch o.do {
+				case "set":
+					if err := b.Set(ctx, o.key, o.field, []byte(o.value), o.ttl); err != nil {
+						t.Fatal(err)
+					}
+				case "get":
+					got, err := b.Get(ctx, o.key, o.field)
+					if err != nil || string(got) != o.want {
+						t.Fatalf("op %d: Get(%s, %s) = %q, %v, want %q", i, o.key, o.field, got, err, o.want)
+					}
+				case "getall":
+					got, err := b.GetAll(ctx, o.key)
+					if err != nil {
+						t.Fatal(err)
+					}
+					var all map[string]string
+					if got != nil {
+						all = map[string]string{}
+						for f, v := range got {
+							all[f] = string(v)
+						}
+					}
+					if !reflect.DeepEqual(all, o.wantAll) {
+						t.Fatalf("op %d: GetAll(%s) = %v, want %v", i, o.key, all, o.wantAll)
+					}
+				case "delete":
+					if err := b.Delete(ctx, o.key); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
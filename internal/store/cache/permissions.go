@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/babaYaga451/social/internal/metrics"
+)
+
+type PermissionsStore struct {
+	backend Backend
+	ttl     time.Duration
+}
+
+func (s *PermissionsStore) GetByRole(ctx context.Context, roleID int64) ([]string, error) {
+	return s.get(ctx, fmt.Sprintf("permissions-role-%v", roleID))
+}
+
+func (s *PermissionsStore) SetByRole(ctx context.Context, roleID int64, permissions []string) error {
+	return s.set(ctx, fmt.Sprintf("permissions-role-%v", roleID), permissions)
+}
+
+func (s *PermissionsStore) DeleteByRole(ctx context.Context, roleID int64) {
+	s.backend.Delete(ctx, fmt.Sprintf("permissions-role-%v", roleID))
+}
+
+func (s *PermissionsStore) GetByUser(ctx context.Context, userID int64) ([]string, error) {
+	return s.get(ctx, fmt.Sprintf("permissions-user-%v", userID))
+}
+
+func (s *PermissionsStore) SetByUser(ctx context.Context, userID int64, permissions []string) error {
+	return s.set(ctx, fmt.Sprintf("permissions-user-%v", userID), permissions)
+}
+
+func (s *PermissionsStore) DeleteByUser(ctx context.Context, userID int64) {
+	s.backend.Delete(ctx, fmt.Sprintf("permissions-user-%v", userID))
+}
+
+// get returns a nil slice on a cache miss and a non-nil (possibly empty)
+// slice on a hit, so callers can cache "no permissions" as well.
+func (s *PermissionsStore) get(ctx context.Context, cacheKey string) ([]string, error) {
+	data, err := s.backend.Get(ctx, cacheKey, "")
+	metrics.ObserveCache("permissions", data != nil, err)
+	if data == nil || err != nil {
+		return nil, err
+	}
+
+	permissions := []string{}
+	if err := json.Unmarshal(data, &permissions); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func (s *PermissionsStore) set(ctx context.Context, cacheKey string, permissions []string) error {
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	json, err := json.Marshal(permissions)
+	if err != nil {
+		return err
+	}
+
+	return s.backend.Set(ctx, cacheKey, "", json, s.ttl)
+}
@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/babaYaga451/social/internal/store"
+)
+
+type PostsStore struct {
+	loader *loader
+	ttl    time.Duration
+}
+
+// postKey holds every cached version of a post, each in its own field, so
+// deleting it drops them all.
+func postKey(postID int64) string {
+	return fmt.Sprintf("post-versions-%v", postID)
+}
+
+func postVersion(post *store.Post) int {
+	return post.Version
+}
+
+// Get returns the cached post, loading it with load on a miss.
+func (s *PostsStore) Get(ctx context.Context, postID int64, load func(context.Context) (*store.Post, error)) (*store.Post, error) {
+	return fetch(ctx, s.loader, "post", postKey(postID), "", s.ttl, postVersion, load)
+}
+
+// Set caches post as its version; readers keep getting a newer version if
+// one is already cached.
+func (s *PostsStore) Set(ctx context.Context, post *store.Post) error {
+	return setVersion(ctx, s.loader, postKey(post.ID), post, post.Version, s.ttl)
+}
+
+func (s *PostsStore) Delete(ctx context.Context, postID int64) {
+	s.loader.backend.Delete(ctx, postKey(postID))
+}
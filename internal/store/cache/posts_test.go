@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/babaYaga451/social/internal/store"
+)
+
+func TestPostsStoreKeepsLatestVersion(t *testing.T) {
+	ctx := context.Background()
+	posts := NewStorage(NewLRUBackend(10), TTLs{Post: time.Minute}).Posts
+
+	load := func(version int) func(context.Context) (*store.Post, error) {
+		return func(context.Context) (*store.Post, error) {
+			return &store.Post{ID: 1, Version: version}, nil
+		}
+	}
+
+	tests := []struct {
+		name string
+		// set caches this version first, when not 0
+		set  int
+		load func(context.Context) (*store.Post, error)
+		want int
+	}{
+		{name: "miss loads", load: load(1), want: 1},
+		{name: "hit", load: load(5), want: 1},
+		{name: "update cached", set: 3, load: load(5), want: 3},
+		{name: "older write ignored", set: 2, load: load(5), want: 3},
+		{
+			name: "loads nothing on a hit",
+			load: func(context.Context) (*store.Post, error) {
+				return nil, errors.New("loaded")
+			},
+			want: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.set != 0 {
+				if err := posts.Set(ctx, &store.Post{ID: 1, Version: tt.set}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			post, err := posts.Get(ctx, 1, tt.load)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if post.Version != tt.want {
+				t.Fatalf("Get returned version %d, want %d", post.Version, tt.want)
+			}
+		})
+	}
+
+	// deleting drops every version
+	posts.Delete(ctx, 1)
+	post, err := posts.Get(ctx, 1, load(7))
+	if err != nil || post.Version != 7 {
+		t.Fatalf("Get after Delete = %v, %v, want version 7", post, err)
+	}
+}
@@ -1,11 +1,17 @@
 package cache
 
-import "github.com/redis/go-redis/v9"
+import (
+	"github.com/babaYaga451/social/internal/tracing"
+	"github.com/redis/go-redis/v9"
+)
 
 func NewRedisClient(addr, pw string, db int) *redis.Client {
-	return redis.NewClient(&redis.Options{
+	rdb := redis.NewClient(&redis.Options{
 		Addr:     addr,
 		Password: pw,
 		DB:       db,
 	})
+	rdb.AddHook(tracing.RedisHook{})
+
+	return rdb
 }
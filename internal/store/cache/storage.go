@@ -2,9 +2,9 @@ package cache
 
 import (
 	"context"
+	"time"
 
 	"github.com/babaYaga451/social/internal/store"
-	"github.com/redis/go-redis/v9"
 )
 
 type Storage struct {
@@ -13,10 +13,49 @@ type Storage struct {
 		Set(context.Context, *store.User) error
 		Delete(context.Context, int64)
 	}
+	Permissions interface {
+		GetByRole(context.Context, int64) ([]string, error)
+		SetByRole(context.Context, int64, []string) error
+		DeleteByRole(context.Context, int64)
+		GetByUser(context.Context, int64) ([]string, error)
+		SetByUser(context.Context, int64, []string) error
+		DeleteByUser(context.Context, int64)
+	}
+	Posts interface {
+		Get(context.Context, int64, func(context.Context) (*store.Post, error)) (*store.Post, error)
+		Set(context.Context, *store.Post) error
+		Delete(context.Context, int64)
+	}
+	Comments interface {
+		GetByPostID(context.Context, int64, func(context.Context) ([]store.Comment, error)) ([]store.Comment, error)
+		DeleteByPostID(context.Context, int64)
+	}
+	Feeds interface {
+		Cacheable(store.PaginatedFeedQuery) bool
+		Get(context.Context, int64, store.PaginatedFeedQuery, func(context.Context) ([]store.PostWithMetaData, error)) ([]store.PostWithMetaData, error)
+		Delete(context.Context, ...int64)
+	}
+}
+
+// TTLs sets how long each type of entry stays cached.
+type TTLs struct {
+	User        time.Duration
+	Permissions time.Duration
+	Post        time.Duration
+	Comments    time.Duration
+	Feed        time.Duration
 }
 
-func NewRedisStore(rdb *redis.Client) Storage {
+// NewStorage builds the typed caches on top of backend. Use NoopBackend to
+// disable caching.
+func NewStorage(backend Backend, ttls TTLs) Storage {
+	l := &loader{backend: backend}
+
 	return Storage{
-		User: &UsersStore{rdb: rdb},
+		User:        &UsersStore{backend: backend, ttl: ttls.User},
+		Permissions: &PermissionsStore{backend: backend, ttl: ttls.Permissions},
+		Posts:       &PostsStore{loader: l, ttl: ttls.Post},
+		Comments:    &CommentsStore{loader: l, ttl: ttls.Comments},
+		Feeds:       &FeedsStore{loader: l, ttl: ttls.Feed},
 	}
 }
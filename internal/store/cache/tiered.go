@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// invalidationChannel carries keys changed by one instance so the others
+// drop them from their L1.
+const invalidationChannel = "cache-invalidation"
+
+// TieredBackend reads through an in-process LRU (L1) to Redis (L2). Writes
+// go to Redis and evict the key from every instance's L1, so an instance
+// serves a stale L1 entry for at most the time a pub/sub message takes to
+// arrive, or l1TTL if one is lost.
+type TieredBackend struct {
+	l1    *LRUBackend
+	l2    *RedisBackend
+	rdb   *redis.Client
+	l1TTL time.Duration
+	id    string
+}
+
+func NewTieredBackend(l1 *LRUBackend, rdb *redis.Client, l1TTL time.Duration) *TieredBackend {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+
+	return &TieredBackend{
+		l1:    l1,
+		l2:    NewRedisBackend(rdb),
+		rdb:   rdb,
+		l1TTL: l1TTL,
+		id:    hex.EncodeToString(id),
+	}
+}
+
+func (b *TieredBackend) Get(ctx context.Context, key, field string) ([]byte, error) {
+	value, err := b.l1.Get(ctx, key, field)
+	if value != nil || err != nil {
+		return value, err
+	}
+
+	value, err = b.l2.Get(ctx, key, field)
+	if value == nil || err != nil {
+		return value, err
+	}
+
+	return value, b.l1.Set(ctx, key, field, value, b.l1TTL)
+}
+
+func (b *TieredBackend) GetAll(ctx context.Context, key string) (map[string][]byte, error) {
+	values, err := b.l1.GetAll(ctx, key)
+	if values != nil || err != nil {
+		return values, err
+	}
+
+	values, err = b.l2.GetAll(ctx, key)
+	if values == nil || err != nil {
+		return values, err
+	}
+
+	for field, value := range values {
+		if err := b.l1.Set(ctx, key, field, value, b.l1TTL); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+func (b *TieredBackend) Set(ctx context.Context, key, field string, value []byte, ttl time.Duration) error {
+	if err := b.l2.Set(ctx, key, field, value, ttl); err != nil {
+		return err
+	}
+
+	// L1 copies of the key may miss the new field, so the whole key is read
+	// back from L2 on the next Get rather than copied into L1 here
+	return b.invalidate(ctx, key)
+}
+
+func (b *TieredBackend) Delete(ctx context.Context, keys ...string) error {
+	if err := b.l2.Delete(ctx, keys...); err != nil {
+		return err
+	}
+
+	return b.invalidate(ctx, keys...)
+}
+
+func (b *TieredBackend) invalidate(ctx context.Context, keys ...string) error {
+	if err := b.l1.Delete(ctx, keys...); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := b.rdb.Publish(ctx, invalidationChannel, b.id+" "+key).Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Listen applies invalidations published by other instances until ctx is
+// cancelled.
+func (b *TieredBackend) Listen(ctx context.Context) error {
+	sub := b.rdb.Subscribe(ctx, invalidationChannel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+
+			sender, key, found := strings.Cut(msg.Payload, " ")
+			if !found || sender == b.id {
+				continue
+			}
+
+			_ = b.l1.Delete(ctx, key)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// unreachableRedis fails every command, so a test passing with it shows
+// that Redis was not needed.
+func unreachableRedis(t *testing.T) *redis.Client {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:        "127.0.0.1:1",
+		MaxRetries:  -1,
+		DialTimeout: 100 * time.Millisecond,
+	})
+	t.Cleanup(func() { rdb.Close() })
+	return rdb
+}
+
+func TestTieredBackendServesL1(t *testing.T) {
+	ctx := context.Background()
+	l1 := NewLRUBackend(10)
+	b := NewTieredBackend(l1, unreachableRedis(t), time.Minute)
+
+	if err := l1.Set(ctx, "a", "f", []byte("1"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := b.Get(ctx, "a", "f")
+	if err != nil || string(got) != "1" {
+		t.Fatalf("Get = %q, %v, want the L1 value", got, err)
+	}
+
+	all, err := b.GetAll(ctx, "a")
+	if err != nil || string(all["f"]) != "1" {
+		t.Fatalf("GetAll = %q, %v, want the L1 value", all, err)
+	}
+}
+
+func TestTieredBackendL2Failures(t *testing.T) {
+	ctx := context.Background()
+	l1 := NewLRUBackend(10)
+	b := NewTieredBackend(l1, unreachableRedis(t), time.Minute)
+
+	if _, err := b.Get(ctx, "a", "f"); err == nil {
+		t.Fatal("Get on an L1 miss did not go to L2")
+	}
+
+	// a write that did not reach L2 must not be served from L1
+	if err := b.Set(ctx, "a", "f", []byte("1"), time.Minute); err == nil {
+		t.Fatal("Set did not fail without L2")
+	}
+	if got, _ := l1.Get(ctx, "a", "f"); got != nil {
+		t.Fatalf("L1 holds %q after a failed Set", got)
+	}
+
+	if err := b.Delete(ctx, "a"); err == nil {
+		t.Fatal("Delete did not fail without L2")
+	}
+}
@@ -6,50 +6,52 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/babaYaga451/social/internal/metrics"
 	"github.com/babaYaga451/social/internal/store"
-	"github.com/redis/go-redis/v9"
 )
 
 type UsersStore struct {
-	rdb *redis.Client
+	backend Backend
+	ttl     time.Duration
 }
 
-const UserExpTime = time.Minute
+// userEntry carries the fields that are hidden from API responses but are
+// still needed when the user is served from the cache.
+type userEntry struct {
+	*store.User
+	TokenVersion int `json:"token_version"`
+}
 
 func (s *UsersStore) Get(ctx context.Context, userID int64) (*store.User, error) {
 	cacheKey := fmt.Sprintf("user-%v", userID)
-	data, err := s.rdb.Get(ctx, cacheKey).Result()
-	if err == redis.Nil {
-		return nil, nil
-	} else if err != nil {
+	data, err := s.backend.Get(ctx, cacheKey, "")
+	metrics.ObserveCache("user", data != nil, err)
+	if data == nil || err != nil {
 		return nil, err
 	}
 
-	var user store.User
-	if data != "" {
-		err := json.Unmarshal([]byte(data), &user)
-		if err != nil {
-			return nil, err
-		}
+	entry := userEntry{User: &store.User{}}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
 	}
+	entry.User.TokenVersion = entry.TokenVersion
 
-	return &user, nil
-
+	return entry.User, nil
 }
 
 func (s *UsersStore) Set(ctx context.Context, user *store.User) error {
 	cacheKey := fmt.Sprintf("user-%v", user.ID)
 
-	json, err := json.Marshal(user)
+	json, err := json.Marshal(userEntry{User: user, TokenVersion: user.TokenVersion})
 	if err != nil {
 		return err
 	}
 
-	return s.rdb.SetEx(ctx, cacheKey, json, UserExpTime).Err()
+	return s.backend.Set(ctx, cacheKey, "", json, s.ttl)
 }
 
 func (s *UsersStore) Delete(ctx context.Context, userID int64) {
 	cacheKey := fmt.Sprintf("user-%v", userID)
 
-	s.rdb.Del(ctx, cacheKey)
+	s.backend.Delete(ctx, cacheKey)
 }
@@ -3,15 +3,21 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
 )
 
 type Comment struct {
-	ID        int64  `json:"id"`
-	PostID    int64  `json:"post_id"`
-	UserID    int64  `json:"user_id"`
-	Content   string `json:"content"`
-	CreatedAt string `json:"created_at"`
-	User      User   `json:"user"`
+	ID        int64    `json:"id"`
+	PostID    int64    `json:"post_id"`
+	UserID    int64    `json:"user_id"`
+	Content   string   `json:"content"`
+	CreatedAt string   `json:"created_at"`
+	HiddenAt  *string  `json:"hidden_at,omitempty"`
+	Labels    []string `json:"labels,omitempty"`
+	Entities  []Entity `json:"entities"`
+	User      User     `json:"user"`
 }
 
 type CommentStore struct {
@@ -23,7 +29,7 @@ func (s *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]Comment
    SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, u.id, u.username
    FROM comments c
    JOIN users u ON u.id = c.user_id
-   WHERE c.post_id = $1
+   WHERE c.post_id = $1 AND c.hidden_at IS NULL
    ORDER BY c.created_at DESC
   `
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
@@ -44,27 +50,121 @@ func (s *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]Comment
 		}
 		comments = append(comments, c)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(comments))
+	for i, c := range comments {
+		ids[i] = c.ID
+	}
+
+	mentions, err := listMentions(ctx, s.db, mentionsOfComment, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range comments {
+		comments[i].Entities = entitiesOf(mentions, comments[i].ID)
+	}
 
 	return comments, nil
 }
 
+// Create saves comment with its mentions, which only need the usernames as
+// written and are replaced by the stored records.
 func (s *CommentStore) Create(ctx context.Context, comment *Comment) error {
 	query := `
-  INSERT INTO comments (user_id, post_id, content)
-  VALUES ($1, $2, $3) RETURNING id, created_at
+  INSERT INTO comments (user_id, post_id, content, hidden_at, labels)
+  VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at
   `
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		comment.UserID,
-		comment.PostID,
-		comment.Content,
-	).Scan(
+	return WithTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			comment.UserID,
+			comment.PostID,
+			comment.Content,
+			comment.HiddenAt,
+			pq.Array(labels(comment.Labels)),
+		).Scan(
+			&comment.ID,
+			&comment.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		comment.Entities, err = saveMentions(ctx, tx, mentionsOfComment, comment.ID, comment.Entities)
+		return err
+	})
+}
+
+func (s *CommentStore) GetById(ctx context.Context, id int64) (*Comment, error) {
+	query := `
+  SELECT id, post_id, user_id, content, created_at, hidden_at
+  FROM comments
+  WHERE id = $1
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	comment := &Comment{}
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
 		&comment.ID,
+		&comment.PostID,
+		&comment.UserID,
+		&comment.Content,
 		&comment.CreatedAt,
+		&comment.HiddenAt,
 	)
-	return err
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return comment, nil
+}
+
+func (s *CommentStore) Delete(ctx context.Context, id int64) error {
+	query := `
+  DELETE FROM comments WHERE id = $1
+  `
+	return s.exec(ctx, query, id)
+}
+
+func (s *CommentStore) SetHidden(ctx context.Context, id int64, hidden bool) error {
+	query := `
+  UPDATE comments
+  SET hidden_at = CASE WHEN $2::boolean THEN NOW() ELSE NULL END
+  WHERE id = $1
+  `
+	return s.exec(ctx, query, id, hidden)
+}
+
+func (s *CommentStore) exec(ctx context.Context, query string, args ...any) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
 }
@@ -26,6 +26,50 @@ func (s *FollowerStore) Follow(ctx context.Context, followerID, userID int64) er
 	return err
 }
 
+// IsFollowing reports whether followerID follows userID.
+func (s *FollowerStore) IsFollowing(ctx context.Context, followerID, userID int64) (bool, error) {
+	query := `
+  SELECT EXISTS (SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = $2)
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	var following bool
+	err := s.db.QueryRowContext(ctx, query, userID, followerID).Scan(&following)
+	return following, err
+}
+
+// FanOutFollowerIDs lists the followers of userID whose timelines receive
+// the posts of userID, i.e. all of them unless userID has at least
+// celebrityThreshold followers.
+func (s *FollowerStore) FanOutFollowerIDs(ctx context.Context, userID int64, celebrityThreshold int) ([]int64, error) {
+	query := `
+  SELECT f.follower_id
+  FROM followers f
+  JOIN users u ON u.id = f.user_id
+  WHERE f.user_id = $1 AND u.follower_count < $2
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, celebrityThreshold)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
 func (s *FollowerStore) Unfollow(ctx context.Context, followerID, userID int64) error {
 	query := `
   DELETE FROM followers
@@ -0,0 +1,877 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Interceptor runs call, the underlying store method named by method, and may
+// observe or decorate it (timing, errors, tracing). It must return the error
+// returned by call.
+type Interceptor func(ctx context.Context, method string, call func(context.Context) error) error
+
+type instrumented struct {
+	next      Storage
+	intercept Interceptor
+}
+
+// NewInstrumentedStorage wraps every interface in next so that each method
+// call goes through intercept.
+func NewInstrumentedStorage(next Storage, intercept Interceptor) Storage {
+	i := &instrumented{next: next, intercept: intercept}
+
+	return Storage{
+		Posts:       (*instrumentedPosts)(i),
+		Users:       (*instrumentedUsers)(i),
+		Comment:     (*instrumentedComment)(i),
+		Follower:    (*instrumentedFollower)(i),
+		Roles:       (*instrumentedRoles)(i),
+		Permissions: (*instrumentedPermissions)(i),
+		Audit:       (*instrumentedAudit)(i),
+		Reports:     (*instrumentedReports)(i),
+		Timeline:    (*instrumentedTimeline)(i),
+		Tags:        (*instrumentedTags)(i),
+		Media:       (*instrumentedMedia)(i),
+		Links:       (*instrumentedLinks)(i),
+		Reposts:     (*instrumentedReposts)(i),
+		Bookmarks:   (*instrumentedBookmarks)(i),
+		Polls:       (*instrumentedPolls)(i),
+		Blocks:      (*instrumentedBlocks)(i),
+		Messages:    (*instrumentedMessages)(i),
+		Revisions:   (*instrumentedRevisions)(i),
+		Search:      (*instrumentedSearch)(i),
+	}
+}
+
+type instrumentedPosts instrumented
+
+func (s *instrumentedPosts) Create(ctx context.Context, post *Post, celebrityThreshold int) error {
+	return s.intercept(ctx, "Posts.Create", func(ctx context.Context) error {
+		return s.next.Posts.Create(ctx, post, celebrityThreshold)
+	})
+}
+
+func (s *instrumentedPosts) GetById(ctx context.Context, id int64) (*Post, error) {
+	var result *Post
+	err := s.intercept(ctx, "Posts.GetById", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Posts.GetById(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedPosts) Delete(ctx context.Context, id int64) error {
+	return s.intercept(ctx, "Posts.Delete", func(ctx context.Context) error {
+		return s.next.Posts.Delete(ctx, id)
+	})
+}
+
+func (s *instrumentedPosts) Update(ctx context.Context, post *Post, editorID int64, celebrityThreshold int) error {
+	return s.intercept(ctx, "Posts.Update", func(ctx context.Context) error {
+		return s.next.Posts.Update(ctx, post, editorID, celebrityThreshold)
+	})
+}
+
+func (s *instrumentedPosts) SetHidden(ctx context.Context, id int64, b bool) error {
+	return s.intercept(ctx, "Posts.SetHidden", func(ctx context.Context) error {
+		return s.next.Posts.SetHidden(ctx, id, b)
+	})
+}
+
+func (s *instrumentedPosts) GetUnpublished(ctx context.Context, userID int64, lq PaginatedListQuery) ([]Post, error) {
+	var result []Post
+	err := s.intercept(ctx, "Posts.GetUnpublished", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Posts.GetUnpublished(ctx, userID, lq)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedPosts) PublishDue(ctx context.Context, limit int, celebrityThreshold int) ([]Post, error) {
+	var result []Post
+	err := s.intercept(ctx, "Posts.PublishDue", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Posts.PublishDue(ctx, limit, celebrityThreshold)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedPosts) GetEntities(ctx context.Context, postIDs []int64) (map[int64][]Entity, error) {
+	var result map[int64][]Entity
+	err := s.intercept(ctx, "Posts.GetEntities", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Posts.GetEntities(ctx, postIDs)
+		return err
+	})
+	return result, err
+}
+
+type instrumentedUsers instrumented
+
+func (s *instrumentedUsers) GetById(ctx context.Context, id int64) (*User, error) {
+	var result *User
+	err := s.intercept(ctx, "Users.GetById", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Users.GetById(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedUsers) GetByEmail(ctx context.Context, str string) (*User, error) {
+	var result *User
+	err := s.intercept(ctx, "Users.GetByEmail", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Users.GetByEmail(ctx, str)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedUsers) Create(ctx context.Context, tx *sql.Tx, user *User) error {
+	return s.intercept(ctx, "Users.Create", func(ctx context.Context) error {
+		return s.next.Users.Create(ctx, tx, user)
+	})
+}
+
+func (s *instrumentedUsers) CreateAndInvite(ctx context.Context, user *User, token string, exp time.Duration) error {
+	return s.intercept(ctx, "Users.CreateAndInvite", func(ctx context.Context) error {
+		return s.next.Users.CreateAndInvite(ctx, user, token, exp)
+	})
+}
+
+func (s *instrumentedUsers) Activate(ctx context.Context, str string) error {
+	return s.intercept(ctx, "Users.Activate", func(ctx context.Context) error {
+		return s.next.Users.Activate(ctx, str)
+	})
+}
+
+func (s *instrumentedUsers) Delete(ctx context.Context, id int64) error {
+	return s.intercept(ctx, "Users.Delete", func(ctx context.Context) error {
+		return s.next.Users.Delete(ctx, id)
+	})
+}
+
+func (s *instrumentedUsers) SetRole(ctx context.Context, id int64, str string) error {
+	return s.intercept(ctx, "Users.SetRole", func(ctx context.Context) error {
+		return s.next.Users.SetRole(ctx, id, str)
+	})
+}
+
+func (s *instrumentedUsers) List(ctx context.Context, q PaginatedUserQuery) ([]User, error) {
+	var result []User
+	err := s.intercept(ctx, "Users.List", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Users.List(ctx, q)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedUsers) Deactivate(ctx context.Context, id int64) error {
+	return s.intercept(ctx, "Users.Deactivate", func(ctx context.Context) error {
+		return s.next.Users.Deactivate(ctx, id)
+	})
+}
+
+func (s *instrumentedUsers) Ban(ctx context.Context, id int64, str string) error {
+	return s.intercept(ctx, "Users.Ban", func(ctx context.Context) error {
+		return s.next.Users.Ban(ctx, id, str)
+	})
+}
+
+func (s *instrumentedUsers) Unban(ctx context.Context, id int64) error {
+	return s.intercept(ctx, "Users.Unban", func(ctx context.Context) error {
+		return s.next.Users.Unban(ctx, id)
+	})
+}
+
+func (s *instrumentedUsers) Suspend(ctx context.Context, id int64, t time.Time) error {
+	return s.intercept(ctx, "Users.Suspend", func(ctx context.Context) error {
+		return s.next.Users.Suspend(ctx, id, t)
+	})
+}
+
+func (s *instrumentedUsers) RevokeTokens(ctx context.Context, id int64) error {
+	return s.intercept(ctx, "Users.RevokeTokens", func(ctx context.Context) error {
+		return s.next.Users.RevokeTokens(ctx, id)
+	})
+}
+
+func (s *instrumentedUsers) SetPrivate(ctx context.Context, id int64, b bool) error {
+	return s.intercept(ctx, "Users.SetPrivate", func(ctx context.Context) error {
+		return s.next.Users.SetPrivate(ctx, id, b)
+	})
+}
+
+type instrumentedComment instrumented
+
+func (s *instrumentedComment) GetByPostID(ctx context.Context, id int64) ([]Comment, error) {
+	var result []Comment
+	err := s.intercept(ctx, "Comment.GetByPostID", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Comment.GetByPostID(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedComment) GetById(ctx context.Context, id int64) (*Comment, error) {
+	var result *Comment
+	err := s.intercept(ctx, "Comment.GetById", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Comment.GetById(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedComment) Create(ctx context.Context, comment *Comment) error {
+	return s.intercept(ctx, "Comment.Create", func(ctx context.Context) error {
+		return s.next.Comment.Create(ctx, comment)
+	})
+}
+
+func (s *instrumentedComment) Delete(ctx context.Context, id int64) error {
+	return s.intercept(ctx, "Comment.Delete", func(ctx context.Context) error {
+		return s.next.Comment.Delete(ctx, id)
+	})
+}
+
+func (s *instrumentedComment) SetHidden(ctx context.Context, id int64, b bool) error {
+	return s.intercept(ctx, "Comment.SetHidden", func(ctx context.Context) error {
+		return s.next.Comment.SetHidden(ctx, id, b)
+	})
+}
+
+type instrumentedFollower instrumented
+
+func (s *instrumentedFollower) Follow(ctx context.Context, id int64, id2 int64) error {
+	return s.intercept(ctx, "Follower.Follow", func(ctx context.Context) error {
+		return s.next.Follower.Follow(ctx, id, id2)
+	})
+}
+
+func (s *instrumentedFollower) Unfollow(ctx context.Context, id int64, id2 int64) error {
+	return s.intercept(ctx, "Follower.Unfollow", func(ctx context.Context) error {
+		return s.next.Follower.Unfollow(ctx, id, id2)
+	})
+}
+
+func (s *instrumentedFollower) IsFollowing(ctx context.Context, id int64, id2 int64) (bool, error) {
+	var result bool
+	err := s.intercept(ctx, "Follower.IsFollowing", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Follower.IsFollowing(ctx, id, id2)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedFollower) FanOutFollowerIDs(ctx context.Context, id int64, n int) ([]int64, error) {
+	var result []int64
+	err := s.intercept(ctx, "Follower.FanOutFollowerIDs", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Follower.FanOutFollowerIDs(ctx, id, n)
+		return err
+	})
+	return result, err
+}
+
+type instrumentedRoles instrumented
+
+func (s *instrumentedRoles) GetByName(ctx context.Context, str string) (*Role, error) {
+	var result *Role
+	err := s.intercept(ctx, "Roles.GetByName", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Roles.GetByName(ctx, str)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedRoles) List(ctx context.Context) ([]Role, error) {
+	var result []Role
+	err := s.intercept(ctx, "Roles.List", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Roles.List(ctx)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedRoles) Create(ctx context.Context, role *Role) error {
+	return s.intercept(ctx, "Roles.Create", func(ctx context.Context) error {
+		return s.next.Roles.Create(ctx, role)
+	})
+}
+
+type instrumentedPermissions instrumented
+
+func (s *instrumentedPermissions) List(ctx context.Context) ([]Permission, error) {
+	var result []Permission
+	err := s.intercept(ctx, "Permissions.List", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Permissions.List(ctx)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedPermissions) GetByRoleID(ctx context.Context, id int64) ([]string, error) {
+	var result []string
+	err := s.intercept(ctx, "Permissions.GetByRoleID", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Permissions.GetByRoleID(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedPermissions) GetByUserID(ctx context.Context, id int64) ([]string, error) {
+	var result []string
+	err := s.intercept(ctx, "Permissions.GetByUserID", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Permissions.GetByUserID(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedPermissions) SetRolePermissions(ctx context.Context, id int64, strings []string) error {
+	return s.intercept(ctx, "Permissions.SetRolePermissions", func(ctx context.Context) error {
+		return s.next.Permissions.SetRolePermissions(ctx, id, strings)
+	})
+}
+
+func (s *instrumentedPermissions) Grant(ctx context.Context, userID int64, name string, grantedBy int64) error {
+	return s.intercept(ctx, "Permissions.Grant", func(ctx context.Context) error {
+		return s.next.Permissions.Grant(ctx, userID, name, grantedBy)
+	})
+}
+
+func (s *instrumentedPermissions) Revoke(ctx context.Context, userID int64, name string) error {
+	return s.intercept(ctx, "Permissions.Revoke", func(ctx context.Context) error {
+		return s.next.Permissions.Revoke(ctx, userID, name)
+	})
+}
+
+type instrumentedAudit instrumented
+
+func (s *instrumentedAudit) Create(ctx context.Context, auditEvent *AuditEvent) error {
+	return s.intercept(ctx, "Audit.Create", func(ctx context.Context) error {
+		return s.next.Audit.Create(ctx, auditEvent)
+	})
+}
+
+func (s *instrumentedAudit) List(ctx context.Context, q PaginatedAuditQuery) ([]AuditEvent, error) {
+	var result []AuditEvent
+	err := s.intercept(ctx, "Audit.List", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Audit.List(ctx, q)
+		return err
+	})
+	return result, err
+}
+
+type instrumentedReports instrumented
+
+func (s *instrumentedReports) Create(ctx context.Context, report *Report) error {
+	return s.intercept(ctx, "Reports.Create", func(ctx context.Context) error {
+		return s.next.Reports.Create(ctx, report)
+	})
+}
+
+func (s *instrumentedReports) GetById(ctx context.Context, id int64) (*Report, error) {
+	var result *Report
+	err := s.intercept(ctx, "Reports.GetById", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Reports.GetById(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedReports) List(ctx context.Context, q PaginatedReportQuery) ([]Report, error) {
+	var result []Report
+	err := s.intercept(ctx, "Reports.List", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Reports.List(ctx, q)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedReports) Resolve(ctx context.Context, report *Report) error {
+	return s.intercept(ctx, "Reports.Resolve", func(ctx context.Context) error {
+		return s.next.Reports.Resolve(ctx, report)
+	})
+}
+
+func (s *instrumentedReports) ResolveWithSuspension(ctx context.Context, report *Report, t time.Time) error {
+	return s.intercept(ctx, "Reports.ResolveWithSuspension", func(ctx context.Context) error {
+		return s.next.Reports.ResolveWithSuspension(ctx, report, t)
+	})
+}
+
+func (s *instrumentedReports) Warn(ctx context.Context, warning *Warning) error {
+	return s.intercept(ctx, "Reports.Warn", func(ctx context.Context) error {
+		return s.next.Reports.Warn(ctx, warning)
+	})
+}
+
+func (s *instrumentedReports) Warnings(ctx context.Context, id int64, q PaginatedListQuery) ([]Warning, error) {
+	var result []Warning
+	err := s.intercept(ctx, "Reports.Warnings", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Reports.Warnings(ctx, id, q)
+		return err
+	})
+	return result, err
+}
+
+type instrumentedTimeline instrumented
+
+func (s *instrumentedTimeline) Backfill(ctx context.Context, id int64, id2 int64, n int, n2 int) error {
+	return s.intercept(ctx, "Timeline.Backfill", func(ctx context.Context) error {
+		return s.next.Timeline.Backfill(ctx, id, id2, n, n2)
+	})
+}
+
+func (s *instrumentedTimeline) Trim(ctx context.Context, id int64, id2 int64) error {
+	return s.intercept(ctx, "Timeline.Trim", func(ctx context.Context) error {
+		return s.next.Timeline.Trim(ctx, id, id2)
+	})
+}
+
+func (s *instrumentedTimeline) Cap(ctx context.Context, n int) error {
+	return s.intercept(ctx, "Timeline.Cap", func(ctx context.Context) error {
+		return s.next.Timeline.Cap(ctx, n)
+	})
+}
+
+func (s *instrumentedTimeline) Get(ctx context.Context, id int64, q PaginatedFeedQuery, n int) ([]PostWithMetaData, error) {
+	var result []PostWithMetaData
+	err := s.intercept(ctx, "Timeline.Get", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Timeline.Get(ctx, id, q, n)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedTimeline) Signals(ctx context.Context, id int64, d time.Duration) (*EngagementSignals, error) {
+	var result *EngagementSignals
+	err := s.intercept(ctx, "Timeline.Signals", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Timeline.Signals(ctx, id, d)
+		return err
+	})
+	return result, err
+}
+
+type instrumentedTags instrumented
+
+func (s *instrumentedTags) GetPosts(ctx context.Context, viewerID int64, tag string, fq PaginatedFeedQuery) ([]PostWithMetaData, error) {
+	var result []PostWithMetaData
+	err := s.intercept(ctx, "Tags.GetPosts", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Tags.GetPosts(ctx, viewerID, tag, fq)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedTags) Follow(ctx context.Context, id int64, str string) error {
+	return s.intercept(ctx, "Tags.Follow", func(ctx context.Context) error {
+		return s.next.Tags.Follow(ctx, id, str)
+	})
+}
+
+func (s *instrumentedTags) Unfollow(ctx context.Context, id int64, str string) error {
+	return s.intercept(ctx, "Tags.Unfollow", func(ctx context.Context) error {
+		return s.next.Tags.Unfollow(ctx, id, str)
+	})
+}
+
+func (s *instrumentedTags) Followed(ctx context.Context, id int64) ([]string, error) {
+	var result []string
+	err := s.intercept(ctx, "Tags.Followed", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Tags.Followed(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedTags) RefreshTrending(ctx context.Context, window time.Duration, baseline time.Duration, limit int) error {
+	return s.intercept(ctx, "Tags.RefreshTrending", func(ctx context.Context) error {
+		return s.next.Tags.RefreshTrending(ctx, window, baseline, limit)
+	})
+}
+
+func (s *instrumentedTags) Trending(ctx context.Context, n int) ([]TrendingTag, error) {
+	var result []TrendingTag
+	err := s.intercept(ctx, "Tags.Trending", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Tags.Trending(ctx, n)
+		return err
+	})
+	return result, err
+}
+
+type instrumentedMedia instrumented
+
+func (s *instrumentedMedia) Create(ctx context.Context, media *Media) error {
+	return s.intercept(ctx, "Media.Create", func(ctx context.Context) error {
+		return s.next.Media.Create(ctx, media)
+	})
+}
+
+func (s *instrumentedMedia) GetById(ctx context.Context, id int64) (*Media, error) {
+	var result *Media
+	err := s.intercept(ctx, "Media.GetById", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Media.GetById(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedMedia) AppendChunk(ctx context.Context, id int64, offset int64, size int64) error {
+	return s.intercept(ctx, "Media.AppendChunk", func(ctx context.Context) error {
+		return s.next.Media.AppendChunk(ctx, id, offset, size)
+	})
+}
+
+func (s *instrumentedMedia) Chunks(ctx context.Context, id int64) ([]MediaChunk, error) {
+	var result []MediaChunk
+	err := s.intercept(ctx, "Media.Chunks", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Media.Chunks(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedMedia) SetStatus(ctx context.Context, id int64, str string) error {
+	return s.intercept(ctx, "Media.SetStatus", func(ctx context.Context) error {
+		return s.next.Media.SetStatus(ctx, id, str)
+	})
+}
+
+func (s *instrumentedMedia) Complete(ctx context.Context, media *Media) error {
+	return s.intercept(ctx, "Media.Complete", func(ctx context.Context) error {
+		return s.next.Media.Complete(ctx, media)
+	})
+}
+
+func (s *instrumentedMedia) ListOrphaned(ctx context.Context, t time.Time, n int) ([]Media, error) {
+	var result []Media
+	err := s.intercept(ctx, "Media.ListOrphaned", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Media.ListOrphaned(ctx, t, n)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedMedia) Delete(ctx context.Context, id int64) error {
+	return s.intercept(ctx, "Media.Delete", func(ctx context.Context) error {
+		return s.next.Media.Delete(ctx, id)
+	})
+}
+
+type instrumentedLinks instrumented
+
+func (s *instrumentedLinks) SetPostLinks(ctx context.Context, id int64, strings []string) error {
+	return s.intercept(ctx, "Links.SetPostLinks", func(ctx context.Context) error {
+		return s.next.Links.SetPostLinks(ctx, id, strings)
+	})
+}
+
+func (s *instrumentedLinks) GetPreview(ctx context.Context, str string) (*LinkPreview, error) {
+	var result *LinkPreview
+	err := s.intercept(ctx, "Links.GetPreview", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Links.GetPreview(ctx, str)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedLinks) SavePreview(ctx context.Context, linkPreview *LinkPreview) error {
+	return s.intercept(ctx, "Links.SavePreview", func(ctx context.Context) error {
+		return s.next.Links.SavePreview(ctx, linkPreview)
+	})
+}
+
+func (s *instrumentedLinks) GetByPostIDs(ctx context.Context, postIDs []int64) (map[int64][]LinkPreview, error) {
+	var result map[int64][]LinkPreview
+	err := s.intercept(ctx, "Links.GetByPostIDs", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Links.GetByPostIDs(ctx, postIDs)
+		return err
+	})
+	return result, err
+}
+
+type instrumentedReposts instrumented
+
+func (s *instrumentedReposts) Create(ctx context.Context, userID int64, postID int64) error {
+	return s.intercept(ctx, "Reposts.Create", func(ctx context.Context) error {
+		return s.next.Reposts.Create(ctx, userID, postID)
+	})
+}
+
+func (s *instrumentedReposts) Delete(ctx context.Context, userID int64, postID int64) error {
+	return s.intercept(ctx, "Reposts.Delete", func(ctx context.Context) error {
+		return s.next.Reposts.Delete(ctx, userID, postID)
+	})
+}
+
+type instrumentedBookmarks instrumented
+
+func (s *instrumentedBookmarks) Save(ctx context.Context, userID int64, postID int64, collectionID *int64) error {
+	return s.intercept(ctx, "Bookmarks.Save", func(ctx context.Context) error {
+		return s.next.Bookmarks.Save(ctx, userID, postID, collectionID)
+	})
+}
+
+func (s *instrumentedBookmarks) Delete(ctx context.Context, userID int64, postID int64) error {
+	return s.intercept(ctx, "Bookmarks.Delete", func(ctx context.Context) error {
+		return s.next.Bookmarks.Delete(ctx, userID, postID)
+	})
+}
+
+func (s *instrumentedBookmarks) GetPosts(ctx context.Context, userID int64, collectionID *int64, fq PaginatedFeedQuery) ([]PostWithMetaData, error) {
+	var result []PostWithMetaData
+	err := s.intercept(ctx, "Bookmarks.GetPosts", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Bookmarks.GetPosts(ctx, userID, collectionID, fq)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedBookmarks) CreateCollection(ctx context.Context, bookmarkCollection *BookmarkCollection) error {
+	return s.intercept(ctx, "Bookmarks.CreateCollection", func(ctx context.Context) error {
+		return s.next.Bookmarks.CreateCollection(ctx, bookmarkCollection)
+	})
+}
+
+func (s *instrumentedBookmarks) GetCollection(ctx context.Context, id int64) (*BookmarkCollection, error) {
+	var result *BookmarkCollection
+	err := s.intercept(ctx, "Bookmarks.GetCollection", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Bookmarks.GetCollection(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedBookmarks) Collections(ctx context.Context, id int64) ([]BookmarkCollection, error) {
+	var result []BookmarkCollection
+	err := s.intercept(ctx, "Bookmarks.Collections", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Bookmarks.Collections(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedBookmarks) RenameCollection(ctx context.Context, bookmarkCollection *BookmarkCollection) error {
+	return s.intercept(ctx, "Bookmarks.RenameCollection", func(ctx context.Context) error {
+		return s.next.Bookmarks.RenameCollection(ctx, bookmarkCollection)
+	})
+}
+
+func (s *instrumentedBookmarks) DeleteCollection(ctx context.Context, id int64) error {
+	return s.intercept(ctx, "Bookmarks.DeleteCollection", func(ctx context.Context) error {
+		return s.next.Bookmarks.DeleteCollection(ctx, id)
+	})
+}
+
+type instrumentedPolls instrumented
+
+func (s *instrumentedPolls) GetByPostIDs(ctx context.Context, viewerID int64, postIDs []int64) (map[int64]*Poll, error) {
+	var result map[int64]*Poll
+	err := s.intercept(ctx, "Polls.GetByPostIDs", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Polls.GetByPostIDs(ctx, viewerID, postIDs)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedPolls) Vote(ctx context.Context, pollID int64, userID int64, optionIDs []int64) error {
+	return s.intercept(ctx, "Polls.Vote", func(ctx context.Context) error {
+		return s.next.Polls.Vote(ctx, pollID, userID, optionIDs)
+	})
+}
+
+func (s *instrumentedPolls) CloseEnded(ctx context.Context, n int) ([]ClosedPoll, error) {
+	var result []ClosedPoll
+	err := s.intercept(ctx, "Polls.CloseEnded", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Polls.CloseEnded(ctx, n)
+		return err
+	})
+	return result, err
+}
+
+type instrumentedBlocks instrumented
+
+func (s *instrumentedBlocks) Block(ctx context.Context, userID int64, blockedID int64) error {
+	return s.intercept(ctx, "Blocks.Block", func(ctx context.Context) error {
+		return s.next.Blocks.Block(ctx, userID, blockedID)
+	})
+}
+
+func (s *instrumentedBlocks) Unblock(ctx context.Context, userID int64, blockedID int64) error {
+	return s.intercept(ctx, "Blocks.Unblock", func(ctx context.Context) error {
+		return s.next.Blocks.Unblock(ctx, userID, blockedID)
+	})
+}
+
+func (s *instrumentedBlocks) Blocked(ctx context.Context, userID int64, others []int64) (bool, error) {
+	var result bool
+	err := s.intercept(ctx, "Blocks.Blocked", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Blocks.Blocked(ctx, userID, others)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedBlocks) AnyBlocked(ctx context.Context, userIDs []int64) (bool, error) {
+	var result bool
+	err := s.intercept(ctx, "Blocks.AnyBlocked", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Blocks.AnyBlocked(ctx, userIDs)
+		return err
+	})
+	return result, err
+}
+
+type instrumentedMessages instrumented
+
+func (s *instrumentedMessages) CreateConversation(ctx context.Context, c *Conversation, memberIDs []int64) error {
+	return s.intercept(ctx, "Messages.CreateConversation", func(ctx context.Context) error {
+		return s.next.Messages.CreateConversation(ctx, c, memberIDs)
+	})
+}
+
+func (s *instrumentedMessages) GetConversation(ctx context.Context, id int64, userID int64) (*Conversation, error) {
+	var result *Conversation
+	err := s.intercept(ctx, "Messages.GetConversation", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Messages.GetConversation(ctx, id, userID)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedMessages) Conversations(ctx context.Context, userID int64, lq PaginatedListQuery) ([]Conversation, error) {
+	var result []Conversation
+	err := s.intercept(ctx, "Messages.Conversations", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Messages.Conversations(ctx, userID, lq)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedMessages) CreateMessage(ctx context.Context, message *Message) error {
+	return s.intercept(ctx, "Messages.CreateMessage", func(ctx context.Context) error {
+		return s.next.Messages.CreateMessage(ctx, message)
+	})
+}
+
+func (s *instrumentedMessages) GetMessages(ctx context.Context, conversationID int64, mq PaginatedMessageQuery) ([]Message, error) {
+	var result []Message
+	err := s.intercept(ctx, "Messages.GetMessages", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Messages.GetMessages(ctx, conversationID, mq)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedMessages) MarkRead(ctx context.Context, conversationID int64, userID int64, messageID int64) (int64, error) {
+	var result int64
+	err := s.intercept(ctx, "Messages.MarkRead", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Messages.MarkRead(ctx, conversationID, userID, messageID)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedMessages) Unread(ctx context.Context, id int64) (*UnreadCount, error) {
+	var result *UnreadCount
+	err := s.intercept(ctx, "Messages.Unread", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Messages.Unread(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+type instrumentedRevisions instrumented
+
+func (s *instrumentedRevisions) GetByPostID(ctx context.Context, id int64) ([]PostRevision, error) {
+	var result []PostRevision
+	err := s.intercept(ctx, "Revisions.GetByPostID", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Revisions.GetByPostID(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+type instrumentedSearch instrumented
+
+func (s *instrumentedSearch) Users(ctx context.Context, q PaginatedSearchQuery) ([]UserSearchResult, error) {
+	var result []UserSearchResult
+	err := s.intercept(ctx, "Search.Users", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Search.Users(ctx, q)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedSearch) Posts(ctx context.Context, id int64, q PaginatedSearchQuery) ([]PostSearchResult, error) {
+	var result []PostSearchResult
+	err := s.intercept(ctx, "Search.Posts", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Search.Posts(ctx, id, q)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedSearch) Tags(ctx context.Context, q PaginatedSearchQuery) ([]TagSearchResult, error) {
+	var result []TagSearchResult
+	err := s.intercept(ctx, "Search.Tags", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Search.Tags(ctx, q)
+		return err
+	})
+	return result, err
+}
+
+func (s *instrumentedSearch) Autocomplete(ctx context.Context, userID int64, aq AutocompleteQuery) ([]UserSuggestion, error) {
+	var result []UserSuggestion
+	err := s.intercept(ctx, "Search.Autocomplete", func(ctx context.Context) error {
+		var err error
+		result, err = s.next.Search.Autocomplete(ctx, userID, aq)
+		return err
+	})
+	return result, err
+}
@@ -0,0 +1,151 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// LinkStore keeps the URLs found in posts and the previews fetched for
+// them. Previews are shared between posts and refreshed by URL.
+type LinkStore struct {
+	db *sql.DB
+}
+
+type LinkPreview struct {
+	URL         string    `json:"url"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	SiteName    string    `json:"site_name,omitempty"`
+	Failed      bool      `json:"-"`
+	FetchedAt   time.Time `json:"-"`
+}
+
+// SetPostLinks replaces the URLs linked from a post, in order.
+func (s *LinkStore) SetPostLinks(ctx context.Context, postID int64, urls []string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	return WithTx(s.db, ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM post_links WHERE post_id = $1`, postID); err != nil {
+			return err
+		}
+
+		if len(urls) == 0 {
+			return nil
+		}
+
+		_, err := tx.ExecContext(ctx, `
+    INSERT INTO post_links (post_id, url, position)
+    SELECT $1, u.url, u.ord
+    FROM unnest($2::text[]) WITH ORDINALITY AS u(url, ord)
+    ON CONFLICT DO NOTHING
+    `, postID, pq.Array(urls))
+		return err
+	})
+}
+
+// GetPreview returns the cached preview of url, failed attempts included,
+// or ErrorNotFound.
+func (s *LinkStore) GetPreview(ctx context.Context, url string) (*LinkPreview, error) {
+	query := `
+  SELECT url, title, description, image_url, site_name, failed, fetched_at
+  FROM link_previews
+  WHERE url = $1
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	p := &LinkPreview{}
+	err := s.db.QueryRowContext(ctx, query, url).Scan(
+		&p.URL,
+		&p.Title,
+		&p.Description,
+		&p.ImageURL,
+		&p.SiteName,
+		&p.Failed,
+		&p.FetchedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// SavePreview stores the outcome of fetching preview.URL. A failed fetch
+// keeps the metadata of an earlier successful one.
+func (s *LinkStore) SavePreview(ctx context.Context, preview *LinkPreview) error {
+	query := `
+  INSERT INTO link_previews (url, title, description, image_url, site_name, failed, fetched_at)
+  VALUES ($1, $2, $3, $4, $5, $6, NOW())
+  ON CONFLICT (url) DO UPDATE SET
+    title = CASE WHEN EXCLUDED.failed THEN link_previews.title ELSE EXCLUDED.title END,
+    description = CASE WHEN EXCLUDED.failed THEN link_previews.description ELSE EXCLUDED.description END,
+    image_url = CASE WHEN EXCLUDED.failed THEN link_previews.image_url ELSE EXCLUDED.image_url END,
+    site_name = CASE WHEN EXCLUDED.failed THEN link_previews.site_name ELSE EXCLUDED.site_name END,
+    failed = EXCLUDED.failed AND link_previews.title = '' AND link_previews.description = '',
+    fetched_at = EXCLUDED.fetched_at
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(
+		ctx,
+		query,
+		preview.URL,
+		preview.Title,
+		preview.Description,
+		preview.ImageURL,
+		preview.SiteName,
+		preview.Failed,
+	)
+	return err
+}
+
+// GetByPostIDs returns the available previews of each post, in the order
+// the links appear in the post.
+func (s *LinkStore) GetByPostIDs(ctx context.Context, postIDs []int64) (map[int64][]LinkPreview, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	return listPostLinks(ctx, s.db, postIDs)
+}
+
+func listPostLinks(ctx context.Context, q queryer, postIDs []int64) (map[int64][]LinkPreview, error) {
+	query := `
+  SELECT pl.post_id, lp.url, lp.title, lp.description, lp.image_url, lp.site_name
+  FROM post_links pl
+  JOIN link_previews lp ON lp.url = pl.url
+  WHERE pl.post_id = ANY($1) AND NOT lp.failed
+  ORDER BY pl.post_id, pl.position
+  `
+	rows, err := q.QueryContext(ctx, query, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := map[int64][]LinkPreview{}
+	for rows.Next() {
+		var (
+			postID int64
+			p      LinkPreview
+		)
+		if err := rows.Scan(&postID, &p.URL, &p.Title, &p.Description, &p.ImageURL, &p.SiteName); err != nil {
+			return nil, err
+		}
+		links[postID] = append(links[postID], p)
+	}
+
+	return links, rows.Err()
+}
@@ -0,0 +1,321 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// MediaStatusUploading is a resumable upload still receiving chunks.
+	MediaStatusUploading = "uploading"
+	// MediaStatusProcessing is an upload whose blobs are being written.
+	MediaStatusProcessing = "processing"
+	// MediaStatusReady media can be attached to posts and served.
+	MediaStatusReady = "ready"
+)
+
+var (
+	ErrUploadOffsetMismatch = errors.New("upload offset does not match the received bytes")
+	ErrMediaUnavailable     = errors.New("media must be yours, fully uploaded and not attached to another post")
+)
+
+// MediaStore keeps the metadata of uploaded media; the bytes live in a blob
+// store under the keys returned by MediaKey, ThumbnailKey and ChunkKey.
+type MediaStore struct {
+	db *sql.DB
+}
+
+type Media struct {
+	ID           int64  `json:"id"`
+	UserID       int64  `json:"user_id"`
+	PostID       *int64 `json:"post_id,omitempty"`
+	Status       string `json:"status"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	UploadOffset int64  `json:"upload_offset"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// MediaChunk is one received part of a resumable upload.
+type MediaChunk struct {
+	Offset int64
+	Size   int64
+}
+
+func MediaKey(id int64) string {
+	return fmt.Sprintf("media/%d/original", id)
+}
+
+func ThumbnailKey(id int64) string {
+	return fmt.Sprintf("media/%d/thumbnail", id)
+}
+
+func ChunkKey(id, offset int64) string {
+	return fmt.Sprintf("uploads/%d/%020d", id, offset)
+}
+
+func (s *MediaStore) Create(ctx context.Context, media *Media) error {
+	query := `
+  INSERT INTO media (user_id, status, content_type, size, width, height)
+  VALUES ($1, $2, $3, $4, $5, $6)
+  RETURNING id, created_at
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	return s.db.QueryRowContext(
+		ctx,
+		query,
+		media.UserID,
+		media.Status,
+		media.ContentType,
+		media.Size,
+		media.Width,
+		media.Height,
+	).Scan(&media.ID, &media.CreatedAt)
+}
+
+func (s *MediaStore) GetById(ctx context.Context, id int64) (*Media, error) {
+	query := `
+  SELECT id, user_id, post_id, status, content_type, size, width, height, upload_offset, created_at
+  FROM media
+  WHERE id = $1
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	media := &Media{}
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&media.ID,
+		&media.UserID,
+		&media.PostID,
+		&media.Status,
+		&media.ContentType,
+		&media.Size,
+		&media.Width,
+		&media.Height,
+		&media.UploadOffset,
+		&media.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return media, nil
+}
+
+// AppendChunk records size bytes received at offset. It fails with
+// ErrUploadOffsetMismatch unless offset is exactly where the upload stands,
+// so a retried or concurrent chunk is never counted twice.
+func (s *MediaStore) AppendChunk(ctx context.Context, id, offset, size int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	return WithTx(s.db, ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+    UPDATE media SET upload_offset = upload_offset + $3
+    WHERE id = $1 AND upload_offset = $2 AND status = $4 AND upload_offset + $3 <= size
+    `, id, offset, size, MediaStatusUploading)
+		if err != nil {
+			return err
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrUploadOffsetMismatch
+		}
+
+		_, err = tx.ExecContext(ctx, `
+    INSERT INTO media_upload_chunks (media_id, start_offset, size) VALUES ($1, $2, $3)
+    `, id, offset, size)
+		return err
+	})
+}
+
+// Chunks lists the received chunks of an upload in order.
+func (s *MediaStore) Chunks(ctx context.Context, id int64) ([]MediaChunk, error) {
+	query := `
+  SELECT start_offset, size FROM media_upload_chunks
+  WHERE media_id = $1
+  ORDER BY start_offset
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chunks := []MediaChunk{}
+	for rows.Next() {
+		var c MediaChunk
+		if err := rows.Scan(&c.Offset, &c.Size); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c)
+	}
+
+	return chunks, rows.Err()
+}
+
+// SetStatus moves media to status, e.g. a completed upload to processing.
+func (s *MediaStore) SetStatus(ctx context.Context, id int64, status string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE media SET status = $2 WHERE id = $1`, id, status)
+	return err
+}
+
+// Complete stores the processed details of media, marks it ready and
+// forgets its upload chunks.
+func (s *MediaStore) Complete(ctx context.Context, media *Media) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	media.Status = MediaStatusReady
+
+	return WithTx(s.db, ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+    UPDATE media
+    SET status = $2, content_type = $3, size = $4, width = $5, height = $6, upload_offset = $4
+    WHERE id = $1
+    `, media.ID, media.Status, media.ContentType, media.Size, media.Width, media.Height)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM media_upload_chunks WHERE media_id = $1`, media.ID)
+		return err
+	})
+}
+
+// ListOrphaned returns up to limit media that are not attached to any post
+// and have not changed since cutoff: media detached or never attached since
+// then, and uploads that have not received a chunk since then. Uploads still
+// in progress are left alone.
+func (s *MediaStore) ListOrphaned(ctx context.Context, cutoff time.Time, limit int) ([]Media, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	return listMedia(ctx, s.db, `
+  SELECT id, user_id, post_id, status, content_type, size, width, height, upload_offset, created_at
+  FROM media
+  WHERE post_id IS NULL AND updated_at < $1
+  ORDER BY updated_at
+  LIMIT $2
+  `, cutoff, limit)
+}
+
+// Delete deletes media that is not attached to a post. It returns
+// ErrorNotFound if there is no such media, e.g. because it was attached
+// since it was listed, in which case its blobs must be kept.
+func (s *MediaStore) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM media WHERE id = $1 AND post_id IS NULL`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+// attachMedia detaches the current media of postID and attaches mediaIDs
+// in order. Every ID must be ready media of userID that is not attached to
+// another post.
+func attachMedia(ctx context.Context, tx *sql.Tx, postID, userID int64, mediaIDs []int64) error {
+	_, err := tx.ExecContext(ctx, `
+  UPDATE media SET post_id = NULL, position = NULL WHERE post_id = $1
+  `, postID)
+	if err != nil || len(mediaIDs) == 0 {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+  UPDATE media m SET post_id = $1, position = u.ord
+  FROM unnest($3::bigint[]) WITH ORDINALITY AS u(id, ord)
+  WHERE m.id = u.id AND m.user_id = $2 AND m.status = $4 AND m.post_id IS NULL
+  `, postID, userID, pq.Array(mediaIDs), MediaStatusReady)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows != int64(len(mediaIDs)) {
+		return ErrMediaUnavailable
+	}
+
+	return nil
+}
+
+const postMediaQuery = `
+  SELECT id, user_id, post_id, status, content_type, size, width, height, upload_offset, created_at
+  FROM media
+  WHERE post_id = $1
+  ORDER BY position
+  `
+
+type queryer interface {
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+}
+
+func listMedia(ctx context.Context, q queryer, query string, args ...any) ([]Media, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	media := []Media{}
+	for rows.Next() {
+		var m Media
+		err := rows.Scan(
+			&m.ID,
+			&m.UserID,
+			&m.PostID,
+			&m.Status,
+			&m.ContentType,
+			&m.Size,
+			&m.Width,
+			&m.Height,
+			&m.UploadOffset,
+			&m.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		media = append(media, m)
+	}
+
+	return media, rows.Err()
+}
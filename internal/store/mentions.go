@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+const EntityMention = "mention"
+
+// Entity is a structured part of the content of a post or comment, spanning
+// [Start, End) in code points. Mentions point at a user by ID; UserName is
+// their current name, which may differ from the text after a rename.
+type Entity struct {
+	Type     string `json:"type"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	UserID   int64  `json:"user_id,omitempty"`
+	UserName string `json:"username,omitempty"`
+}
+
+// the mentions column holding the ID of what mentions belong to
+const (
+	mentionsOfPost    = "post_id"
+	mentionsOfComment = "comment_id"
+)
+
+// saveMentions replaces the mentions of the post or comment id with
+// entities, which only need UserName as written and offsets. Usernames
+// are matched case-insensitively, preferring an exact match; mentions of
+// unknown users are dropped. It returns the stored entities.
+func saveMentions(ctx context.Context, tx *sql.Tx, column string, id int64, entities []Entity) ([]Entity, error) {
+	_, err := tx.ExecContext(ctx, `DELETE FROM mentions WHERE `+column+` = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entities) == 0 {
+		return []Entity{}, nil
+	}
+
+	var (
+		names  = make([]string, len(entities))
+		starts = make([]int64, len(entities))
+		ends   = make([]int64, len(entities))
+	)
+	for i, e := range entities {
+		names[i] = e.UserName
+		starts[i] = int64(e.Start)
+		ends[i] = int64(e.End)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+  INSERT INTO mentions (`+column+`, user_id, start_offset, end_offset)
+  SELECT $1, u.id, m.start_offset, m.end_offset
+  FROM unnest($2::text[], $3::int[], $4::int[]) AS m (username, start_offset, end_offset)
+  CROSS JOIN LATERAL (
+    SELECT id FROM users
+    WHERE lower(username) = lower(m.username)
+    ORDER BY username = m.username DESC
+    LIMIT 1
+  ) u
+  `, id, pq.Array(names), pq.Array(starts), pq.Array(ends))
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := listMentions(ctx, tx, column, []int64{id})
+	if err != nil {
+		return nil, err
+	}
+
+	return entitiesOf(stored, id), nil
+}
+
+// listMentions returns the mentions of the posts or comments ids, keyed by
+// ID and in order of appearance.
+func listMentions(ctx context.Context, q queryer, column string, ids []int64) (map[int64][]Entity, error) {
+	query := `
+  SELECT m.` + column + `, m.start_offset, m.end_offset, u.id, u.username
+  FROM mentions m
+  JOIN users u ON u.id = m.user_id
+  WHERE m.` + column + ` = ANY($1)
+  ORDER BY m.` + column + `, m.start_offset
+  `
+	rows, err := q.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := map[int64][]Entity{}
+	for rows.Next() {
+		var (
+			id int64
+			e  = Entity{Type: EntityMention}
+		)
+		if err := rows.Scan(&id, &e.Start, &e.End, &e.UserID, &e.UserName); err != nil {
+			return nil, err
+		}
+		mentions[id] = append(mentions[id], e)
+	}
+
+	return mentions, rows.Err()
+}
+
+// entitiesOf returns the entities of id, never nil so they show as an
+// empty array.
+func entitiesOf(entities map[int64][]Entity, id int64) []Entity {
+	if e, ok := entities[id]; ok {
+		return e
+	}
+	return []Entity{}
+}
@@ -0,0 +1,358 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// MessageStore keeps conversations between users, 1:1 or in small groups,
+// their messages and how far each member has read.
+type MessageStore struct {
+	db *sql.DB
+}
+
+// Conversation is a conversation as seen by one of its members: the unread
+// count is theirs.
+type Conversation struct {
+	ID          int64                `json:"id"`
+	Title       string               `json:"title,omitempty"`
+	IsGroup     bool                 `json:"is_group"`
+	CreatedBy   int64                `json:"created_by"`
+	Members     []ConversationMember `json:"members"`
+	LastMessage *Message             `json:"last_message,omitempty"`
+	UnreadCount int                  `json:"unread_count"`
+	CreatedAt   string               `json:"created_at"`
+	UpdatedAt   string               `json:"updated_at"`
+}
+
+// ConversationMember is a member of a conversation with their read
+// receipt: the newest message they have read.
+type ConversationMember struct {
+	UserID            int64   `json:"user_id"`
+	UserName          string  `json:"username"`
+	LastReadMessageID int64   `json:"last_read_message_id"`
+	LastReadAt        *string `json:"last_read_at,omitempty"`
+}
+
+// Message is a message of a conversation. SenderID is 0 once the sender
+// deleted their account.
+type Message struct {
+	ID             int64  `json:"id"`
+	ConversationID int64  `json:"conversation_id"`
+	SenderID       int64  `json:"sender_id"`
+	Content        string `json:"content"`
+	CreatedAt      string `json:"created_at"`
+}
+
+// UnreadCount sums up what a user has not read yet.
+type UnreadCount struct {
+	Conversations int `json:"conversations"`
+	Messages      int `json:"messages"`
+}
+
+// CreateConversation starts a conversation between its creator and
+// memberIDs. A pair of users has a single 1:1 conversation, so creating it
+// again returns the existing one. It fails with ErrorNotFound if a member
+// does not exist.
+func (s *MessageStore) CreateConversation(ctx context.Context, c *Conversation, memberIDs []int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	var directKey *string
+	if !c.IsGroup {
+		if len(memberIDs) != 1 {
+			return fmt.Errorf("1:1 conversations need exactly one other member, got %d", len(memberIDs))
+		}
+		key := fmt.Sprintf("%d:%d", min(c.CreatedBy, memberIDs[0]), max(c.CreatedBy, memberIDs[0]))
+		directKey = &key
+	}
+
+	return WithTx(s.db, ctx, func(tx *sql.Tx) error {
+		// the no-op update makes RETURNING report an existing 1:1
+		// conversation as well
+		err := tx.QueryRowContext(ctx, `
+    INSERT INTO conversations (title, is_group, direct_key, created_by)
+    VALUES (NULLIF($1, ''), $2, $3, $4)
+    ON CONFLICT (direct_key) DO UPDATE SET direct_key = EXCLUDED.direct_key
+    RETURNING id, COALESCE(title, ''), COALESCE(created_by, 0), created_at, updated_at
+    `, c.Title, c.IsGroup, directKey, c.CreatedBy).Scan(&c.ID, &c.Title, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+    INSERT INTO conversation_members (conversation_id, user_id)
+    SELECT $1, unnest($2::bigint[])
+    ON CONFLICT DO NOTHING
+    `, c.ID, pq.Array(append([]int64{c.CreatedBy}, memberIDs...)))
+		if err != nil {
+			switch {
+			case strings.Contains(err.Error(), `"conversation_members_user_id_fkey"`):
+				return ErrorNotFound
+			default:
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetConversation returns conversation id as userID sees it, or
+// ErrorNotFound unless they are a member.
+func (s *MessageStore) GetConversation(ctx context.Context, id, userID int64) (*Conversation, error) {
+	conversations, err := s.conversations(ctx, `AND c.id = $2`, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(conversations) == 0 {
+		return nil, ErrorNotFound
+	}
+
+	return &conversations[0], nil
+}
+
+// Conversations returns a page of the conversations of userID, the most
+// recently active first by default.
+func (s *MessageStore) Conversations(ctx context.Context, userID int64, lq PaginatedListQuery) ([]Conversation, error) {
+	return s.conversations(ctx, `
+  ORDER BY c.updated_at `+lq.Sort+`, c.id `+lq.Sort+`
+  LIMIT $2 OFFSET $3
+  `, userID, lq.Limit, lq.Offset)
+}
+
+// conversations lists the conversations of the user in the first argument,
+// narrowed and ordered by the given SQL, along with their members.
+func (s *MessageStore) conversations(ctx context.Context, filter string, args ...any) ([]Conversation, error) {
+	query := `
+  SELECT
+    c.id,
+    COALESCE(c.title, ''),
+    c.is_group,
+    COALESCE(c.created_by, 0),
+    c.created_at,
+    c.updated_at,
+    (SELECT COUNT(*) FROM messages msg
+      WHERE msg.conversation_id = c.id AND msg.id > m.last_read_message_id
+        AND msg.sender_id IS DISTINCT FROM m.user_id),
+    lm.id,
+    COALESCE(lm.sender_id, 0),
+    lm.content,
+    lm.created_at
+  FROM conversation_members m
+  JOIN conversations c ON c.id = m.conversation_id
+  LEFT JOIN messages lm ON lm.id = c.last_message_id
+  WHERE m.user_id = $1 ` + filter
+	membersQuery := `
+  SELECT m.conversation_id, m.user_id, u.username, m.last_read_message_id, m.last_read_at
+  FROM conversation_members m
+  JOIN users u ON u.id = m.user_id
+  WHERE m.conversation_id = ANY($1)
+  ORDER BY m.conversation_id, m.joined_at, m.user_id
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conversations := []Conversation{}
+	for rows.Next() {
+		var (
+			c         Conversation
+			lastID    sql.NullInt64
+			senderID  int64
+			content   sql.NullString
+			createdAt sql.NullString
+		)
+		err := rows.Scan(
+			&c.ID,
+			&c.Title,
+			&c.IsGroup,
+			&c.CreatedBy,
+			&c.CreatedAt,
+			&c.UpdatedAt,
+			&c.UnreadCount,
+			&lastID,
+			&senderID,
+			&content,
+			&createdAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if lastID.Valid {
+			c.LastMessage = &Message{
+				ID:             lastID.Int64,
+				ConversationID: c.ID,
+				SenderID:       senderID,
+				Content:        content.String,
+				CreatedAt:      createdAt.String,
+			}
+		}
+		c.Members = []ConversationMember{}
+		conversations = append(conversations, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(conversations) == 0 {
+		return conversations, nil
+	}
+
+	byID := make(map[int64]*Conversation, len(conversations))
+	ids := make([]int64, len(conversations))
+	for i := range conversations {
+		byID[conversations[i].ID] = &conversations[i]
+		ids[i] = conversations[i].ID
+	}
+
+	memberRows, err := s.db.QueryContext(ctx, membersQuery, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer memberRows.Close()
+
+	for memberRows.Next() {
+		var (
+			conversationID int64
+			m              ConversationMember
+		)
+		if err := memberRows.Scan(&conversationID, &m.UserID, &m.UserName, &m.LastReadMessageID, &m.LastReadAt); err != nil {
+			return nil, err
+		}
+		if c, ok := byID[conversationID]; ok {
+			c.Members = append(c.Members, m)
+		}
+	}
+
+	return conversations, memberRows.Err()
+}
+
+// CreateMessage adds a message to its conversation, which it marks active
+// and read by the sender up to the new message.
+func (s *MessageStore) CreateMessage(ctx context.Context, m *Message) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	return WithTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+    INSERT INTO messages (conversation_id, sender_id, content)
+    VALUES ($1, $2, $3)
+    RETURNING id, created_at
+    `, m.ConversationID, m.SenderID, m.Content).Scan(&m.ID, &m.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+    UPDATE conversations
+    SET last_message_id = $2, updated_at = NOW()
+    WHERE id = $1
+    `, m.ConversationID, m.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+    UPDATE conversation_members
+    SET last_read_message_id = $3, last_read_at = NOW()
+    WHERE conversation_id = $1 AND user_id = $2
+    `, m.ConversationID, m.SenderID, m.ID)
+		return err
+	})
+}
+
+// GetMessages returns a page of the messages of a conversation: the newest
+// ones before mq.Before (or overall) newest first, or the oldest ones after
+// mq.After oldest first.
+func (s *MessageStore) GetMessages(ctx context.Context, conversationID int64, mq PaginatedMessageQuery) ([]Message, error) {
+	order := "DESC"
+	if mq.After > 0 {
+		order = "ASC"
+	}
+	query := `
+  SELECT id, conversation_id, COALESCE(sender_id, 0), content, created_at
+  FROM messages
+  WHERE conversation_id = $1 AND ($2::bigint = 0 OR id < $2) AND id > $3
+  ORDER BY id ` + order + `
+  LIMIT $4
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, conversationID, mq.Before, mq.After, mq.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []Message{}
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.Content, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+
+	return messages, rows.Err()
+}
+
+// MarkRead moves the read receipt of userID in a conversation up to
+// messageID, capped at the newest message, and returns where it moved to.
+// Receipts never move back; it returns 0 when this one did not move.
+func (s *MessageStore) MarkRead(ctx context.Context, conversationID, userID, messageID int64) (int64, error) {
+	query := `
+  UPDATE conversation_members m
+  SET last_read_message_id = LEAST($3, c.last_message_id), last_read_at = NOW()
+  FROM conversations c
+  WHERE c.id = m.conversation_id AND m.conversation_id = $1 AND m.user_id = $2
+    AND LEAST($3, COALESCE(c.last_message_id, 0)) > m.last_read_message_id
+  RETURNING m.last_read_message_id
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	var lastRead int64
+	err := s.db.QueryRowContext(ctx, query, conversationID, userID, messageID).Scan(&lastRead)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, nil
+		default:
+			return 0, err
+		}
+	}
+
+	return lastRead, nil
+}
+
+// Unread counts the conversations with unread messages of userID and those
+// messages.
+func (s *MessageStore) Unread(ctx context.Context, userID int64) (*UnreadCount, error) {
+	query := `
+  SELECT COUNT(DISTINCT msg.conversation_id), COUNT(*)
+  FROM conversation_members m
+  JOIN messages msg ON msg.conversation_id = m.conversation_id
+  WHERE m.user_id = $1 AND msg.id > m.last_read_message_id
+    AND msg.sender_id IS DISTINCT FROM m.user_id
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	var unread UnreadCount
+	if err := s.db.QueryRowContext(ctx, query, userID).Scan(&unread.Conversations, &unread.Messages); err != nil {
+		return nil, err
+	}
+
+	return &unread, nil
+}
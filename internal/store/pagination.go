@@ -3,12 +3,22 @@ package store
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FeedModeChronological = "chronological"
+	FeedModeRanked        = "ranked"
 )
 
 type PaginatedFeedQuery struct {
-	Limit  int    `json:"limit" validate:"gte=1,lte=20"`
-	Offset int    `json:"offset" validate:"gte=0"`
-	Sort   string `json:"sort" validate:"oneof=asc desc"`
+	Limit         int    `json:"limit" validate:"gte=1,lte=20"`
+	Offset        int    `json:"offset" validate:"gte=0"`
+	Sort          string `json:"sort" validate:"oneof=asc desc"`
+	Mode          string `json:"mode" validate:"oneof=chronological ranked"`
+	Debug         bool   `json:"debug"`
+	IncludeHidden bool   `json:"-"`
 }
 
 func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error) {
@@ -36,5 +46,331 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 	if sort != "" {
 		fq.Sort = sort
 	}
+
+	if mode := queryParam.Get("mode"); mode != "" {
+		fq.Mode = mode
+	}
+
+	if debug := queryParam.Get("debug"); debug != "" {
+		d, err := strconv.ParseBool(debug)
+		if err != nil {
+			return fq, err
+		}
+		fq.Debug = d
+	}
 	return fq, nil
 }
+
+// PaginatedListQuery pages through plain listings that have no feed modes.
+type PaginatedListQuery struct {
+	Limit  int    `json:"limit" validate:"gte=1,lte=100"`
+	Offset int    `json:"offset" validate:"gte=0"`
+	Sort   string `json:"sort" validate:"oneof=asc desc"`
+}
+
+func (lq PaginatedListQuery) Parse(r *http.Request) (PaginatedListQuery, error) {
+	queryParam := r.URL.Query()
+
+	if limit := queryParam.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return lq, err
+		}
+		lq.Limit = l
+	}
+
+	if offset := queryParam.Get("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return lq, err
+		}
+		lq.Offset = o
+	}
+
+	if sort := queryParam.Get("sort"); sort != "" {
+		lq.Sort = sort
+	}
+
+	return lq, nil
+}
+
+const (
+	SearchTypeUsers = "users"
+	SearchTypePosts = "posts"
+	SearchTypeTags  = "tags"
+)
+
+type PaginatedSearchQuery struct {
+	Query         string `json:"q" validate:"required,max=100"`
+	Type          string `json:"type" validate:"oneof=users posts tags"`
+	Limit         int    `json:"limit" validate:"gte=1,lte=50"`
+	Offset        int    `json:"offset" validate:"gte=0"`
+	IncludeHidden bool   `json:"-"`
+}
+
+func (sq PaginatedSearchQuery) Parse(r *http.Request) (PaginatedSearchQuery, error) {
+	queryParam := r.URL.Query()
+
+	sq.Query = strings.TrimSpace(queryParam.Get("q"))
+
+	if searchType := queryParam.Get("type"); searchType != "" {
+		sq.Type = searchType
+	}
+
+	if limit := queryParam.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return sq, err
+		}
+		sq.Limit = l
+	}
+
+	if offset := queryParam.Get("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return sq, err
+		}
+		sq.Offset = o
+	}
+
+	return sq, nil
+}
+
+// AutocompleteQuery looks up usernames starting with Prefix, with or
+// without the @ of a mention being typed.
+type AutocompleteQuery struct {
+	Prefix string `json:"prefix" validate:"required,max=100"`
+	Limit  int    `json:"limit" validate:"gte=1,lte=20"`
+}
+
+func (aq AutocompleteQuery) Parse(r *http.Request) (AutocompleteQuery, error) {
+	queryParam := r.URL.Query()
+
+	aq.Prefix = strings.TrimPrefix(strings.TrimSpace(queryParam.Get("prefix")), "@")
+
+	if limit := queryParam.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return aq, err
+		}
+		aq.Limit = l
+	}
+
+	return aq, nil
+}
+
+type PaginatedUserQuery struct {
+	Limit         int        `json:"limit" validate:"gte=1,lte=100"`
+	Offset        int        `json:"offset" validate:"gte=0"`
+	Sort          string     `json:"sort" validate:"oneof=asc desc"`
+	Search        string     `json:"search" validate:"max=255"`
+	Active        *bool      `json:"active"`
+	Role          string     `json:"role" validate:"max=255"`
+	CreatedAfter  *time.Time `json:"created_after"`
+	CreatedBefore *time.Time `json:"created_before"`
+}
+
+func (uq PaginatedUserQuery) Parse(r *http.Request) (PaginatedUserQuery, error) {
+	queryParam := r.URL.Query()
+
+	if limit := queryParam.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return uq, err
+		}
+		uq.Limit = l
+	}
+
+	if offset := queryParam.Get("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return uq, err
+		}
+		uq.Offset = o
+	}
+
+	if sort := queryParam.Get("sort"); sort != "" {
+		uq.Sort = sort
+	}
+
+	uq.Search = queryParam.Get("search")
+	uq.Role = queryParam.Get("role")
+
+	if active := queryParam.Get("active"); active != "" {
+		a, err := strconv.ParseBool(active)
+		if err != nil {
+			return uq, err
+		}
+		uq.Active = &a
+	}
+
+	if after := queryParam.Get("created_after"); after != "" {
+		t, err := time.Parse(time.RFC3339, after)
+		if err != nil {
+			return uq, err
+		}
+		uq.CreatedAfter = &t
+	}
+
+	if before := queryParam.Get("created_before"); before != "" {
+		t, err := time.Parse(time.RFC3339, before)
+		if err != nil {
+			return uq, err
+		}
+		uq.CreatedBefore = &t
+	}
+
+	return uq, nil
+}
+
+type PaginatedReportQuery struct {
+	Limit      int    `json:"limit" validate:"gte=1,lte=100"`
+	Offset     int    `json:"offset" validate:"gte=0"`
+	Sort       string `json:"sort" validate:"oneof=asc desc"`
+	Status     string `json:"status" validate:"oneof=open resolved"`
+	TargetType string `json:"target_type" validate:"omitempty,oneof=post comment"`
+}
+
+func (rq PaginatedReportQuery) Parse(r *http.Request) (PaginatedReportQuery, error) {
+	queryParam := r.URL.Query()
+
+	if limit := queryParam.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return rq, err
+		}
+		rq.Limit = l
+	}
+
+	if offset := queryParam.Get("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return rq, err
+		}
+		rq.Offset = o
+	}
+
+	if sort := queryParam.Get("sort"); sort != "" {
+		rq.Sort = sort
+	}
+
+	if status := queryParam.Get("status"); status != "" {
+		rq.Status = status
+	}
+
+	rq.TargetType = queryParam.Get("target_type")
+
+	return rq, nil
+}
+
+type PaginatedAuditQuery struct {
+	Limit      int        `json:"limit" validate:"gte=1,lte=5000"`
+	Offset     int        `json:"offset" validate:"gte=0"`
+	Sort       string     `json:"sort" validate:"oneof=asc desc"`
+	ActorID    int64      `json:"actor_id" validate:"gte=0"`
+	Action     string     `json:"action" validate:"max=255"`
+	TargetType string     `json:"target_type" validate:"max=255"`
+	TargetID   int64      `json:"target_id" validate:"gte=0"`
+	From       *time.Time `json:"from"`
+	To         *time.Time `json:"to"`
+}
+
+func (aq PaginatedAuditQuery) Parse(r *http.Request) (PaginatedAuditQuery, error) {
+	queryParam := r.URL.Query()
+
+	if limit := queryParam.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return aq, err
+		}
+		aq.Limit = l
+	}
+
+	if offset := queryParam.Get("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return aq, err
+		}
+		aq.Offset = o
+	}
+
+	if sort := queryParam.Get("sort"); sort != "" {
+		aq.Sort = sort
+	}
+
+	if actorID := queryParam.Get("actor_id"); actorID != "" {
+		id, err := strconv.ParseInt(actorID, 10, 64)
+		if err != nil {
+			return aq, err
+		}
+		aq.ActorID = id
+	}
+
+	if targetID := queryParam.Get("target_id"); targetID != "" {
+		id, err := strconv.ParseInt(targetID, 10, 64)
+		if err != nil {
+			return aq, err
+		}
+		aq.TargetID = id
+	}
+
+	aq.Action = queryParam.Get("action")
+	aq.TargetType = queryParam.Get("target_type")
+
+	if from := queryParam.Get("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return aq, err
+		}
+		aq.From = &t
+	}
+
+	if to := queryParam.Get("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return aq, err
+		}
+		aq.To = &t
+	}
+
+	return aq, nil
+}
+
+// PaginatedMessageQuery pages through a conversation by message ID rather
+// than offset, so messages arriving meanwhile do not shift pages. Before
+// pages back from the newest message; After catches up on newer ones.
+type PaginatedMessageQuery struct {
+	Limit  int   `json:"limit" validate:"gte=1,lte=100"`
+	Before int64 `json:"before" validate:"gte=0"`
+	After  int64 `json:"after" validate:"gte=0,excluded_with=Before"`
+}
+
+func (mq PaginatedMessageQuery) Parse(r *http.Request) (PaginatedMessageQuery, error) {
+	queryParam := r.URL.Query()
+
+	if limit := queryParam.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return mq, err
+		}
+		mq.Limit = l
+	}
+
+	if before := queryParam.Get("before"); before != "" {
+		id, err := strconv.ParseInt(before, 10, 64)
+		if err != nil {
+			return mq, err
+		}
+		mq.Before = id
+	}
+
+	if after := queryParam.Get("after"); after != "" {
+		id, err := strconv.ParseInt(after, 10, 64)
+		if err != nil {
+			return mq, err
+		}
+		mq.After = id
+	}
+
+	return mq, nil
+}
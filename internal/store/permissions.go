@@ -0,0 +1,218 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+const (
+	PermissionPostUpdateAny   = "post.update.any"
+	PermissionPostDeleteAny   = "post.delete.any"
+	PermissionCommentModerate = "comment.moderate"
+	PermissionUserBan         = "user.ban"
+	PermissionUserManage      = "user.manage"
+	PermissionUserImpersonate = "user.impersonate"
+	PermissionRoleManage      = "role.manage"
+	PermissionReportManage    = "report.manage"
+	PermissionAuditRead       = "audit.read"
+	PermissionFeedDebug       = "feed.debug"
+)
+
+var ErrUnknownPermission = errors.New("unknown permission")
+
+type Permission struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type PermissionStore struct {
+	db *sql.DB
+}
+
+func (s *PermissionStore) List(ctx context.Context) ([]Permission, error) {
+	query := `
+  SELECT id, name, COALESCE(description, '')
+  FROM permissions
+  ORDER BY name
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []Permission{}
+	for rows.Next() {
+		var p Permission
+		if err := rows.Scan(&p.ID, &p.Name, &p.Description); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+
+	return permissions, rows.Err()
+}
+
+func (s *PermissionStore) GetByRoleID(ctx context.Context, roleID int64) ([]string, error) {
+	query := `
+  SELECT p.name
+  FROM permissions p
+  JOIN role_permissions rp ON rp.permission_id = p.id
+  WHERE rp.role_id = $1
+  ORDER BY p.name
+  `
+	return s.queryNames(ctx, query, roleID)
+}
+
+func (s *PermissionStore) GetByUserID(ctx context.Context, userID int64) ([]string, error) {
+	query := `
+  SELECT p.name
+  FROM permissions p
+  JOIN user_permissions up ON up.permission_id = p.id
+  WHERE up.user_id = $1
+  ORDER BY p.name
+  `
+	return s.queryNames(ctx, query, userID)
+}
+
+func (s *PermissionStore) SetRolePermissions(ctx context.Context, roleID int64, names []string) error {
+	return WithTx(s.db, ctx, func(tx *sql.Tx) error {
+		return setRolePermissions(ctx, tx, roleID, names)
+	})
+}
+
+// setRolePermissions replaces the permissions of roleID inside tx and
+// fails with ErrUnknownPermission when any of names does not exist.
+func setRolePermissions(ctx context.Context, tx *sql.Tx, roleID int64, names []string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM role_permissions WHERE role_id = $1`, roleID); err != nil {
+		return err
+	}
+
+	query := `
+  INSERT INTO role_permissions (role_id, permission_id)
+  SELECT $1, id FROM permissions WHERE name = ANY($2)
+  `
+	result, err := tx.ExecContext(ctx, query, roleID, pq.Array(names))
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if int(rows) != len(unique(names)) {
+		return ErrUnknownPermission
+	}
+
+	return nil
+}
+
+// Grant gives userID a permission on top of their role. It fails with
+// ErrUnknownPermission for an unknown permission and ErrorNotFound for an
+// unknown user.
+func (s *PermissionStore) Grant(ctx context.Context, userID int64, name string, grantedBy int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	var permissionID int64
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM permissions WHERE name = $1`, name).Scan(&permissionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrUnknownPermission
+		default:
+			return err
+		}
+	}
+
+	query := `
+  INSERT INTO user_permissions (user_id, permission_id, granted_by)
+  VALUES ($1, $2, $3)
+  ON CONFLICT (user_id, permission_id) DO NOTHING
+  `
+	_, err = s.db.ExecContext(ctx, query, userID, permissionID, grantedBy)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), `"user_permissions_user_id_fkey"`):
+			return ErrorNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *PermissionStore) Revoke(ctx context.Context, userID int64, name string) error {
+	query := `
+  DELETE FROM user_permissions up
+  USING permissions p
+  WHERE up.permission_id = p.id AND up.user_id = $1 AND p.name = $2
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, userID, name)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+func (s *PermissionStore) queryNames(ctx context.Context, query string, args ...any) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+func unique(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		out = append(out, v)
+	}
+	return out
+}
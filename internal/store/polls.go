@@ -0,0 +1,285 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrPollEnded         = errors.New("poll has ended")
+	ErrAlreadyVoted      = errors.New("you already voted in this poll")
+	ErrInvalidPollOption = errors.New("options must belong to the poll")
+)
+
+// PollStore keeps the polls attached to posts and their votes. Counts are
+// live while a poll runs; once it ended, CloseEnded stores final tallies.
+type PollStore struct {
+	db *sql.DB
+}
+
+// Poll is the poll of a post as seen by a viewer: vote counts are only
+// filled in once the viewer voted or the poll ended.
+type Poll struct {
+	ID             int64        `json:"id"`
+	PostID         int64        `json:"post_id"`
+	MultipleChoice bool         `json:"multiple_choice"`
+	EndsAt         time.Time    `json:"ends_at"`
+	Ended          bool         `json:"ended"`
+	Options        []PollOption `json:"options"`
+	VoterCount     *int         `json:"voter_count,omitempty"`
+	Voted          bool         `json:"voted"`
+	Choices        []int64      `json:"choices,omitempty"`
+}
+
+type PollOption struct {
+	ID        int64  `json:"id"`
+	Text      string `json:"text"`
+	VoteCount *int   `json:"vote_count,omitempty"`
+}
+
+// ClosedPoll identifies a poll whose final tallies were just stored.
+type ClosedPoll struct {
+	ID       int64
+	PostID   int64
+	AuthorID int64
+}
+
+// GetByPostIDs returns the polls of the given posts, keyed by post ID, as
+// viewerID sees them.
+func (s *PollStore) GetByPostIDs(ctx context.Context, viewerID int64, postIDs []int64) (map[int64]*Poll, error) {
+	pollsQuery := `
+  SELECT p.id, p.post_id, p.multiple_choice, p.ends_at, p.ends_at <= NOW(),
+    CASE WHEN p.closed_at IS NOT NULL THEN p.voter_count
+      ELSE (SELECT COUNT(*) FROM poll_ballots b WHERE b.poll_id = p.id) END
+  FROM polls p
+  WHERE p.post_id = ANY($1)
+  `
+	optionsQuery := `
+  SELECT o.poll_id, o.id, o.text,
+    CASE WHEN p.closed_at IS NOT NULL THEN o.vote_count
+      ELSE (SELECT COUNT(*) FROM poll_choices c WHERE c.option_id = o.id) END
+  FROM poll_options o
+  JOIN polls p ON p.id = o.poll_id
+  WHERE p.post_id = ANY($1)
+  ORDER BY o.poll_id, o.position
+  `
+	choicesQuery := `
+  SELECT c.poll_id, c.option_id
+  FROM poll_choices c
+  JOIN polls p ON p.id = c.poll_id
+  WHERE p.post_id = ANY($1) AND c.user_id = $2
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	polls := map[int64]*Poll{}
+	if len(postIDs) == 0 {
+		return polls, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, pollsQuery, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := map[int64]*Poll{}
+	voters := map[int64]int{}
+	for rows.Next() {
+		var (
+			p     Poll
+			count int
+		)
+		if err := rows.Scan(&p.ID, &p.PostID, &p.MultipleChoice, &p.EndsAt, &p.Ended, &count); err != nil {
+			return nil, err
+		}
+		p.Options = []PollOption{}
+		polls[p.PostID] = &p
+		byID[p.ID] = &p
+		voters[p.ID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(byID) == 0 {
+		return polls, nil
+	}
+
+	optionRows, err := s.db.QueryContext(ctx, optionsQuery, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer optionRows.Close()
+
+	for optionRows.Next() {
+		var (
+			pollID int64
+			o      PollOption
+			count  int
+		)
+		if err := optionRows.Scan(&pollID, &o.ID, &o.Text, &count); err != nil {
+			return nil, err
+		}
+		o.VoteCount = &count
+		if p, ok := byID[pollID]; ok {
+			p.Options = append(p.Options, o)
+		}
+	}
+	if err := optionRows.Err(); err != nil {
+		return nil, err
+	}
+
+	choiceRows, err := s.db.QueryContext(ctx, choicesQuery, pq.Array(postIDs), viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer choiceRows.Close()
+
+	for choiceRows.Next() {
+		var pollID, optionID int64
+		if err := choiceRows.Scan(&pollID, &optionID); err != nil {
+			return nil, err
+		}
+		if p, ok := byID[pollID]; ok {
+			p.Voted = true
+			p.Choices = append(p.Choices, optionID)
+		}
+	}
+	if err := choiceRows.Err(); err != nil {
+		return nil, err
+	}
+
+	for id, p := range byID {
+		if !p.Voted && !p.Ended {
+			for i := range p.Options {
+				p.Options[i].VoteCount = nil
+			}
+			continue
+		}
+		count := voters[id]
+		p.VoterCount = &count
+	}
+
+	return polls, nil
+}
+
+// Vote casts the ballot of userID. The database allows one ballot per user
+// and poll and only options of that poll; voting after the poll ended
+// fails with ErrPollEnded.
+func (s *PollStore) Vote(ctx context.Context, pollID, userID int64, optionIDs []int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	return WithTx(s.db, ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+    INSERT INTO poll_ballots (poll_id, user_id)
+    SELECT id, $2 FROM polls WHERE id = $1 AND ends_at > NOW()
+    `, pollID, userID)
+		if err != nil {
+			switch {
+			case strings.Contains(err.Error(), `"poll_ballots_pkey"`):
+				return ErrAlreadyVoted
+			default:
+				return err
+			}
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrPollEnded
+		}
+
+		_, err = tx.ExecContext(ctx, `
+    INSERT INTO poll_choices (poll_id, user_id, option_id)
+    SELECT $1, $2, unnest($3::bigint[])
+    `, pollID, userID, pq.Array(optionIDs))
+		if err != nil {
+			switch {
+			case strings.Contains(err.Error(), `"poll_choices_option_fkey"`):
+				return ErrInvalidPollOption
+			default:
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// CloseEnded stores the final tallies of up to limit ended polls and marks
+// them closed. Polls are claimed with SKIP LOCKED, so concurrent jobs on
+// several instances never close the same poll twice.
+func (s *PollStore) CloseEnded(ctx context.Context, limit int) ([]ClosedPoll, error) {
+	query := `
+  WITH due AS (
+    SELECT id FROM polls
+    WHERE closed_at IS NULL AND ends_at <= NOW()
+    ORDER BY ends_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+  ), tallies AS (
+    UPDATE poll_options o
+    SET vote_count = (SELECT COUNT(*) FROM poll_choices c WHERE c.option_id = o.id)
+    FROM due
+    WHERE o.poll_id = due.id
+  )
+  UPDATE polls p
+  SET closed_at = NOW(), voter_count = (SELECT COUNT(*) FROM poll_ballots b WHERE b.poll_id = p.id)
+  FROM due, posts
+  WHERE p.id = due.id AND posts.id = p.post_id
+  RETURNING p.id, p.post_id, posts.user_id
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	closed := []ClosedPoll{}
+	for rows.Next() {
+		var c ClosedPoll
+		if err := rows.Scan(&c.ID, &c.PostID, &c.AuthorID); err != nil {
+			return nil, err
+		}
+		closed = append(closed, c)
+	}
+
+	return closed, rows.Err()
+}
+
+// createPoll adds poll with the given option texts to postID.
+func createPoll(ctx context.Context, tx *sql.Tx, postID int64, poll *Poll) error {
+	err := tx.QueryRowContext(ctx, `
+  INSERT INTO polls (post_id, multiple_choice, ends_at)
+  VALUES ($1, $2, $3)
+  RETURNING id
+  `, postID, poll.MultipleChoice, poll.EndsAt).Scan(&poll.ID)
+	if err != nil {
+		return err
+	}
+	poll.PostID = postID
+
+	for i := range poll.Options {
+		err := tx.QueryRowContext(ctx, `
+    INSERT INTO poll_options (poll_id, position, text)
+    VALUES ($1, $2, $3)
+    RETURNING id
+    `, poll.ID, i, poll.Options[i].Text).Scan(&poll.Options[i].ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -4,60 +4,145 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/lib/pq"
 )
 
+const (
+	// PostStatusDraft posts are only visible to their author.
+	PostStatusDraft = "draft"
+	// PostStatusScheduled posts are published by the scheduler at PublishAt.
+	PostStatusScheduled = "scheduled"
+	// PostStatusPublished posts are public and show up in feeds.
+	PostStatusPublished = "published"
+)
+
 type PostStore struct {
 	db *sql.DB
 }
 
+// Post is a post of a user. Until it is published, CreatedAt is when it was
+// written; publishing moves it to the time it went public so that feeds
+// show it as new. Edited is only set by changes made after publishing.
 type Post struct {
-	ID        int64     `json:"id"`
-	Content   string    `json:"content"`
-	Title     string    `json:"title"`
-	UserID    int64     `json:"user_id"`
-	Tags      []string  `json:"tags"`
-	CreatedAt string    `json:"created_at"`
-	UpdatedAt string    `json:"updated_at"`
-	Version   int       `json:"version"`
-	Comments  []Comment `json:"comments"`
-	User      User      `json:"user"`
+	ID           int64         `json:"id"`
+	Content      string        `json:"content"`
+	Title        string        `json:"title"`
+	UserID       int64         `json:"user_id"`
+	Tags         []string      `json:"tags"`
+	Status       string        `json:"status"`
+	PublishAt    *time.Time    `json:"publish_at,omitempty"`
+	CreatedAt    string        `json:"created_at"`
+	UpdatedAt    string        `json:"updated_at"`
+	Version      int           `json:"version"`
+	Edited       bool          `json:"edited"`
+	LastEditedBy *int64        `json:"last_edited_by,omitempty"`
+	HiddenAt     *string       `json:"hidden_at,omitempty"`
+	Labels       []string      `json:"labels,omitempty"`
+	Comments     []Comment     `json:"comments"`
+	Media        []Media       `json:"media"`
+	Links        []LinkPreview `json:"links,omitempty"`
+	QuotedPostID *int64        `json:"quoted_post_id,omitempty"`
+	Quote        *QuotedPost   `json:"quote,omitempty"`
+	RepostCount  int           `json:"repost_count"`
+	Poll         *Poll         `json:"poll,omitempty"`
+	Entities     []Entity      `json:"entities"`
+	User         User          `json:"user"`
+}
+
+// QuotedPost is the original embedded in a quote post. When the original
+// was deleted or the viewer may not see it, only ID is set.
+type QuotedPost struct {
+	ID        int64  `json:"id"`
+	Available bool   `json:"available"`
+	UserID    int64  `json:"user_id,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Content   string `json:"content,omitempty"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// Published reports whether post is public.
+func (p *Post) Published() bool {
+	return p.Status == PostStatusPublished
 }
 
 type PostWithMetaData struct {
 	Post
-	CommentCount int `json:"comment_count"`
+	CommentCount int     `json:"comment_count"`
+	RepostedBy   *Repost `json:"reposted_by,omitempty"`
 }
 
-func (s *PostStore) Create(ctx context.Context, post *Post) error {
+// Create saves post and, if it is published, fans it out to the timelines
+// of its author's followers unless they are a celebrity.
+func (s *PostStore) Create(ctx context.Context, post *Post, celebrityThreshold int) error {
 	query := `
-  INSERT INTO posts (content, title, user_id, tags)
-  VALUES ($1, $2, $3, $4) RETURNING id,
+  INSERT INTO posts (content, title, user_id, tags, hidden_at, labels, status, publish_at, quoted_post_id)
+  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id,
   created_at,
   updated_at
 `
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
 	defer cancel()
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		post.Content,
-		post.Title,
-		post.UserID,
-		pq.Array(post.Tags),
-	).Scan(
-		&post.ID,
-		&post.CreatedAt,
-		&post.UpdatedAt,
-	)
 
-	if err != nil {
-		return err
-	}
+	// post.Media only needs IDs and post.Entities the mentions as written;
+	// they are saved in the same transaction and replaced by the stored
+	// records
+	return WithTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			post.Content,
+			post.Title,
+			post.UserID,
+			pq.Array(post.Tags),
+			post.HiddenAt,
+			pq.Array(labels(post.Labels)),
+			post.Status,
+			post.PublishAt,
+			post.QuotedPostID,
+		).Scan(
+			&post.ID,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		mediaIDs := make([]int64, len(post.Media))
+		for i, m := range post.Media {
+			mediaIDs[i] = m.ID
+		}
+
+		if err := attachMedia(ctx, tx, post.ID, post.UserID, mediaIDs); err != nil {
+			return err
+		}
+
+		if err := saveRevision(ctx, tx, post.ID, post.UserID); err != nil {
+			return err
+		}
+
+		if post.Poll != nil {
+			if err := createPoll(ctx, tx, post.ID, post.Poll); err != nil {
+				return err
+			}
+		}
 
+		post.Entities, err = saveMentions(ctx, tx, mentionsOfPost, post.ID, post.Entities)
+		if err != nil {
+			return err
+		}
+
+		if post.Published() {
+			if err := fanOut(ctx, tx, post, celebrityThreshold); err != nil {
+				return err
+			}
+		}
+
+		post.Media, err = listMedia(ctx, tx, postMediaQuery, post.ID)
+		return err
+	})
 }
 
 func (s *PostStore) GetById(ctx context.Context, id int64) (*Post, error) {
@@ -67,9 +152,16 @@ func (s *PostStore) GetById(ctx context.Context, id int64) (*Post, error) {
        title,
        user_id,
        tags,
+       status,
+       publish_at,
        created_at,
        updated_at,
-       VERSION
+       VERSION,
+       last_edited_by,
+       hidden_at,
+       labels,
+       quoted_post_id,
+       (SELECT COUNT(*) FROM reposts r WHERE r.post_id = posts.id)
   FROM posts
   WHERE id = $1
 `
@@ -84,9 +176,16 @@ func (s *PostStore) GetById(ctx context.Context, id int64) (*Post, error) {
 		&post.Title,
 		&post.UserID,
 		pq.Array(&post.Tags),
+		&post.Status,
+		&post.PublishAt,
 		&post.CreatedAt,
 		&post.UpdatedAt,
 		&post.Version,
+		&post.LastEditedBy,
+		&post.HiddenAt,
+		pq.Array(&post.Labels),
+		&post.QuotedPostID,
+		&post.RepostCount,
 	)
 
 	if err != nil {
@@ -98,6 +197,25 @@ func (s *PostStore) GetById(ctx context.Context, id int64) (*Post, error) {
 		}
 	}
 
+	post.Edited = post.LastEditedBy != nil
+
+	post.Media, err = listMedia(ctx, s.db, postMediaQuery, id)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := listPostLinks(ctx, s.db, []int64{id})
+	if err != nil {
+		return nil, err
+	}
+	post.Links = links[id]
+
+	mentions, err := listMentions(ctx, s.db, mentionsOfPost, []int64{id})
+	if err != nil {
+		return nil, err
+	}
+	post.Entities = entitiesOf(mentions, id)
+
 	return &post, nil
 }
 
@@ -125,81 +243,238 @@ func (s *PostStore) Delete(ctx context.Context, postID int64) error {
 	return nil
 }
 
-func (s *PostStore) Update(ctx context.Context, post *Post) error {
+// Update saves post as edited by editorID, along with its media and
+// mentions as Create does, and records the new version as a revision. A
+// draft or scheduled post updated to published goes public right away, with
+// CreatedAt moved to now, and is fanned out like a new post; only edits
+// after that mark the post as edited.
+func (s *PostStore) Update(ctx context.Context, post *Post, editorID int64, celebrityThreshold int) error {
 	query := `
   UPDATE posts
-  SET title = $1 , content = $2, version = version + 1
+  SET title = $1 , content = $2, labels = $5, tags = $6, status = $7, publish_at = $8,
+    created_at = CASE WHEN status <> 'published' AND $7 = 'published' THEN NOW() ELSE created_at END,
+    last_edited_by = CASE WHEN status = 'published' THEN $9 ELSE last_edited_by END,
+    version = version + 1
+  FROM (SELECT status AS old_status FROM posts WHERE id = $3) old
   WHERE id = $3 AND version = $4
-  RETURNING version
+  RETURNING version, created_at, last_edited_by, old.old_status
   `
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		post.Title,
-		post.Content,
-		post.ID,
-		post.Version,
-	).Scan(&post.Version)
-	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return ErrorNotFound
-		default:
+	return WithTx(s.db, ctx, func(tx *sql.Tx) error {
+		var oldStatus string
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			post.Title,
+			post.Content,
+			post.ID,
+			post.Version,
+			pq.Array(labels(post.Labels)),
+			pq.Array(labels(post.Tags)),
+			post.Status,
+			post.PublishAt,
+			editorID,
+		).Scan(&post.Version, &post.CreatedAt, &post.LastEditedBy, &oldStatus)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrorNotFound
+			default:
+				return err
+			}
+		}
+		post.Edited = post.LastEditedBy != nil
+
+		mediaIDs := make([]int64, len(post.Media))
+		for i, m := range post.Media {
+			mediaIDs[i] = m.ID
+		}
+
+		if err := attachMedia(ctx, tx, post.ID, post.UserID, mediaIDs); err != nil {
 			return err
 		}
-	}
-	return nil
-}
 
-func (s *PostStore) GetUserFeed(ctx context.Context, userId int64, fq PaginatedFeedQuery) ([]PostWithMetaData, error) {
+		post.Media, err = listMedia(ctx, tx, postMediaQuery, post.ID)
+		if err != nil {
+			return err
+		}
 
+		if post.Published() && oldStatus != PostStatusPublished {
+			if err := fanOut(ctx, tx, post, celebrityThreshold); err != nil {
+				return err
+			}
+		}
+
+		post.Entities, err = saveMentions(ctx, tx, mentionsOfPost, post.ID, post.Entities)
+		if err != nil {
+			return err
+		}
+
+		return saveRevision(ctx, tx, post.ID, editorID)
+	})
+}
+
+// GetUnpublished returns a page of the drafts and scheduled posts of userID,
+// most recently created first unless lq.Sort says otherwise.
+func (s *PostStore) GetUnpublished(ctx context.Context, userID int64, lq PaginatedListQuery) ([]Post, error) {
 	query := `
-  SELECT 
-    p.id as post_id,
-    p.user_id,
-    u.username as author_username,
-    p.title,
-    p.created_at,
-    p.version,
-    p.tags,
-    COUNT(distinct c.id) as comments_count
-  FROM posts p
-  LEFT JOIN comments c on c.post_id = p.id
-  JOIN users u on u.id = p.user_id
-  JOIN followers f on f.follower_id = p.user_id or p.user_id = $1
-  WHERE f.user_id = $1 or p.user_id = $1
-  GROUP BY p.id, u.username
-  ORDER BY p.created_at ` + fq.Sort +
-		` LIMIT $2 OFFSET $3`
-
-	rows, err := s.db.QueryContext(ctx, query, userId, fq.Limit, fq.Offset)
+  SELECT id, content, title, user_id, tags, status, publish_at, created_at, updated_at, version, hidden_at, labels
+  FROM posts
+  WHERE user_id = $1 AND status <> 'published'
+  ORDER BY created_at ` + lq.Sort + `, id ` + lq.Sort + `
+  LIMIT $2 OFFSET $3
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, lq.Limit, lq.Offset)
 	if err != nil {
 		return nil, err
 	}
-
 	defer rows.Close()
-	var feed []PostWithMetaData
+
+	posts := []Post{}
 	for rows.Next() {
-		var p PostWithMetaData
+		var p Post
 		err := rows.Scan(
 			&p.ID,
-			&p.UserID,
-			&p.User.UserName,
+			&p.Content,
 			&p.Title,
+			&p.UserID,
+			pq.Array(&p.Tags),
+			&p.Status,
+			&p.PublishAt,
 			&p.CreatedAt,
+			&p.UpdatedAt,
 			&p.Version,
-			pq.Array(&p.Tags),
-			&p.CommentCount,
+			&p.HiddenAt,
+			pq.Array(&p.Labels),
 		)
 		if err != nil {
 			return nil, err
 		}
-		feed = append(feed, p)
+		posts = append(posts, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+
+	mentions, err := listMentions(ctx, s.db, mentionsOfPost, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range posts {
+		posts[i].Entities = entitiesOf(mentions, posts[i].ID)
+	}
+
+	return posts, nil
+}
+
+// GetEntities returns the entities of each post, in order of appearance.
+// Posts without any are left out.
+func (s *PostStore) GetEntities(ctx context.Context, postIDs []int64) (map[int64][]Entity, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	return listMentions(ctx, s.db, mentionsOfPost, postIDs)
+}
+
+// PublishDue publishes up to limit scheduled posts whose time has come and
+// fans them out like freshly created posts, all in one transaction. Due
+// rows are claimed with SKIP LOCKED, so concurrent schedulers on several
+// instances never publish the same post twice.
+func (s *PostStore) PublishDue(ctx context.Context, limit, celebrityThreshold int) ([]Post, error) {
+	query := `
+  UPDATE posts p
+  SET status = 'published', created_at = NOW(), version = version + 1
+  FROM (
+    SELECT id FROM posts
+    WHERE status = 'scheduled' AND publish_at <= NOW()
+    ORDER BY publish_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+  ) due
+  WHERE p.id = due.id
+  RETURNING p.id, p.user_id, p.title, p.status, p.publish_at, p.created_at, p.version
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	var published []Post
+	err := WithTx(s.db, ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var p Post
+			err := rows.Scan(&p.ID, &p.UserID, &p.Title, &p.Status, &p.PublishAt, &p.CreatedAt, &p.Version)
+			if err != nil {
+				return err
+			}
+			published = append(published, p)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, p := range published {
+			if err := saveRevision(ctx, tx, p.ID, p.UserID); err != nil {
+				return err
+			}
+			if err := fanOut(ctx, tx, &p, celebrityThreshold); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return feed, nil
+	return published, nil
+}
+
+func (s *PostStore) SetHidden(ctx context.Context, postID int64, hidden bool) error {
+	query := `
+  UPDATE posts
+  SET hidden_at = CASE WHEN $2::boolean THEN NOW() ELSE NULL END
+  WHERE id = $1
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, postID, hidden)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
+// labels keeps the NOT NULL labels columns from receiving a NULL array.
+func labels(l []string) []string {
+	if l == nil {
+		return []string{}
+	}
+	return l
 }
@@ -0,0 +1,308 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	ReportTargetPost    = "post"
+	ReportTargetComment = "comment"
+
+	ReportStatusOpen     = "open"
+	ReportStatusResolved = "resolved"
+
+	ReportActionDismiss = "dismiss"
+	ReportActionHide    = "hide"
+	ReportActionDelete  = "delete"
+	ReportActionWarn    = "warn"
+	ReportActionSuspend = "suspend"
+
+	// ReportReasonFilter marks reports filed by the content filter rather
+	// than by a user; such reports have no reporter, and a target has at
+	// most one open report of this kind.
+	ReportReasonFilter = "filter"
+)
+
+var ErrDuplicateReport = errors.New("content already reported")
+
+type Report struct {
+	ID             int64   `json:"id"`
+	ReporterID     int64   `json:"reporter_id"`
+	TargetType     string  `json:"target_type"`
+	TargetID       int64   `json:"target_id"`
+	TargetUserID   int64   `json:"target_user_id"`
+	Reason         string  `json:"reason"`
+	Details        string  `json:"details"`
+	Status         string  `json:"status"`
+	Resolution     *string `json:"resolution,omitempty"`
+	ResolutionNote string  `json:"resolution_note,omitempty"`
+	ResolvedBy     *int64  `json:"resolved_by,omitempty"`
+	ResolvedAt     *string `json:"resolved_at,omitempty"`
+	CreatedAt      string  `json:"created_at"`
+}
+
+// Warning is a warning a moderator gave a user when resolving a report
+// against their content.
+type Warning struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"user_id"`
+	ReportID  int64  `json:"report_id,omitempty"`
+	Reason    string `json:"reason"`
+	Note      string `json:"note,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+type ReportStore struct {
+	db *sql.DB
+}
+
+func (s *ReportStore) Create(ctx context.Context, report *Report) error {
+	query := `
+  INSERT INTO reports (reporter_id, target_type, target_id, target_user_id, reason, details)
+  VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6)
+  RETURNING id, status, created_at
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		report.ReporterID,
+		report.TargetType,
+		report.TargetID,
+		report.TargetUserID,
+		report.Reason,
+		report.Details,
+	).Scan(
+		&report.ID,
+		&report.Status,
+		&report.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), `"idx_reports_open_unique"`),
+			strings.Contains(err.Error(), `"idx_reports_open_filter_unique"`):
+			return ErrDuplicateReport
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *ReportStore) GetById(ctx context.Context, id int64) (*Report, error) {
+	query := `
+  SELECT id, COALESCE(reporter_id, 0), target_type, target_id, COALESCE(target_user_id, 0),
+    reason, details, status, resolution, resolution_note, resolved_by, resolved_at, created_at
+  FROM reports
+  WHERE id = $1
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	report := &Report{}
+	err := s.db.QueryRowContext(ctx, query, id).Scan(report.fields()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+func (s *ReportStore) List(ctx context.Context, rq PaginatedReportQuery) ([]Report, error) {
+	query := `
+  SELECT id, COALESCE(reporter_id, 0), target_type, target_id, COALESCE(target_user_id, 0),
+    reason, details, status, resolution, resolution_note, resolved_by, resolved_at, created_at
+  FROM reports
+  WHERE status = $1 AND ($2 = '' OR target_type = $2)
+  ORDER BY created_at ` + rq.Sort + `, id
+  LIMIT $3 OFFSET $4
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, rq.Status, rq.TargetType, rq.Limit, rq.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []Report{}
+	for rows.Next() {
+		var report Report
+		if err := rows.Scan(report.fields()...); err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, rows.Err()
+}
+
+// Resolve closes every open report against the same target as report, so a
+// single moderator decision clears duplicate reports from the queue.
+func (s *ReportStore) Resolve(ctx context.Context, report *Report) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	return WithTx(s.db, ctx, func(tx *sql.Tx) error {
+		return resolveReports(ctx, tx, report)
+	})
+}
+
+// ResolveWithSuspension suspends the reported user until the given time and
+// resolves the report in the same transaction, so a failed resolution does
+// not leave a suspension behind an open report.
+func (s *ReportStore) ResolveWithSuspension(ctx context.Context, report *Report, until time.Time) error {
+	query := `
+  UPDATE users
+  SET suspended_until = $2, token_version = token_version + 1
+  WHERE id = $1
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	return WithTx(s.db, ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, report.TargetUserID, until)
+		if err != nil {
+			return err
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrorNotFound
+		}
+
+		return resolveReports(ctx, tx, report)
+	})
+}
+
+// resolveReports closes every open report on the target of report.
+func resolveReports(ctx context.Context, tx *sql.Tx, report *Report) error {
+	query := `
+  UPDATE reports
+  SET status = $1, resolution = $2, resolution_note = $3, resolved_by = $4, resolved_at = NOW()
+  WHERE target_type = $5 AND target_id = $6 AND status = $7
+  `
+	result, err := tx.ExecContext(
+		ctx,
+		query,
+		ReportStatusResolved,
+		report.Resolution,
+		report.ResolutionNote,
+		report.ResolvedBy,
+		report.TargetType,
+		report.TargetID,
+		ReportStatusOpen,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrorNotFound
+	}
+
+	report.Status = ReportStatusResolved
+	return nil
+}
+
+func (s *ReportStore) Warn(ctx context.Context, warning *Warning) error {
+	query := `
+  INSERT INTO user_warnings (user_id, report_id, reason, note)
+  VALUES ($1, NULLIF($2, 0), $3, $4)
+  RETURNING id, created_at
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		warning.UserID,
+		warning.ReportID,
+		warning.Reason,
+		warning.Note,
+	).Scan(
+		&warning.ID,
+		&warning.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), `"user_warnings_user_id_fkey"`):
+			return ErrorNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Warnings returns a page of the warnings given to userID.
+func (s *ReportStore) Warnings(ctx context.Context, userID int64, lq PaginatedListQuery) ([]Warning, error) {
+	query := `
+  SELECT id, user_id, COALESCE(report_id, 0), reason, note, created_at
+  FROM user_warnings
+  WHERE user_id = $1
+  ORDER BY created_at ` + lq.Sort + `, id ` + lq.Sort + `
+  LIMIT $2 OFFSET $3
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, lq.Limit, lq.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	warnings := []Warning{}
+	for rows.Next() {
+		var w Warning
+		if err := rows.Scan(&w.ID, &w.UserID, &w.ReportID, &w.Reason, &w.Note, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, w)
+	}
+
+	return warnings, rows.Err()
+}
+
+func (r *Report) fields() []any {
+	return []any{
+		&r.ID,
+		&r.ReporterID,
+		&r.TargetType,
+		&r.TargetID,
+		&r.TargetUserID,
+		&r.Reason,
+		&r.Details,
+		&r.Status,
+		&r.Resolution,
+		&r.ResolutionNote,
+		&r.ResolvedBy,
+		&r.ResolvedAt,
+		&r.CreatedAt,
+	}
+}
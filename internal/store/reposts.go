@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+// RepostStore keeps pure shares of posts. Reposts by followed users are
+// merged into home timelines when they are read.
+type RepostStore struct {
+	db *sql.DB
+}
+
+// Repost attributes a feed entry to the user who shared it.
+type Repost struct {
+	UserID    int64  `json:"user_id"`
+	UserName  string `json:"username"`
+	CreatedAt string `json:"created_at"`
+}
+
+func (s *RepostStore) Create(ctx context.Context, userID, postID int64) error {
+	query := `
+  INSERT INTO reposts (user_id, post_id)
+  VALUES ($1, $2)
+  ON CONFLICT DO NOTHING
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, postID)
+	return err
+}
+
+func (s *RepostStore) Delete(ctx context.Context, userID, postID int64) error {
+	query := `
+  DELETE FROM reposts
+  WHERE user_id = $1 AND post_id = $2
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, postID)
+	return err
+}
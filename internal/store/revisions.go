@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// RevisionStore keeps every version of every post: as created, after each
+// edit and as published by the scheduler.
+type RevisionStore struct {
+	db *sql.DB
+}
+
+type PostRevision struct {
+	ID        int64    `json:"id"`
+	PostID    int64    `json:"post_id"`
+	Version   int      `json:"version"`
+	Title     string   `json:"title"`
+	Content   string   `json:"content"`
+	Tags      []string `json:"tags"`
+	EditorID  *int64   `json:"editor_id"`
+	CreatedAt string   `json:"created_at"`
+}
+
+// GetByPostID returns the revisions of a post, newest first.
+func (s *RevisionStore) GetByPostID(ctx context.Context, postID int64) ([]PostRevision, error) {
+	query := `
+  SELECT id, post_id, version, title, content, tags, editor_id, created_at
+  FROM post_revisions
+  WHERE post_id = $1
+  ORDER BY version DESC
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []PostRevision{}
+	for rows.Next() {
+		var r PostRevision
+		err := rows.Scan(
+			&r.ID,
+			&r.PostID,
+			&r.Version,
+			&r.Title,
+			&r.Content,
+			pq.Array(&r.Tags),
+			&r.EditorID,
+			&r.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+
+	return revisions, rows.Err()
+}
+
+// saveRevision records the current version of postID as written by
+// editorID.
+func saveRevision(ctx context.Context, e execer, postID, editorID int64) error {
+	_, err := e.ExecContext(ctx, `
+  INSERT INTO post_revisions (post_id, version, title, content, tags, editor_id)
+  SELECT id, version, title, content, COALESCE(tags, '{}'), $2 FROM posts WHERE id = $1
+  ON CONFLICT (post_id, version) DO NOTHING
+  `, postID, editorID)
+	return err
+}
@@ -3,13 +3,19 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
 )
 
+var ErrDuplicateRole = errors.New("role already exists")
+
 type Role struct {
-	ID          int64  `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Level       int    `json:"level"`
+	ID          int64    `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Level       int      `json:"level"`
+	Permissions []string `json:"permissions,omitempty"`
 }
 
 type RoleStore struct {
@@ -18,10 +24,12 @@ type RoleStore struct {
 
 func (s *RoleStore) GetByName(ctx context.Context, name string) (*Role, error) {
 	query := `
-  SELECT id, name, description, level 
+  SELECT id, name, description, level
   FROM roles
   WHERE name = $1
   `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
 
 	role := &Role{}
 	err := s.db.QueryRowContext(ctx, query, name).Scan(
@@ -31,8 +39,81 @@ func (s *RoleStore) GetByName(ctx context.Context, name string) (*Role, error) {
 		&role.Level,
 	)
 	if err != nil {
-		return nil, err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
 	}
 
 	return role, nil
 }
+
+func (s *RoleStore) List(ctx context.Context) ([]Role, error) {
+	query := `
+  SELECT r.id, r.name, r.description, r.level,
+    COALESCE(array_agg(p.name ORDER BY p.name) FILTER (WHERE p.name IS NOT NULL), '{}')
+  FROM roles r
+  LEFT JOIN role_permissions rp ON rp.role_id = r.id
+  LEFT JOIN permissions p ON p.id = rp.permission_id
+  GROUP BY r.id
+  ORDER BY r.level, r.name
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []Role{}
+	for rows.Next() {
+		var role Role
+		err := rows.Scan(
+			&role.ID,
+			&role.Name,
+			&role.Description,
+			&role.Level,
+			pq.Array(&role.Permissions),
+		)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// Create stores role together with role.Permissions, so an unknown
+// permission leaves no role behind.
+func (s *RoleStore) Create(ctx context.Context, role *Role) error {
+	query := `
+  INSERT INTO roles (name, description, level)
+  VALUES ($1, $2, $3)
+  RETURNING id
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	return WithTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, query, role.Name, role.Description, role.Level).Scan(&role.ID)
+		if err != nil {
+			switch {
+			case err.Error() == `pq: duplicate key value violates unique constraint "roles_name_key"`:
+				return ErrDuplicateRole
+			default:
+				return err
+			}
+		}
+
+		if len(role.Permissions) == 0 {
+			return nil
+		}
+
+		return setRolePermissions(ctx, tx, role.ID, role.Permissions)
+	})
+}
@@ -0,0 +1,218 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// SearchStore looks up users by username, posts by their full-text
+// search_vector and tags by prefix. Inactive, banned and suspended users
+// never show up, and neither does anything of theirs.
+type SearchStore struct {
+	db *sql.DB
+}
+
+type UserSearchResult struct {
+	ID        int64   `json:"id"`
+	UserName  string  `json:"username"`
+	CreatedAt string  `json:"created_at"`
+	Rank      float64 `json:"rank"`
+}
+
+// UserSuggestion is a user offered while typing a mention.
+type UserSuggestion struct {
+	ID       int64  `json:"id"`
+	UserName string `json:"username"`
+	Followed bool   `json:"followed"`
+}
+
+type PostSearchResult struct {
+	PostWithMetaData
+	Rank float64 `json:"rank"`
+}
+
+type TagSearchResult struct {
+	Tag       string `json:"tag"`
+	PostCount int    `json:"post_count"`
+}
+
+// Users matches usernames starting with sq.Query first, then usernames
+// similar to it. Private accounts are left out.
+func (s *SearchStore) Users(ctx context.Context, sq PaginatedSearchQuery) ([]UserSearchResult, error) {
+	query := `
+  SELECT u.id, u.username, u.created_at, similarity(u.username, $1) AS rank
+  FROM users u
+  WHERE u.is_active AND u.banned_at IS NULL AND NOT u.is_private
+    AND (u.suspended_until IS NULL OR u.suspended_until < NOW())
+    AND (u.username ILIKE $2 ESCAPE '\' OR u.username % $1)
+  ORDER BY u.username ILIKE $2 ESCAPE '\' DESC, rank DESC, u.username
+  LIMIT $3 OFFSET $4
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, sq.Query, likePrefix(sq.Query), sq.Limit, sq.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []UserSearchResult{}
+	for rows.Next() {
+		var u UserSearchResult
+		if err := rows.Scan(&u.ID, &u.UserName, &u.CreatedAt, &u.Rank); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+// Posts runs sq.Query as a web-style search (quotes, "or", "-") against post
+// titles and content. Posts of private accounts are only found by the
+// author and their followers.
+func (s *SearchStore) Posts(ctx context.Context, viewerID int64, sq PaginatedSearchQuery) ([]PostSearchResult, error) {
+	query := `
+  SELECT
+    p.id,
+    p.user_id,
+    u.username,
+    p.title,
+    p.created_at,
+    p.version,
+    p.tags,
+    p.quoted_post_id,
+    (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.hidden_at IS NULL),
+    (SELECT COUNT(*) FROM reposts r WHERE r.post_id = p.id),
+    ts_rank(p.search_vector, q) AS rank
+  FROM posts p
+  JOIN users u ON u.id = p.user_id,
+    websearch_to_tsquery('english', $1) q
+  WHERE p.search_vector @@ q AND p.status = 'published'
+    AND (p.hidden_at IS NULL OR $5::boolean)
+    AND u.is_active AND u.banned_at IS NULL
+    AND (u.suspended_until IS NULL OR u.suspended_until < NOW())
+    AND (NOT u.is_private OR u.id = $2 OR EXISTS (
+      SELECT 1 FROM followers f WHERE f.user_id = u.id AND f.follower_id = $2
+    ))
+  ORDER BY rank DESC, p.created_at DESC, p.id DESC
+  LIMIT $3 OFFSET $4
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, sq.Query, viewerID, sq.Limit, sq.Offset, sq.IncludeHidden)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []PostSearchResult{}
+	for rows.Next() {
+		var p PostSearchResult
+		err := rows.Scan(
+			&p.ID,
+			&p.UserID,
+			&p.User.UserName,
+			&p.Title,
+			&p.CreatedAt,
+			&p.Version,
+			pq.Array(&p.Tags),
+			&p.QuotedPostID,
+			&p.CommentCount,
+			&p.RepostCount,
+			&p.Rank,
+		)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+
+	return posts, rows.Err()
+}
+
+// Tags lists the tags starting with sq.Query, which must already be
+// normalized, most used first. Counts are the same for everyone, so only the
+// posts of public, active users count.
+func (s *SearchStore) Tags(ctx context.Context, sq PaginatedSearchQuery) ([]TagSearchResult, error) {
+	query := `
+  SELECT tag, COUNT(*) AS post_count
+  FROM posts p
+  JOIN users u ON u.id = p.user_id,
+    unnest(p.tags) AS tag
+  WHERE tag LIKE $1 ESCAPE '\' AND p.status = 'published' AND p.hidden_at IS NULL
+    AND u.is_active AND u.banned_at IS NULL AND NOT u.is_private
+    AND (u.suspended_until IS NULL OR u.suspended_until < NOW())
+  GROUP BY tag
+  ORDER BY post_count DESC, tag
+  LIMIT $2 OFFSET $3
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, likePrefix(sq.Query), sq.Limit, sq.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []TagSearchResult{}
+	for rows.Next() {
+		var t TagSearchResult
+		if err := rows.Scan(&t.Tag, &t.PostCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+
+	return tags, rows.Err()
+}
+
+// Autocomplete suggests users whose username starts with aq.Prefix to
+// userID: the accounts they follow first, then the most followed ones.
+// Private accounts are only suggested to their followers, and userID never
+// is.
+func (s *SearchStore) Autocomplete(ctx context.Context, userID int64, aq AutocompleteQuery) ([]UserSuggestion, error) {
+	query := `
+  SELECT u.id, u.username, f.user_id IS NOT NULL AS followed
+  FROM users u
+  LEFT JOIN followers f ON f.user_id = u.id AND f.follower_id = $1
+  WHERE u.is_active AND u.banned_at IS NULL AND u.id <> $1
+    AND (u.suspended_until IS NULL OR u.suspended_until < NOW())
+    AND (NOT u.is_private OR f.user_id IS NOT NULL)
+    AND u.username ILIKE $2 ESCAPE '\'
+  ORDER BY followed DESC, u.follower_count DESC, length(u.username), u.username
+  LIMIT $3
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, likePrefix(aq.Prefix), aq.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []UserSuggestion{}
+	for rows.Next() {
+		var u UserSuggestion
+		if err := rows.Scan(&u.ID, &u.UserName, &u.Followed); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likePrefix builds a LIKE pattern matching values that start with s.
+func likePrefix(s string) string {
+	return likeEscaper.Replace(s) + "%"
+}
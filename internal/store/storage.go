@@ -14,11 +14,14 @@ var (
 
 type Storage struct {
 	Posts interface {
-		Create(context.Context, *Post) error
+		Create(ctx context.Context, post *Post, celebrityThreshold int) error
 		GetById(context.Context, int64) (*Post, error)
 		Delete(context.Context, int64) error
-		Update(context.Context, *Post) error
-		GetUserFeed(context.Context, int64, PaginatedFeedQuery) ([]PostWithMetaData, error)
+		Update(ctx context.Context, post *Post, editorID int64, celebrityThreshold int) error
+		SetHidden(context.Context, int64, bool) error
+		GetUnpublished(ctx context.Context, userID int64, lq PaginatedListQuery) ([]Post, error)
+		PublishDue(ctx context.Context, limit, celebrityThreshold int) ([]Post, error)
+		GetEntities(ctx context.Context, postIDs []int64) (map[int64][]Entity, error)
 	}
 	Users interface {
 		GetById(context.Context, int64) (*User, error)
@@ -27,27 +30,151 @@ type Storage struct {
 		CreateAndInvite(ctx context.Context, user *User, token string, exp time.Duration) error
 		Activate(context.Context, string) error
 		Delete(context.Context, int64) error
+		SetRole(context.Context, int64, string) error
+		List(context.Context, PaginatedUserQuery) ([]User, error)
+		Deactivate(context.Context, int64) error
+		Ban(context.Context, int64, string) error
+		Unban(context.Context, int64) error
+		Suspend(context.Context, int64, time.Time) error
+		RevokeTokens(context.Context, int64) error
+		SetPrivate(context.Context, int64, bool) error
 	}
 	Comment interface {
 		GetByPostID(context.Context, int64) ([]Comment, error)
+		GetById(context.Context, int64) (*Comment, error)
 		Create(context.Context, *Comment) error
+		Delete(context.Context, int64) error
+		SetHidden(context.Context, int64, bool) error
 	}
 	Follower interface {
 		Follow(context.Context, int64, int64) error
 		Unfollow(context.Context, int64, int64) error
+		IsFollowing(context.Context, int64, int64) (bool, error)
+		FanOutFollowerIDs(context.Context, int64, int) ([]int64, error)
 	}
 	Roles interface {
 		GetByName(context.Context, string) (*Role, error)
+		List(context.Context) ([]Role, error)
+		Create(context.Context, *Role) error
+	}
+	Permissions interface {
+		List(context.Context) ([]Permission, error)
+		GetByRoleID(context.Context, int64) ([]string, error)
+		GetByUserID(context.Context, int64) ([]string, error)
+		SetRolePermissions(context.Context, int64, []string) error
+		Grant(ctx context.Context, userID int64, name string, grantedBy int64) error
+		Revoke(ctx context.Context, userID int64, name string) error
+	}
+	Audit interface {
+		Create(context.Context, *AuditEvent) error
+		List(context.Context, PaginatedAuditQuery) ([]AuditEvent, error)
+	}
+	Reports interface {
+		Create(context.Context, *Report) error
+		GetById(context.Context, int64) (*Report, error)
+		List(context.Context, PaginatedReportQuery) ([]Report, error)
+		Resolve(context.Context, *Report) error
+		ResolveWithSuspension(context.Context, *Report, time.Time) error
+		Warn(context.Context, *Warning) error
+		Warnings(context.Context, int64, PaginatedListQuery) ([]Warning, error)
+	}
+	Timeline interface {
+		Backfill(context.Context, int64, int64, int, int) error
+		Trim(context.Context, int64, int64) error
+		Cap(context.Context, int) error
+		Get(context.Context, int64, PaginatedFeedQuery, int) ([]PostWithMetaData, error)
+		Signals(context.Context, int64, time.Duration) (*EngagementSignals, error)
+	}
+	Tags interface {
+		GetPosts(ctx context.Context, viewerID int64, tag string, fq PaginatedFeedQuery) ([]PostWithMetaData, error)
+		Follow(context.Context, int64, string) error
+		Unfollow(context.Context, int64, string) error
+		Followed(context.Context, int64) ([]string, error)
+		RefreshTrending(ctx context.Context, window, baseline time.Duration, limit int) error
+		Trending(context.Context, int) ([]TrendingTag, error)
+	}
+	Media interface {
+		Create(context.Context, *Media) error
+		GetById(context.Context, int64) (*Media, error)
+		AppendChunk(ctx context.Context, id, offset, size int64) error
+		Chunks(context.Context, int64) ([]MediaChunk, error)
+		SetStatus(context.Context, int64, string) error
+		Complete(context.Context, *Media) error
+		ListOrphaned(context.Context, time.Time, int) ([]Media, error)
+		Delete(context.Context, int64) error
+	}
+	Links interface {
+		SetPostLinks(context.Context, int64, []string) error
+		GetPreview(context.Context, string) (*LinkPreview, error)
+		SavePreview(context.Context, *LinkPreview) error
+		GetByPostIDs(ctx context.Context, postIDs []int64) (map[int64][]LinkPreview, error)
+	}
+	Reposts interface {
+		Create(ctx context.Context, userID, postID int64) error
+		Delete(ctx context.Context, userID, postID int64) error
+	}
+	Bookmarks interface {
+		Save(ctx context.Context, userID, postID int64, collectionID *int64) error
+		Delete(ctx context.Context, userID, postID int64) error
+		GetPosts(ctx context.Context, userID int64, collectionID *int64, fq PaginatedFeedQuery) ([]PostWithMetaData, error)
+		CreateCollection(context.Context, *BookmarkCollection) error
+		GetCollection(context.Context, int64) (*BookmarkCollection, error)
+		Collections(context.Context, int64) ([]BookmarkCollection, error)
+		RenameCollection(context.Context, *BookmarkCollection) error
+		DeleteCollection(context.Context, int64) error
+	}
+	Polls interface {
+		GetByPostIDs(ctx context.Context, viewerID int64, postIDs []int64) (map[int64]*Poll, error)
+		Vote(ctx context.Context, pollID, userID int64, optionIDs []int64) error
+		CloseEnded(context.Context, int) ([]ClosedPoll, error)
+	}
+	Blocks interface {
+		Block(ctx context.Context, userID, blockedID int64) error
+		Unblock(ctx context.Context, userID, blockedID int64) error
+		Blocked(ctx context.Context, userID int64, others []int64) (bool, error)
+		AnyBlocked(ctx context.Context, userIDs []int64) (bool, error)
+	}
+	Messages interface {
+		CreateConversation(ctx context.Context, c *Conversation, memberIDs []int64) error
+		GetConversation(ctx context.Context, id, userID int64) (*Conversation, error)
+		Conversations(ctx context.Context, userID int64, lq PaginatedListQuery) ([]Conversation, error)
+		CreateMessage(context.Context, *Message) error
+		GetMessages(ctx context.Context, conversationID int64, mq PaginatedMessageQuery) ([]Message, error)
+		MarkRead(ctx context.Context, conversationID, userID, messageID int64) (int64, error)
+		Unread(context.Context, int64) (*UnreadCount, error)
+	}
+	Revisions interface {
+		GetByPostID(context.Context, int64) ([]PostRevision, error)
+	}
+	Search interface {
+		Users(context.Context, PaginatedSearchQuery) ([]UserSearchResult, error)
+		Posts(context.Context, int64, PaginatedSearchQuery) ([]PostSearchResult, error)
+		Tags(context.Context, PaginatedSearchQuery) ([]TagSearchResult, error)
+		Autocomplete(ctx context.Context, userID int64, aq AutocompleteQuery) ([]UserSuggestion, error)
 	}
 }
 
 func NewStorage(db *sql.DB) Storage {
 	return Storage{
-		Posts:    &PostStore{db: db},
-		Users:    &UserStore{db: db},
-		Comment:  &CommentStore{db: db},
-		Follower: &FollowerStore{db: db},
-		Roles:    &RoleStore{db: db},
+		Posts:       &PostStore{db: db},
+		Users:       &UserStore{db: db},
+		Comment:     &CommentStore{db: db},
+		Follower:    &FollowerStore{db: db},
+		Roles:       &RoleStore{db: db},
+		Permissions: &PermissionStore{db: db},
+		Audit:       &AuditStore{db: db},
+		Reports:     &ReportStore{db: db},
+		Timeline:    &TimelineStore{db: db},
+		Tags:        &TagStore{db: db},
+		Search:      &SearchStore{db: db},
+		Media:       &MediaStore{db: db},
+		Links:       &LinkStore{db: db},
+		Revisions:   &RevisionStore{db: db},
+		Reposts:     &RepostStore{db: db},
+		Bookmarks:   &BookmarkStore{db: db},
+		Polls:       &PollStore{db: db},
+		Blocks:      &BlockStore{db: db},
+		Messages:    &MessageStore{db: db},
 	}
 }
 
@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// trendingLockKey is the advisory lock serializing RefreshTrending runs.
+const trendingLockKey = 7_140_001
+
+// TagStore serves tag pages, tag follows and the trending tags table.
+// Tags are expected to be normalized by the caller.
+type TagStore struct {
+	db *sql.DB
+}
+
+type TrendingTag struct {
+	Tag           string    `json:"tag"`
+	Score         float64   `json:"score"`
+	RecentCount   int       `json:"recent_count"`
+	BaselineCount int       `json:"baseline_count"`
+	ComputedAt    time.Time `json:"computed_at"`
+}
+
+// GetPosts returns a page of the posts carrying tag as viewerID sees them:
+// the posts of private users only show to their followers.
+func (s *TagStore) GetPosts(ctx context.Context, viewerID int64, tag string, fq PaginatedFeedQuery) ([]PostWithMetaData, error) {
+	query := `
+  SELECT
+    p.id,
+    p.user_id,
+    u.username,
+    p.title,
+    p.created_at,
+    p.version,
+    p.tags,
+    p.quoted_post_id,
+    (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.hidden_at IS NULL),
+    (SELECT COUNT(*) FROM reposts r WHERE r.post_id = p.id)
+  FROM posts p
+  JOIN users u ON u.id = p.user_id
+  WHERE p.tags @> ARRAY[$1]::varchar[] AND p.status = 'published' AND (p.hidden_at IS NULL OR $4::boolean)
+    AND (NOT u.is_private OR u.id = $5 OR EXISTS (
+      SELECT 1 FROM followers f WHERE f.user_id = u.id AND f.follower_id = $5
+    ))
+  ORDER BY p.created_at ` + fq.Sort + `, p.id ` + fq.Sort + `
+  LIMIT $2 OFFSET $3
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, tag, fq.Limit, fq.Offset, fq.IncludeHidden, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []PostWithMetaData{}
+	for rows.Next() {
+		var p PostWithMetaData
+		err := rows.Scan(
+			&p.ID,
+			&p.UserID,
+			&p.User.UserName,
+			&p.Title,
+			&p.CreatedAt,
+			&p.Version,
+			pq.Array(&p.Tags),
+			&p.QuotedPostID,
+			&p.CommentCount,
+			&p.RepostCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+
+	return posts, rows.Err()
+}
+
+func (s *TagStore) Follow(ctx context.Context, userID int64, tag string) error {
+	query := `
+  INSERT INTO tag_follows (user_id, tag)
+  VALUES ($1, $2)
+  ON CONFLICT DO NOTHING
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, tag)
+	return err
+}
+
+func (s *TagStore) Unfollow(ctx context.Context, userID int64, tag string) error {
+	query := `
+  DELETE FROM tag_follows
+  WHERE user_id = $1 AND tag = $2
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, tag)
+	return err
+}
+
+// Followed lists the tags userID follows, alphabetically.
+func (s *TagStore) Followed(ctx context.Context, userID int64) ([]string, error) {
+	query := `
+  SELECT tag FROM tag_follows
+  WHERE user_id = $1
+  ORDER BY tag
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// RefreshTrending recomputes the trending tags table. Each tag is scored by
+// how far its post count over the last window exceeds the count expected
+// from its rate over the preceding baseline period:
+//
+//	expected = baseline_count * window / (baseline - window)
+//	score    = (recent_count - expected) / sqrt(expected + 1)
+//
+// so a tag jumping from 0 to 10 posts ranks above one steadily getting 50.
+// Only the top limit tags with a positive score are kept. Trending tags are
+// the same for everyone, so only the posts of public, active users count.
+//
+// Every API instance calls it; while one refresh runs, the others return
+// without doing anything.
+func (s *TagStore) RefreshTrending(ctx context.Context, window, baseline time.Duration, limit int) error {
+	query := `
+  INSERT INTO trending_tags (tag, score, recent_count, baseline_count, computed_at)
+  SELECT tag, score, recent_count, baseline_count, $1
+  FROM (
+    SELECT
+      tag,
+      recent_count,
+      baseline_count,
+      (recent_count - baseline_count * $4::float8) / sqrt(baseline_count * $4::float8 + 1) AS score
+    FROM (
+      SELECT
+        tag,
+        COUNT(*) FILTER (WHERE p.created_at >= $2) AS recent_count,
+        COUNT(*) FILTER (WHERE p.created_at < $2) AS baseline_count
+      FROM posts p
+      JOIN users u ON u.id = p.user_id,
+        unnest(p.tags) AS tag
+      WHERE p.created_at >= $3 AND p.status = 'published' AND p.hidden_at IS NULL
+        AND u.is_active AND u.banned_at IS NULL AND NOT u.is_private
+        AND (u.suspended_until IS NULL OR u.suspended_until < NOW())
+      GROUP BY tag
+    ) counts
+    WHERE recent_count > 0
+  ) scored
+  WHERE score > 0
+  ORDER BY score DESC, tag
+  LIMIT $5
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	now := time.Now()
+	ratio := float64(window) / float64(baseline-window)
+
+	return WithTx(s.db, ctx, func(tx *sql.Tx) error {
+		// the instance holding the lock does the refresh for everyone
+		var locked bool
+		err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, trendingLockKey).Scan(&locked)
+		if err != nil || !locked {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM trending_tags`); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, query, now, now.Add(-window), now.Add(-baseline), ratio, limit)
+		return err
+	})
+}
+
+// Trending returns the top limit tags of the last RefreshTrending run.
+func (s *TagStore) Trending(ctx context.Context, limit int) ([]TrendingTag, error) {
+	query := `
+  SELECT tag, score, recent_count, baseline_count, computed_at
+  FROM trending_tags
+  ORDER BY score DESC, tag
+  LIMIT $1
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trending := []TrendingTag{}
+	for rows.Next() {
+		var t TrendingTag
+		if err := rows.Scan(&t.Tag, &t.Score, &t.RecentCount, &t.BaselineCount, &t.ComputedAt); err != nil {
+			return nil, err
+		}
+		trending = append(trending, t)
+	}
+
+	return trending, rows.Err()
+}
@@ -0,0 +1,319 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TimelineStore keeps precomputed home timelines: one row per post in the
+// timeline of every follower of its author (fan-out on write), capped to the
+// newest posts per user. Authors with at least celebrityThreshold followers
+// are not fanned out; their posts are merged in when the timeline is read
+// instead.
+type TimelineStore struct {
+	db *sql.DB
+}
+
+type execer interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+}
+
+// fanOut adds a post to its author's timeline and, unless the author is a
+// celebrity, to the timelines of all their followers. It runs in the
+// transaction publishing the post, so a published post is always fanned out.
+// The post records whether it was, so that Get keeps merging in the posts
+// that were not after their author drops below the threshold.
+func fanOut(ctx context.Context, e execer, post *Post, celebrityThreshold int) error {
+	query := `
+  WITH post AS (
+    UPDATE posts
+    SET fanned_out = (SELECT follower_count < $4 FROM users WHERE id = $2)
+    WHERE id = $1
+    RETURNING fanned_out
+  )
+  INSERT INTO timeline_entries (user_id, post_id, author_id, created_at)
+  SELECT $2::bigint, $1::bigint, $2::bigint, $3::timestamptz
+  UNION ALL
+  SELECT f.follower_id, $1::bigint, $2::bigint, $3::timestamptz
+  FROM followers f, post
+  WHERE f.user_id = $2 AND post.fanned_out
+  ON CONFLICT DO NOTHING
+  `
+	_, err := e.ExecContext(ctx, query, post.ID, post.UserID, post.CreatedAt, celebrityThreshold)
+	return err
+}
+
+// Backfill copies the latest limit posts of authorID into the timeline of
+// userID, typically right after userID follows them. Celebrities are skipped
+// as their posts are read on the fly.
+func (s *TimelineStore) Backfill(ctx context.Context, userID, authorID int64, limit, celebrityThreshold int) error {
+	query := `
+  INSERT INTO timeline_entries (user_id, post_id, author_id, created_at)
+  SELECT $1, p.id, p.user_id, p.created_at
+  FROM posts p
+  JOIN users u ON u.id = p.user_id
+  WHERE p.user_id = $2 AND p.status = 'published' AND u.follower_count < $4
+  ORDER BY p.created_at DESC
+  LIMIT $3
+  ON CONFLICT DO NOTHING
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, authorID, limit, celebrityThreshold)
+	return err
+}
+
+// Trim removes every post of authorID from the timeline of userID.
+func (s *TimelineStore) Trim(ctx context.Context, userID, authorID int64) error {
+	query := `
+  DELETE FROM timeline_entries
+  WHERE user_id = $1 AND author_id = $2
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, authorID)
+	return err
+}
+
+// Cap cuts every timeline back to its newest maxEntries fanned-out posts.
+func (s *TimelineStore) Cap(ctx context.Context, maxEntries int) error {
+	query := `
+  DELETE FROM timeline_entries te
+  USING (
+    SELECT user_id, post_id
+    FROM (
+      SELECT user_id, post_id, row_number() OVER (
+        PARTITION BY user_id ORDER BY created_at DESC, post_id DESC
+      ) AS n
+      FROM timeline_entries
+      WHERE user_id IN (
+        SELECT user_id FROM timeline_entries GROUP BY user_id HAVING COUNT(*) > $1
+      )
+    ) ranked
+    WHERE n > $1
+  ) old
+  WHERE te.user_id = old.user_id AND te.post_id = old.post_id
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, maxEntries)
+	return err
+}
+
+// Get returns a page of the home timeline of userID: the fanned-out entries
+// merged with the posts of followed celebrities, the followed posts that
+// were not fanned out, those carrying a followed tag and those reposted by
+// userID or the users they follow.
+//
+// A post reached several ways shows up once, at the latest time it was
+// posted or reposted, and attributed to the reposter if that was a repost.
+// Posts of private accounts, however they were reached, only show to their
+// followers, and reposts of hidden posts follow the same rules as the posts
+// themselves.
+//
+// Newest-first pages read no more than offset+limit visible rows from each
+// source, which is enough as every source yields a post at most once.
+// Oldest-first pages have to read the sources in full.
+func (s *TimelineStore) Get(ctx context.Context, userID int64, fq PaginatedFeedQuery, celebrityThreshold int) ([]PostWithMetaData, error) {
+	visible := `p.status = 'published' AND (p.hidden_at IS NULL OR p.user_id = $1 OR $4::boolean)
+      AND (NOT u.is_private OR u.id = $1 OR u.id IN (SELECT user_id FROM following))`
+
+	query := `
+  WITH following AS (
+    SELECT user_id FROM followers WHERE follower_id = $1
+  ), entries AS (
+    (SELECT te.post_id, NULL::bigint AS reposted_by, te.created_at AS shared_at
+    FROM timeline_entries te
+    JOIN posts p ON p.id = te.post_id
+    JOIN users u ON u.id = p.user_id
+    WHERE te.user_id = $1 AND ` + visible + `
+    ORDER BY te.created_at ` + fq.Sort + `, te.post_id ` + fq.Sort + `
+    LIMIT $6)
+    UNION ALL
+    (SELECT p.id, NULL, p.created_at
+    FROM posts p
+    JOIN users u ON u.id = p.user_id
+    WHERE p.user_id IN (SELECT user_id FROM following)
+      AND (NOT p.fanned_out OR u.follower_count >= $5) AND ` + visible + `
+    ORDER BY p.created_at ` + fq.Sort + `, p.id ` + fq.Sort + `
+    LIMIT $6)
+    UNION ALL
+    (SELECT p.id, NULL, p.created_at
+    FROM posts p
+    JOIN users u ON u.id = p.user_id
+    WHERE p.tags && ARRAY(SELECT tag FROM tag_follows WHERE user_id = $1) AND ` + visible + `
+    ORDER BY p.created_at ` + fq.Sort + `, p.id ` + fq.Sort + `
+    LIMIT $6)
+    UNION ALL
+    (SELECT r.post_id, r.user_id, r.created_at
+    FROM reposts r
+    JOIN posts p ON p.id = r.post_id
+    JOIN users u ON u.id = p.user_id
+    WHERE (r.user_id = $1 OR r.user_id IN (SELECT user_id FROM following)) AND ` + visible + `
+      AND NOT EXISTS (
+        SELECT 1 FROM reposts later
+        WHERE later.post_id = r.post_id AND later.created_at > r.created_at
+          AND (later.user_id = $1 OR later.user_id IN (SELECT user_id FROM following))
+      )
+    ORDER BY r.created_at ` + fq.Sort + `, r.post_id ` + fq.Sort + `
+    LIMIT $6)
+  ), latest AS (
+    SELECT DISTINCT ON (post_id) post_id, reposted_by, shared_at
+    FROM entries
+    ORDER BY post_id, shared_at DESC, reposted_by NULLS FIRST
+  )
+  SELECT
+    p.id,
+    p.user_id,
+    u.username,
+    p.title,
+    p.created_at,
+    p.version,
+    p.tags,
+    p.quoted_post_id,
+    (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.hidden_at IS NULL),
+    (SELECT COUNT(*) FROM reposts r WHERE r.post_id = p.id),
+    l.reposted_by,
+    ru.username,
+    l.shared_at
+  FROM latest l
+  JOIN posts p ON p.id = l.post_id
+  JOIN users u ON u.id = p.user_id
+  LEFT JOIN users ru ON ru.id = l.reposted_by
+  ORDER BY l.shared_at ` + fq.Sort + `, p.id ` + fq.Sort + `
+  LIMIT $2 OFFSET $3
+  `
+
+	// LIMIT NULL reads a source in full
+	var sourceLimit sql.NullInt64
+	if fq.Sort == "desc" {
+		sourceLimit = sql.NullInt64{Int64: int64(fq.Offset + fq.Limit), Valid: true}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, fq.Limit, fq.Offset, fq.IncludeHidden, celebrityThreshold, sourceLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feed := []PostWithMetaData{}
+	for rows.Next() {
+		var (
+			p          PostWithMetaData
+			repostedBy sql.NullInt64
+			reposter   sql.NullString
+			sharedAt   string
+		)
+		err := rows.Scan(
+			&p.ID,
+			&p.UserID,
+			&p.User.UserName,
+			&p.Title,
+			&p.CreatedAt,
+			&p.Version,
+			pq.Array(&p.Tags),
+			&p.QuotedPostID,
+			&p.CommentCount,
+			&p.RepostCount,
+			&repostedBy,
+			&reposter,
+			&sharedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if repostedBy.Valid {
+			p.RepostedBy = &Repost{UserID: repostedBy.Int64, UserName: reposter.String, CreatedAt: sharedAt}
+		}
+		feed = append(feed, p)
+	}
+
+	return feed, rows.Err()
+}
+
+// EngagementSignals summarizes what a user interacted with recently.
+type EngagementSignals struct {
+	// AuthorAffinity counts the user's comments per post author.
+	AuthorAffinity map[int64]int
+	// Tags counts the user's own and commented-on posts per tag.
+	Tags map[string]int
+}
+
+// Signals collects the engagement of userID over the given window.
+func (s *TimelineStore) Signals(ctx context.Context, userID int64, window time.Duration) (*EngagementSignals, error) {
+	affinityQuery := `
+  SELECT p.user_id, COUNT(*)
+  FROM comments c
+  JOIN posts p ON p.id = c.post_id
+  WHERE c.user_id = $1 AND p.user_id <> $1 AND c.created_at >= $2
+  GROUP BY p.user_id
+  `
+	tagsQuery := `
+  SELECT tag, COUNT(*)
+  FROM (
+    SELECT p.id, p.tags FROM posts p
+    WHERE p.user_id = $1 AND p.status = 'published' AND p.created_at >= $2
+    UNION
+    SELECT p.id, p.tags FROM posts p
+    JOIN comments c ON c.post_id = p.id
+    WHERE c.user_id = $1 AND c.created_at >= $2
+  ) engaged, unnest(engaged.tags) AS tag
+  GROUP BY tag
+  `
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	since := time.Now().Add(-window)
+	signals := &EngagementSignals{
+		AuthorAffinity: map[int64]int{},
+		Tags:           map[string]int{},
+	}
+
+	rows, err := s.db.QueryContext(ctx, affinityQuery, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			authorID int64
+			count    int
+		)
+		if err := rows.Scan(&authorID, &count); err != nil {
+			return nil, err
+		}
+		signals.AuthorAffinity[authorID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tagRows, err := s.db.QueryContext(ctx, tagsQuery, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+
+	for tagRows.Next() {
+		var (
+			tag   string
+			count int
+		)
+		if err := tagRows.Scan(&tag, &count); err != nil {
+			return nil, err
+		}
+		signals.Tags[tag] = count
+	}
+
+	return signals, tagRows.Err()
+}
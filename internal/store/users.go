@@ -17,14 +17,19 @@ var (
 )
 
 type User struct {
-	ID        int64    `json:"id"`
-	UserName  string   `json:"username"`
-	Email     string   `json:"email"`
-	Password  password `json:"-"`
-	CreatedAt string   `json:"created_at"`
-	IsActive  bool     `json:"is_active"`
-	RoleID    int64    `json:"role_id"`
-	Role      Role     `json:"role"`
+	ID             int64    `json:"id"`
+	UserName       string   `json:"username"`
+	Email          string   `json:"email"`
+	Password       password `json:"-"`
+	CreatedAt      string   `json:"created_at"`
+	IsActive       bool     `json:"is_active"`
+	IsPrivate      bool     `json:"is_private"`
+	BannedAt       *string  `json:"banned_at,omitempty"`
+	BanReason      string   `json:"ban_reason,omitempty"`
+	SuspendedUntil *string  `json:"suspended_until,omitempty"`
+	TokenVersion   int      `json:"-"`
+	RoleID         int64    `json:"role_id"`
+	Role           Role     `json:"role"`
 }
 
 type password struct {
@@ -94,10 +99,11 @@ func (s *UserStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
 
 func (s *UserStore) GetById(ctx context.Context, userId int64) (*User, error) {
 	query := `
-  SELECT u.id, u.username, u.email, u.password, u.created_at, r.*
+  SELECT u.id, u.username, u.email, u.password, u.created_at, u.is_private, u.token_version, r.*
   FROM users u
   JOIN roles r ON (u.role_id = r.id)
-  WHERE u.id = $1 AND u.is_active = true
+  WHERE u.id = $1 AND u.is_active = true AND u.banned_at IS NULL
+    AND (u.suspended_until IS NULL OR u.suspended_until < NOW())
   `
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
@@ -114,6 +120,8 @@ func (s *UserStore) GetById(ctx context.Context, userId int64) (*User, error) {
 		&user.Email,
 		&user.Password.hash,
 		&user.CreatedAt,
+		&user.IsPrivate,
+		&user.TokenVersion,
 		&user.Role.ID,
 		&user.Role.Name,
 		&user.Role.Level,
@@ -133,9 +141,10 @@ func (s *UserStore) GetById(ctx context.Context, userId int64) (*User, error) {
 
 func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-  SELECT id, username, email, password, created_at 
+  SELECT id, username, email, password, created_at, token_version
   FROM users
-  WHERE email = $1 AND is_active = true
+  WHERE email = $1 AND is_active = true AND banned_at IS NULL
+    AND (suspended_until IS NULL OR suspended_until < NOW())
   `
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
 	defer cancel()
@@ -151,6 +160,7 @@ func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error)
 		&user.Email,
 		&user.Password.hash,
 		&user.CreatedAt,
+		&user.TokenVersion,
 	)
 	if err != nil {
 		switch err {
@@ -213,6 +223,146 @@ func (s *UserStore) Delete(ctx context.Context, userId int64) error {
 	})
 }
 
+func (s *UserStore) SetRole(ctx context.Context, userId int64, roleName string) error {
+	query := `
+  UPDATE users u
+  SET role_id = r.id
+  FROM roles r
+  WHERE r.name = $1 AND u.id = $2
+  `
+	return s.exec(ctx, query, roleName, userId)
+}
+
+func (s *UserStore) List(ctx context.Context, uq PaginatedUserQuery) ([]User, error) {
+	query := `
+  SELECT u.id, u.username, u.email, u.created_at, u.is_active, u.banned_at, u.ban_reason,
+    u.suspended_until, r.id, r.name, r.description, r.level
+  FROM users u
+  JOIN roles r ON (u.role_id = r.id)
+  WHERE ($1 = '' OR u.username ILIKE '%' || $1 || '%' OR u.email ILIKE '%' || $1 || '%')
+    AND ($2::boolean IS NULL OR u.is_active = $2)
+    AND ($3 = '' OR r.name = $3)
+    AND ($4::timestamptz IS NULL OR u.created_at >= $4)
+    AND ($5::timestamptz IS NULL OR u.created_at < $5)
+  ORDER BY u.created_at ` + uq.Sort + `, u.id
+  LIMIT $6 OFFSET $7
+  `
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		uq.Search,
+		uq.Active,
+		uq.Role,
+		uq.CreatedAfter,
+		uq.CreatedBefore,
+		uq.Limit,
+		uq.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		var u User
+		err := rows.Scan(
+			&u.ID,
+			&u.UserName,
+			&u.Email,
+			&u.CreatedAt,
+			&u.IsActive,
+			&u.BannedAt,
+			&u.BanReason,
+			&u.SuspendedUntil,
+			&u.Role.ID,
+			&u.Role.Name,
+			&u.Role.Description,
+			&u.Role.Level,
+		)
+		if err != nil {
+			return nil, err
+		}
+		u.RoleID = u.Role.ID
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+func (s *UserStore) Deactivate(ctx context.Context, userId int64) error {
+	query := `
+  UPDATE users SET is_active = false, token_version = token_version + 1 WHERE id = $1
+  `
+	return s.exec(ctx, query, userId)
+}
+
+func (s *UserStore) Ban(ctx context.Context, userId int64, reason string) error {
+	query := `
+  UPDATE users
+  SET banned_at = NOW(), ban_reason = $2, token_version = token_version + 1
+  WHERE id = $1
+  `
+	return s.exec(ctx, query, userId, reason)
+}
+
+func (s *UserStore) Unban(ctx context.Context, userId int64) error {
+	query := `
+  UPDATE users SET banned_at = NULL, ban_reason = '' WHERE id = $1 AND banned_at IS NOT NULL
+  `
+	return s.exec(ctx, query, userId)
+}
+
+func (s *UserStore) Suspend(ctx context.Context, userId int64, until time.Time) error {
+	query := `
+  UPDATE users
+  SET suspended_until = $2, token_version = token_version + 1
+  WHERE id = $1
+  `
+	return s.exec(ctx, query, userId, until)
+}
+
+func (s *UserStore) RevokeTokens(ctx context.Context, userId int64) error {
+	query := `
+  UPDATE users SET token_version = token_version + 1 WHERE id = $1
+  `
+	return s.exec(ctx, query, userId)
+}
+
+// SetPrivate hides or shows the user in search results; the posts of a
+// private user are only found by their followers.
+func (s *UserStore) SetPrivate(ctx context.Context, userId int64, private bool) error {
+	query := `
+  UPDATE users SET is_private = $2 WHERE id = $1
+  `
+	return s.exec(ctx, query, userId, private)
+}
+
+func (s *UserStore) exec(ctx context.Context, query string, args ...any) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrorNotFound
+	}
+
+	return nil
+}
+
 func (s *UserStore) createUserInvitation(ctx context.Context, tx *sql.Tx, token string, exp time.Duration, userId int64) error {
 	query := ` 
   INSERT INTO user_invitation(token, user_id, expiry)
@@ -0,0 +1,59 @@
+// Package tags canonicalizes hashtags so that "#Go", "go" and "ｇｏ" are the
+// same tag everywhere they are stored or looked up.
+package tags
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const MaxLength = 50
+
+var ErrInvalidTag = errors.New("tags may only contain letters, digits and underscores, up to 50 characters")
+
+// Normalize returns the canonical form of tag: NFKC-normalized (full-width
+// and styled letters become plain ones), without leading '#' and
+// surrounding spaces, and lower-cased.
+func Normalize(tag string) (string, error) {
+	tag = norm.NFKC.String(tag)
+	tag = strings.TrimSpace(tag)
+	tag = strings.TrimLeft(tag, "#")
+	tag = strings.ToLower(tag)
+
+	if tag == "" || utf8.RuneCountInString(tag) > MaxLength {
+		return "", ErrInvalidTag
+	}
+
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return "", ErrInvalidTag
+		}
+	}
+
+	return tag, nil
+}
+
+// NormalizeAll normalizes every tag and drops duplicates, keeping the order
+// of first occurrence.
+func NormalizeAll(tags []string) ([]string, error) {
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		tag, err := Normalize(tag)
+		if err != nil {
+			return nil, err
+		}
+
+		if !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,60 @@
+package tags
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    string
+		wantErr bool
+	}{
+		{tag: "go", want: "go"},
+		{tag: "#Go", want: "go"},
+		{tag: "##golang", want: "golang"},
+		{tag: "  Cloud_Native ", want: "cloud_native"},
+		{tag: "ｇｏ", want: "go"},
+		{tag: "Straße", want: "straße"},
+		{tag: "日本", want: "日本"},
+		{tag: "web3", want: "web3"},
+		{tag: "", wantErr: true},
+		{tag: "#", wantErr: true},
+		{tag: "go-lang", wantErr: true},
+		{tag: "two words", wantErr: true},
+		{tag: strings.Repeat("a", MaxLength), want: strings.Repeat("a", MaxLength)},
+		{tag: strings.Repeat("a", MaxLength+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got, err := Normalize(tt.tag)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTag) {
+					t.Fatalf("Normalize(%q) error = %v, want ErrInvalidTag", tt.tag, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("Normalize(%q) = %q, %v, want %q", tt.tag, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeAll(t *testing.T) {
+	got, err := NormalizeAll([]string{"#Go", "cloud", "go", "GO", "Cloud"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"go", "cloud"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("NormalizeAll = %v, want %v", got, want)
+	}
+
+	if _, err := NormalizeAll([]string{"go", "not valid"}); !errors.Is(err, ErrInvalidTag) {
+		t.Fatalf("NormalizeAll error = %v, want ErrInvalidTag", err)
+	}
+}
@@ -0,0 +1,26 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/babaYaga451/social/internal/store"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceStore is a store.Interceptor that runs every store method in its own
+// client span, so a slow request shows which query it was waiting on.
+func TraceStore(ctx context.Context, method string, call func(context.Context) error) error {
+	ctx, span := Tracer().Start(ctx, "store."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(method),
+		),
+	)
+
+	err := call(ctx)
+	End(span, err, store.ErrorNotFound)
+
+	return err
+}
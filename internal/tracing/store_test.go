@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/babaYaga451/social/internal/store"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestTraceStore(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := New(ctx, Config{Exporter: ExporterMemory, ServiceName: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Shutdown(ctx) })
+
+	failure := errors.New("connection reset")
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus codes.Code
+		wantEvents int
+	}{
+		{name: "success", wantStatus: codes.Unset},
+		{name: "not found is expected", err: fmt.Errorf("loading: %w", store.ErrorNotFound), wantStatus: codes.Unset, wantEvents: 1},
+		{name: "failure", err: failure, wantStatus: codes.Error, wantEvents: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p.Memory.Reset()
+
+			err := TraceStore(ctx, "Posts.GetById", func(ctx context.Context) error {
+				return tt.err
+			})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("TraceStore = %v, want %v", err, tt.err)
+			}
+
+			spans := p.Memory.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("recorded %d spans, want 1", len(spans))
+			}
+			span := spans[0]
+			if span.Name != "store.Posts.GetById" {
+				t.Fatalf("span name %q", span.Name)
+			}
+			if span.Status.Code != tt.wantStatus {
+				t.Fatalf("span status %v, want %v", span.Status.Code, tt.wantStatus)
+			}
+			if len(span.Events) != tt.wantEvents {
+				t.Fatalf("span has %d events, want %d", len(span.Events), tt.wantEvents)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterMemory = "memory"
+
+	instrumentationName = "github.com/babaYaga451/social"
+)
+
+type Config struct {
+	Exporter     string
+	OTLPEndpoint string
+	ServiceName  string
+	Environment  string
+}
+
+// Provider owns the global tracer provider. Memory is set when the in-memory
+// exporter is configured so tests can inspect the recorded spans.
+type Provider struct {
+	tp     *sdktrace.TracerProvider
+	Memory *tracetest.InMemoryExporter
+}
+
+// New installs a tracer provider and the W3C trace context propagator as
+// the otel globals. With ExporterNone spans are still created, so trace IDs
+// propagate, but nothing is exported.
+func New(ctx context.Context, cfg Config) (*Provider, error) {
+	p := &Provider{}
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case ExporterNone, "":
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{}
+		if cfg.OTLPEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+		}
+
+		exp, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		exporter = exp
+	case ExporterStdout:
+		exp, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, err
+		}
+		exporter = exp
+	case ExporterMemory:
+		p.Memory = tracetest.NewInMemoryExporter()
+		exporter = p.Memory
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.DeploymentEnvironment(cfg.Environment),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	switch exporter {
+	case nil:
+	case p.Memory:
+		// export synchronously so spans are visible as soon as they end
+		opts = append(opts, sdktrace.WithSyncer(exporter))
+	default:
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	p.tp = sdktrace.NewTracerProvider(opts...)
+
+	otel.SetTracerProvider(p.tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return p, nil
+}
+
+// Shutdown flushes pending spans and stops the exporter.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	return p.tp.Shutdown(ctx)
+}
+
+// Tracer returns the application tracer from the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// End records err on span, if any, and ends it. Errors matching one of
+// expected, such as a not-found lookup, are recorded without failing the span.
+func End(span trace.Span, err error, expected ...error) {
+	if err != nil {
+		span.RecordError(err)
+
+		failed := true
+		for _, e := range expected {
+			if errors.Is(err, e) {
+				failed = false
+				break
+			}
+		}
+		if failed {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+
+	span.End()
+}
@@ -0,0 +1,63 @@
+package unfurl
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+var ErrForbiddenAddress = errors.New("unfurl: destination is not a public address")
+
+// allowedPorts keeps previews to regular web servers.
+var allowedPorts = map[string]bool{"80": true, "443": true}
+
+// nonPublic lists ranges that are not covered by the netip predicates but
+// must not be reachable either.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// isPublic reports whether ip is a globally routable unicast address.
+func isPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+		return false
+	}
+
+	for _, prefix := range nonPublic {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkDestination runs as the dialer's Control hook, after DNS resolution
+// and right before connecting, so a hostname cannot resolve to a public
+// address when validated and to a private one when used.
+func checkDestination(network, address string, _ syscall.RawConn) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil || !isPublic(ip) || !allowedPorts[port] {
+		return ErrForbiddenAddress
+	}
+
+	return nil
+}
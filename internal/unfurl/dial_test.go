@@ -0,0 +1,70 @@
+package unfurl
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"93.184.216.34", true},
+		{"2606:4700:4700::1111", true},
+		{"::ffff:8.8.8.8", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"192.0.2.1", false},
+		{"198.18.0.1", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"::", false},
+		{"fe80::1", false},
+		{"fc00::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+		{"2001:db8::1", false},
+		{"2002:a00:1::", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := isPublic(netip.MustParseAddr(tt.ip)); got != tt.want {
+				t.Fatalf("isPublic(%s) = %t, want %t", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDestination(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{"8.8.8.8:443", false},
+		{"8.8.8.8:80", false},
+		{"[2606:4700:4700::1111]:443", false},
+		{"8.8.8.8:22", true},
+		{"127.0.0.1:443", true},
+		{"example.com:443", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := checkDestination("tcp", tt.address, nil)
+			if got := errors.Is(err, ErrForbiddenAddress); got != tt.wantErr || (err != nil && !got) {
+				t.Fatalf("checkDestination(%s) = %v, want forbidden %t", tt.address, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package unfurl
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	maxTitle       = 300
+	maxDescription = 1000
+	maxURL         = 2048
+)
+
+// parse reads the metadata in the head of an HTML document. OpenGraph
+// properties win over Twitter card ones, which win over <title> and the
+// description meta tag. Relative image URLs are resolved against base.
+func parse(r io.Reader, base *url.URL) *Preview {
+	meta := map[string]string{}
+	var title string
+
+	z := html.NewTokenizer(r)
+	for inTitle := false; ; {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return build(meta, title, base)
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch tok.DataAtom {
+			case atom.Body:
+				return build(meta, title, base)
+			case atom.Title:
+				inTitle = tt == html.StartTagToken
+			case atom.Meta:
+				var key, content string
+				for _, a := range tok.Attr {
+					switch a.Key {
+					case "property", "name":
+						key = strings.ToLower(strings.TrimSpace(a.Val))
+					case "content":
+						content = strings.TrimSpace(a.Val)
+					}
+				}
+				if key != "" && content != "" {
+					if _, ok := meta[key]; !ok {
+						meta[key] = content
+					}
+				}
+			}
+
+		case html.EndTagToken:
+			switch z.Token().DataAtom {
+			case atom.Head:
+				return build(meta, title, base)
+			case atom.Title:
+				inTitle = false
+			}
+
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = strings.TrimSpace(string(z.Text()))
+			}
+		}
+	}
+}
+
+func build(meta map[string]string, title string, base *url.URL) *Preview {
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := meta[k]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	if t := first("og:title", "twitter:title"); t != "" {
+		title = t
+	}
+
+	return &Preview{
+		Title:       truncate(title, maxTitle),
+		Description: truncate(first("og:description", "twitter:description", "description"), maxDescription),
+		ImageURL:    resolve(base, first("og:image", "og:image:url", "og:image:secure_url", "twitter:image", "twitter:image:src")),
+		SiteName:    truncate(first("og:site_name"), maxTitle),
+	}
+}
+
+// resolve makes ref absolute against base and keeps only http(s) URLs.
+func resolve(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.String()) > maxURL {
+		return ""
+	}
+	return u.String()
+}
+
+func truncate(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max-1]) + "…"
+}
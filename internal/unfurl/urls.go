@@ -0,0 +1,60 @@
+// Package unfurl builds link previews: it finds URLs in post content and
+// reads their OpenGraph and Twitter card metadata, refusing to connect
+// anywhere but the public internet.
+package unfurl
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var urlPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"']+`)
+
+// ExtractURLs returns up to max distinct http(s) URLs in content, in order
+// of appearance. Punctuation ending a sentence is not part of the URL.
+func ExtractURLs(content string, max int) []string {
+	var urls []string
+	seen := map[string]bool{}
+
+	for _, match := range urlPattern.FindAllString(content, -1) {
+		match = trimTrailing(match)
+
+		u, err := url.Parse(match)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		u.Fragment = ""
+		normalized := u.String()
+
+		if seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		urls = append(urls, normalized)
+
+		if len(urls) == max {
+			break
+		}
+	}
+
+	return urls
+}
+
+// trimTrailing drops trailing punctuation, and closing brackets that have
+// no opening partner inside the URL, e.g. "(see https://x.io/a)".
+func trimTrailing(s string) string {
+	for {
+		trimmed := strings.TrimRight(s, ".,;:!?")
+		for _, pair := range [][2]string{{"(", ")"}, {"[", "]"}} {
+			if strings.HasSuffix(trimmed, pair[1]) && strings.Count(trimmed, pair[0]) < strings.Count(trimmed, pair[1]) {
+				trimmed = strings.TrimSuffix(trimmed, pair[1])
+			}
+		}
+
+		if trimmed == s {
+			return s
+		}
+		s = trimmed
+	}
+}
@@ -0,0 +1,78 @@
+package unfurl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractURLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		max     int
+		want    []string
+	}{
+		{
+			name:    "none",
+			content: "no links here, just www.example.com",
+			max:     5,
+		},
+		{
+			name:    "in order",
+			content: "see https://a.io/x and http://b.io",
+			max:     5,
+			want:    []string{"https://a.io/x", "http://b.io"},
+		},
+		{
+			name:    "trailing punctuation",
+			content: "read https://a.io/post. Or https://b.io/faq?!",
+			max:     5,
+			want:    []string{"https://a.io/post", "https://b.io/faq"},
+		},
+		{
+			name:    "unbalanced bracket",
+			content: "(see https://a.io/x)",
+			max:     5,
+			want:    []string{"https://a.io/x"},
+		},
+		{
+			name:    "balanced brackets kept",
+			content: "https://en.wikipedia.org/wiki/Go_(programming_language)",
+			max:     5,
+			want:    []string{"https://en.wikipedia.org/wiki/Go_(programming_language)"},
+		},
+		{
+			name:    "duplicates by fragment",
+			content: "https://a.io/x#top https://a.io/x#end",
+			max:     5,
+			want:    []string{"https://a.io/x"},
+		},
+		{
+			name:    "scheme case",
+			content: "HTTPS://a.io",
+			max:     5,
+			want:    []string{"https://a.io"},
+		},
+		{
+			name:    "capped",
+			content: "https://a.io https://b.io https://c.io",
+			max:     2,
+			want:    []string{"https://a.io", "https://b.io"},
+		},
+		{
+			name:    "quoted",
+			content: `<a href="https://a.io/x">link</a>`,
+			max:     5,
+			want:    []string{"https://a.io/x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractURLs(tt.content, tt.max)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ExtractURLs(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}